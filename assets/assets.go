@@ -0,0 +1,9 @@
+// Package assets holds the files that are built into the bot, so it doesn't need to download them
+package assets
+
+import "embed"
+
+// Emojis are the PNGs of the emojis the bot uploads to the guilds, named like the emojis, e.g. emojis/aured.png
+//
+//go:embed emojis/*.png
+var Emojis embed.FS
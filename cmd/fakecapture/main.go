@@ -0,0 +1,171 @@
+// Command fakecapture pretends to be amonguscapture, so the bot can be tried out without Windows or Among Us.
+// It links itself with a connect code, and then plays a scripted game, or one typed in step by step:
+//
+//	go run ./cmd/fakecapture -code ABCD1234 -script game.yaml
+//	go run ./cmd/fakecapture -code ABCD1234 -i
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/denverquane/amongusdiscord/logging"
+)
+
+const interactiveHelp = `Befehle:
+  <Enter>                    nächsten Schritt aus dem Skript ausführen
+  lobby <code> [na|eu|as]    Lobby-Code und Region senden
+  phase <lobby|tasks|discussion|menu>
+  join <name> <farbe>
+  die <name>
+  exile <name>
+  leave <name>
+  disconnect <name>
+  help
+  quit`
+
+func main() {
+	serverURL := flag.String("url", "http://localhost:8123", "URL des Socket.io-Servers des Bots")
+	code := flag.String("code", "", "Verbindungscode aus der Nachricht des Bots nach .au new")
+	scriptPath := flag.String("script", "", "Skript mit dem Spielablauf (YAML oder JSON)")
+	interactive := flag.Bool("i", false, "Schritte von Hand auslösen, statt das Skript ablaufen zu lassen")
+	flag.Parse()
+
+	if *code == "" {
+		fmt.Fprintln(os.Stderr, "Ein Verbindungscode (-code) ist erforderlich")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	script := &Script{}
+	if *scriptPath != "" {
+		var err error
+		script, err = LoadScript(*scriptPath)
+		if err != nil {
+			logging.Fatalf("Skript konnte nicht gelesen werden: %s", err)
+		}
+	} else if !*interactive {
+		fmt.Fprintln(os.Stderr, "Ohne Skript (-script) geht nur der interaktive Modus (-i)")
+		os.Exit(2)
+	}
+	delay, _ := script.StepDelay()
+
+	client, err := Dial(*serverURL)
+	if err != nil {
+		logging.Fatalf("Verbindung zu %s fehlgeschlagen: %s", *serverURL, err)
+	}
+	defer client.Close()
+
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sc:
+			client.Close()
+			os.Exit(0)
+		case <-client.Done():
+			os.Exit(1)
+		}
+	}()
+
+	if err := client.Emit(Event{Name: "connectCode", Data: *code}); err != nil {
+		logging.Fatalf("Verbindungscode konnte nicht gesendet werden: %s", err)
+	}
+	logging.Infof("Verbindungscode %s gesendet", *code)
+
+	runner := &Runner{client: client, game: NewGame(), delay: delay}
+	if script.Lobby != nil {
+		runner.Run(Step{Lobby: script.Lobby})
+	}
+
+	if *interactive {
+		runner.Interactive(script.Steps)
+	} else {
+		for _, step := range script.Steps {
+			if !runner.Run(step) {
+				break
+			}
+			time.Sleep(delay)
+		}
+		logging.Info("Skript beendet")
+	}
+}
+
+// Runner sends the steps over the connection
+type Runner struct {
+	client *SocketClient
+	game   *Game
+	delay  time.Duration
+}
+
+// Run sends the step's events and waits for as long as it says. It returns false if the connection is gone
+func (r *Runner) Run(step Step) bool {
+	events, wait, err := r.game.Events(step)
+	if err != nil {
+		logging.Error(err)
+		return true
+	}
+	for _, event := range events {
+		logging.Infof("Sende %s: %s", event.Name, event.Data)
+		if err := r.client.Emit(event); err != nil {
+			logging.Errorf("Senden fehlgeschlagen: %s", err)
+			return false
+		}
+	}
+	if wait > 0 {
+		logging.Infof("Warte %s", wait)
+		time.Sleep(wait)
+	}
+	return true
+}
+
+// Interactive reads commands from stdin. An empty line runs the next of the scripted steps
+func (r *Runner) Interactive(steps []Step) {
+	fmt.Println(interactiveHelp)
+	next := 0
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		if next < len(steps) {
+			fmt.Printf("[%d/%d] > ", next+1, len(steps))
+		} else {
+			fmt.Print("> ")
+		}
+		if !scanner.Scan() {
+			return
+		}
+		line := strings.TrimSpace(scanner.Text())
+
+		switch strings.ToLower(line) {
+		case "quit", "exit":
+			return
+		case "help", "?":
+			fmt.Println(interactiveHelp)
+			continue
+		case "":
+			if next >= len(steps) {
+				fmt.Println("Das Skript ist zu Ende")
+				continue
+			}
+			if !r.Run(steps[next]) {
+				return
+			}
+			next++
+			continue
+		}
+
+		step, err := ParseCommand(line)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if !r.Run(step) {
+			return
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denverquane/amongusdiscord/game"
+	"gopkg.in/yaml.v2"
+)
+
+const DefaultStepDelay = time.Second
+
+// Script is a game for the fake capture to play. It's YAML, or JSON (which is valid YAML too):
+//
+//	delay: 2s
+//	lobby: {code: ABCDEF, region: eu}
+//	steps:
+//	  - join: {name: alice, color: red}
+//	  - phase: tasks
+//	  - die: alice
+//	  - wait: 10s
+//	  - phase: discussion
+type Script struct {
+	//the pause between steps
+	Delay string `yaml:"delay"`
+	//sent right after connecting, if set
+	Lobby *LobbyStep `yaml:"lobby"`
+	Steps []Step     `yaml:"steps"`
+}
+
+// Step is one thing that happens in the game. Exactly one of the fields is set
+type Step struct {
+	Phase      string     `yaml:"phase,omitempty"`
+	Lobby      *LobbyStep `yaml:"lobby,omitempty"`
+	Join       *JoinStep  `yaml:"join,omitempty"`
+	Die        string     `yaml:"die,omitempty"`
+	Exile      string     `yaml:"exile,omitempty"`
+	Leave      string     `yaml:"leave,omitempty"`
+	Disconnect string     `yaml:"disconnect,omitempty"`
+	//pause for longer than the usual delay
+	Wait string `yaml:"wait,omitempty"`
+}
+
+type LobbyStep struct {
+	Code   string `yaml:"code"`
+	Region string `yaml:"region"`
+}
+
+type JoinStep struct {
+	Name  string `yaml:"name"`
+	Color string `yaml:"color"`
+}
+
+// Event is a socket.io event the way the real capture sends it: every payload is a string
+type Event struct {
+	Name string
+	Data string
+}
+
+func LoadScript(path string) (*Script, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseScript(data)
+}
+
+func ParseScript(data []byte) (*Script, error) {
+	script := Script{}
+	err := yaml.UnmarshalStrict(data, &script)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := script.StepDelay(); err != nil {
+		return nil, err
+	}
+	for i, step := range script.Steps {
+		if err := step.validate(); err != nil {
+			return nil, fmt.Errorf("Schritt %d: %s", i+1, err)
+		}
+	}
+	return &script, nil
+}
+
+func (script *Script) StepDelay() (time.Duration, error) {
+	if script.Delay == "" {
+		return DefaultStepDelay, nil
+	}
+	delay, err := time.ParseDuration(script.Delay)
+	if err != nil {
+		return 0, fmt.Errorf("ungültige Verzögerung %q", script.Delay)
+	}
+	return delay, nil
+}
+
+func (step *Step) validate() error {
+	set := 0
+	for _, v := range []bool{step.Phase != "", step.Lobby != nil, step.Join != nil, step.Die != "", step.Exile != "", step.Leave != "", step.Disconnect != "", step.Wait != ""} {
+		if v {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("jeder Schritt braucht genau eine Aktion")
+	}
+	if step.Phase != "" {
+		if _, err := parsePhase(step.Phase); err != nil {
+			return err
+		}
+	}
+	if step.Lobby != nil {
+		if _, err := parseRegion(step.Lobby.Region); err != nil {
+			return err
+		}
+	}
+	if step.Join != nil {
+		if step.Join.Name == "" {
+			return errors.New("der Spieler braucht einen Namen")
+		}
+		if _, ok := game.DefaultAliases().Color(step.Join.Color); !ok {
+			return fmt.Errorf("unbekannte Farbe %q", step.Join.Color)
+		}
+	}
+	if step.Wait != "" {
+		if _, err := time.ParseDuration(step.Wait); err != nil {
+			return fmt.Errorf("ungültige Wartezeit %q", step.Wait)
+		}
+	}
+	return nil
+}
+
+// ParseCommand reads a step written the way it's typed in interactive mode, e.g. "join alice red"
+// or "phase tasks"
+func ParseCommand(line string) (Step, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return Step{}, errors.New("leerer Befehl")
+	}
+	arg := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	step := Step{}
+	switch strings.ToLower(fields[0]) {
+	case "phase":
+		step.Phase = arg(1)
+	case "lobby":
+		step.Lobby = &LobbyStep{Code: arg(1), Region: arg(2)}
+	case "join":
+		step.Join = &JoinStep{Name: arg(1), Color: arg(2)}
+	case "die":
+		step.Die = arg(1)
+	case "exile":
+		step.Exile = arg(1)
+	case "leave":
+		step.Leave = arg(1)
+	case "disconnect":
+		step.Disconnect = arg(1)
+	case "wait":
+		step.Wait = arg(1)
+	default:
+		return step, fmt.Errorf("unbekannter Befehl %q", fields[0])
+	}
+	return step, step.validate()
+}
+
+func parsePhase(name string) (game.Phase, error) {
+	switch strings.ToLower(name) {
+	case "lobby", "l":
+		return game.LOBBY, nil
+	case "tasks", "task", "t":
+		return game.TASKS, nil
+	case "discussion", "discuss", "d":
+		return game.DISCUSS, nil
+	case "menu", "m":
+		return game.MENU, nil
+	}
+	return game.UNINITIALIZED, fmt.Errorf("unbekannte Phase %q", name)
+}
+
+func parseRegion(name string) (game.Region, error) {
+	switch strings.ToLower(name) {
+	case "na", "":
+		return game.NA, nil
+	case "eu":
+		return game.EU, nil
+	case "as":
+		return game.AS, nil
+	}
+	return game.NA, fmt.Errorf("unbekannte Region %q, erlaubt sind na, eu und as", name)
+}
+
+// Game turns steps into the events the capture would send, and remembers the players' colors for that
+type Game struct {
+	colors map[string]int
+}
+
+func NewGame() *Game {
+	return &Game{colors: map[string]int{}}
+}
+
+// Events returns what to send for the step, and how long to wait afterwards on top of the usual delay
+func (g *Game) Events(step Step) ([]Event, time.Duration, error) {
+	if err := step.validate(); err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case step.Phase != "":
+		phase, _ := parsePhase(step.Phase)
+		return []Event{{Name: "state", Data: strconv.Itoa(int(phase))}}, 0, nil
+	case step.Lobby != nil:
+		region, _ := parseRegion(step.Lobby.Region)
+		data, err := json.Marshal(game.Lobby{LobbyCode: step.Lobby.Code, Region: region})
+		return []Event{{Name: "lobby", Data: string(data)}}, 0, err
+	case step.Join != nil:
+		color, _ := game.DefaultAliases().Color(step.Join.Color)
+		g.colors[step.Join.Name] = color
+		return g.playerEvent(game.Player{Action: game.JOINED, Name: step.Join.Name, Color: color})
+	case step.Die != "":
+		return g.playerEvent(game.Player{Action: game.DIED, Name: step.Die, Color: g.colors[step.Die], IsDead: true})
+	case step.Exile != "":
+		return g.playerEvent(game.Player{Action: game.EXILED, Name: step.Exile, Color: g.colors[step.Exile], IsDead: true})
+	case step.Leave != "":
+		return g.playerEvent(game.Player{Action: game.LEFT, Name: step.Leave, Color: g.colors[step.Leave]})
+	case step.Disconnect != "":
+		return g.playerEvent(game.Player{Action: game.DISCONNECTED, Name: step.Disconnect, Color: g.colors[step.Disconnect], Disconnected: true})
+	default:
+		wait, _ := time.ParseDuration(step.Wait)
+		return nil, wait, nil
+	}
+}
+
+func (g *Game) playerEvent(player game.Player) ([]Event, time.Duration, error) {
+	data, err := json.Marshal(player)
+	return []Event{{Name: "player", Data: string(data)}}, 0, err
+}
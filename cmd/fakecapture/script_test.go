@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	socketio "github.com/googollee/go-socket.io"
+)
+
+func TestExampleScript(t *testing.T) {
+	script, err := LoadScript("example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delay, _ := script.StepDelay(); delay != 2*time.Second {
+		t.Errorf("Verzögerung %s, erwartet 2s", delay)
+	}
+
+	g := NewGame()
+	for _, step := range script.Steps {
+		if _, _, err := g.Events(step); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, _, _ := g.Events(Step{Die: "bob"})
+	if events[0].Name != "player" || events[0].Data != `{"Action":2,"Name":"bob","Color":1,"IsDead":true,"Disconnected":false}` {
+		t.Errorf("unerwartetes Ereignis: %+v", events[0])
+	}
+}
+
+func TestInvalidSteps(t *testing.T) {
+	for _, data := range []string{
+		"steps: [{phase: voting}]",
+		"steps: [{join: {name: alice, color: gold}}]",
+		"steps: [{die: alice, leave: alice}]",
+		"steps: [{}]",
+		"delay: bald",
+	} {
+		if _, err := ParseScript([]byte(data)); err == nil {
+			t.Errorf("%q sollte ungültig sein", data)
+		}
+	}
+	if step, err := ParseCommand("join alice red"); err != nil || step.Join.Name != "alice" {
+		t.Errorf("join wurde nicht gelesen: %+v, %v", step, err)
+	}
+}
+
+// the events have to arrive at a go-socket.io server the way the bot reads them
+func TestEmitToSocketServer(t *testing.T) {
+	server, err := socketio.NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan Event, 10)
+	for _, name := range []string{"connectCode", "state"} {
+		name := name
+		server.OnEvent("/", name, func(s socketio.Conn, msg string) {
+			received <- Event{Name: name, Data: msg}
+		})
+	}
+	server.OnConnect("/", func(s socketio.Conn) error {
+		return nil
+	})
+	go server.Serve()
+	defer server.Close()
+
+	mux := http.NewServeMux()
+	mux.Handle("/socket.io/", server)
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+
+	client, err := Dial(httpServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	expected := []Event{{Name: "connectCode", Data: "ABCD1234"}, {Name: "state", Data: "1"}}
+	for _, event := range expected {
+		if err := client.Emit(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range expected {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Errorf("erwartet %+v, bekommen %+v", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s ist nicht angekommen", want.Name)
+		}
+	}
+}
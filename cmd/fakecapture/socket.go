@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/denverquane/amongusdiscord/logging"
+	"github.com/gorilla/websocket"
+)
+
+// engine.io v3 packet types, as far as a client that only emits events needs them
+const (
+	packetOpen    = '0'
+	packetClose   = '1'
+	packetPing    = '2'
+	packetPong    = '3'
+	packetMessage = '4'
+)
+
+// socket.io packet types, inside an engine.io message
+const (
+	socketConnect    = '0'
+	socketDisconnect = '1'
+	socketEvent      = '2'
+)
+
+// SocketClient talks the small part of socket.io that the capture uses: it connects to the default
+// namespace and emits events, over a websocket
+type SocketClient struct {
+	conn      *websocket.Conn
+	writeLock sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Dial connects to the bot's socket.io server, e.g. http://localhost:8123
+func Dial(serverURL string) (*SocketClient, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return nil, fmt.Errorf("die URL muss mit http:// oder https:// beginnen, nicht %q", serverURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/socket.io/"
+	u.RawQuery = "EIO=3&transport=websocket"
+
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if len(msg) == 0 || msg[0] != packetOpen {
+		conn.Close()
+		return nil, fmt.Errorf("unerwartete erste Nachricht vom Server: %q", msg)
+	}
+	handshake := struct {
+		SID          string `json:"sid"`
+		PingInterval int    `json:"pingInterval"`
+	}{}
+	if err := json.Unmarshal(msg[1:], &handshake); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	logging.Infof("Verbunden mit %s, Sitzung %s", serverURL, handshake.SID)
+
+	client := &SocketClient{
+		conn: conn,
+		done: make(chan struct{}),
+	}
+	go client.readLoop()
+	go client.pingLoop(time.Duration(handshake.PingInterval) * time.Millisecond)
+	return client, nil
+}
+
+// Emit sends an event to the default namespace
+func (sc *SocketClient) Emit(event Event) error {
+	payload, err := json.Marshal([]string{event.Name, event.Data})
+	if err != nil {
+		return err
+	}
+	return sc.write(string([]byte{packetMessage, socketEvent}) + string(payload))
+}
+
+// Done is closed once the connection is gone
+func (sc *SocketClient) Done() <-chan struct{} {
+	return sc.done
+}
+
+func (sc *SocketClient) Close() error {
+	err := sc.write(string([]byte{packetClose}))
+	sc.shutdown()
+	return err
+}
+
+func (sc *SocketClient) write(msg string) error {
+	sc.writeLock.Lock()
+	defer sc.writeLock.Unlock()
+	select {
+	case <-sc.done:
+		return errors.New("die Verbindung ist geschlossen")
+	default:
+	}
+	return sc.conn.WriteMessage(websocket.TextMessage, []byte(msg))
+}
+
+func (sc *SocketClient) shutdown() {
+	sc.closeOnce.Do(func() {
+		close(sc.done)
+		sc.conn.Close()
+	})
+}
+
+func (sc *SocketClient) readLoop() {
+	defer sc.shutdown()
+	for {
+		_, msg, err := sc.conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-sc.done:
+			default:
+				logging.Warnf("Verbindung zum Server verloren: %s", err)
+			}
+			return
+		}
+		if len(msg) == 0 {
+			continue
+		}
+		switch msg[0] {
+		case packetClose:
+			logging.Info("Der Server hat die Verbindung geschlossen")
+			return
+		case packetMessage:
+			if len(msg) > 1 && msg[1] == socketDisconnect {
+				logging.Info("Der Server hat den Namespace getrennt")
+				return
+			}
+			if len(msg) > 1 && msg[1] == socketConnect {
+				logging.Debug("Mit dem Namespace / verbunden")
+			} else {
+				logging.Debugf("Nachricht vom Server: %s", msg[1:])
+			}
+		}
+	}
+}
+
+func (sc *SocketClient) pingLoop(interval time.Duration) {
+	if interval <= 0 {
+		interval = 25 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sc.done:
+			return
+		case <-ticker.C:
+			if err := sc.write(string([]byte{packetPing})); err != nil {
+				return
+			}
+		}
+	}
+}
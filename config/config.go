@@ -0,0 +1,207 @@
+// Package config loads the bot's settings. They come from, in that order, the defaults, a YAML file, the
+// environment and the command line, so that each layer overrides the ones before it:
+//
+//	port: 8123                  # config.yaml
+//	PORT=8124                   # environment
+//	-port 8125                  # command line
+//
+// Secrets can't be given on the command line, but like in Docker, they can be read from the file named by
+// the variable with _FILE appended, e.g. DISCORD_BOT_TOKEN_FILE=/run/secrets/discord_token
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/denverquane/amongusdiscord/logging"
+)
+
+// DefaultFile is read if it exists and no other file is named with -config or CONFIG_FILE
+const DefaultFile = "config.yaml"
+
+type Config struct {
+	DiscordToken  string `yaml:"discordToken" env:"DISCORD_BOT_TOKEN" secret:"true" desc:"Token des Discord-Bots"`
+	DiscordToken2 string `yaml:"discordToken2" env:"DISCORD_BOT_TOKEN_2" secret:"true" desc:"Token eines 2. Bots, der bei vielen Stummschaltungen aushilft"`
+	EmojiGuildID  string `yaml:"emojiGuildID" env:"EMOJI_GUILD_ID" desc:"die einzige Gilde, in der die Emojis angelegt werden"`
+
+	NumShards int    `yaml:"numShards" env:"NUM_SHARDS" desc:"Anzahl aller Shards"`
+	ShardIDs  []int  `yaml:"shardIDs" env:"SHARD_IDS" desc:"die Shards dieses Prozesses, z.B. 0,1; leer für alle"`
+	ProcessID string `yaml:"processID" env:"PROCESS_ID" desc:"eindeutiger Name des Prozesses; leer für Hostname und PID"`
+
+	Port      int    `yaml:"port" env:"PORT" desc:"Port für die Verbindungen der Erfassung"`
+	ExtPort   string `yaml:"extPort" env:"EXT_PORT" desc:"Port in der URL für die Erfassung, oder protocol für keinen; leer für port"`
+	ServerURL string `yaml:"serverURL" env:"SERVER_URL" desc:"von außen erreichbare URL des Bots, z.B. http://example.com"`
+
+	ConfigPath         string `yaml:"configPath" env:"CONFIG_PATH" desc:"Verzeichnis für die Daten der Gilden"`
+	FirestoreProjectID string `yaml:"firestoreProjectID" env:"FIRESTORE_PROJECT_ID" desc:"Firestore-Projekt statt des Verzeichnisses"`
+	GoogleCredentials  string `yaml:"googleCredentials" env:"GOOGLE_APPLICATION_CREDENTIALS" desc:"Zugangsdaten für Firestore"`
+
+	AdminPort       int    `yaml:"adminPort" env:"ADMIN_PORT" desc:"Port für die Admin-API und /metrics"`
+	AdminToken      string `yaml:"adminToken" env:"ADMIN_API_TOKEN" secret:"true" desc:"Token für die Admin-API; ohne ist sie abgeschaltet"`
+	ShutdownTimeout int    `yaml:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT" desc:"Sekunden, die das Herunterfahren höchstens dauern darf"`
+
+	StatusEditWindowMs int `yaml:"statusEditWindowMs" env:"STATUS_EDIT_WINDOW_MS" desc:"Millisekunden, in denen Änderungen an der Statusmeldung gesammelt werden; Gilden können eigene festlegen"`
+
+	Redis RedisConfig `yaml:"redis"`
+	Log   LogConfig   `yaml:"log"`
+}
+
+type RedisConfig struct {
+	URL    string `yaml:"url" env:"REDIS_URL" secret:"true" desc:"Redis zur Abstimmung mit den anderen Prozessen"`
+	Prefix string `yaml:"prefix" env:"REDIS_PREFIX" desc:"Präfix der Redis-Schlüssel"`
+}
+
+type LogConfig struct {
+	Level       string `yaml:"level" env:"LOG_LEVEL" desc:"debug, info, warn oder error"`
+	Format      string `yaml:"format" env:"LOG_FORMAT" desc:"console oder json"`
+	File        string `yaml:"file" env:"LOG_FILE" desc:"Logdatei"`
+	DisableFile bool   `yaml:"disableFile" env:"DISABLE_LOG_FILE" desc:"keine Logdatei schreiben"`
+	MaxSizeMB   int    `yaml:"maxSizeMB" env:"LOG_MAX_SIZE_MB" desc:"Größe, ab der die Logdatei rotiert wird"`
+	MaxAgeDays  int    `yaml:"maxAgeDays" env:"LOG_MAX_AGE_DAYS" desc:"Tage, die rotierte Logdateien behalten werden; 0 für immer"`
+	MaxBackups  int    `yaml:"maxBackups" env:"LOG_MAX_BACKUPS" desc:"Anzahl der rotierten Logdateien; 0 für alle"`
+	RotateDaily bool   `yaml:"rotateDaily" env:"LOG_ROTATE_DAILY" desc:"die Logdatei auch jeden Tag um Mitternacht rotieren"`
+}
+
+func Default() *Config {
+	logConfig := logging.DefaultConfig()
+	return &Config{
+		NumShards:       1,
+		Port:            8123,
+		ServerURL:       "http://localhost",
+		ConfigPath:      "./",
+		AdminPort:       5000,
+		ShutdownTimeout: 20,
+
+		StatusEditWindowMs: 1000,
+		Redis: RedisConfig{
+			Prefix: "amongusdiscord:",
+		},
+		Log: LogConfig{
+			Level:       logConfig.Level,
+			Format:      logConfig.Format,
+			File:        logConfig.File,
+			MaxSizeMB:   logConfig.MaxSizeMB,
+			MaxAgeDays:  logConfig.MaxAgeDays,
+			MaxBackups:  logConfig.MaxBackups,
+			RotateDaily: logConfig.RotateDaily,
+		},
+	}
+}
+
+// Logging is the configuration for logging.Init
+func (lc LogConfig) Logging() logging.Config {
+	file := lc.File
+	if lc.DisableFile {
+		file = ""
+	}
+	return logging.Config{
+		Level:       lc.Level,
+		Format:      lc.Format,
+		File:        file,
+		MaxSizeMB:   lc.MaxSizeMB,
+		MaxAgeDays:  lc.MaxAgeDays,
+		MaxBackups:  lc.MaxBackups,
+		RotateDaily: lc.RotateDaily,
+	}
+}
+
+// ValidationError lists everything that's wrong with the configuration, not just the first problem
+type ValidationError []string
+
+func (ve ValidationError) Error() string {
+	return "ungültige Konfiguration:\n  " + strings.Join(ve, "\n  ")
+}
+
+// Validate checks the values that can't be fixed by the bot itself
+func (cfg *Config) Validate() error {
+	problems := ValidationError{}
+	problem := func(key, format string, args ...interface{}) {
+		problems = append(problems, cfg.describe(key)+": "+fmt.Sprintf(format, args...))
+	}
+
+	if cfg.DiscordToken == "" {
+		problem("discordToken", "fehlt")
+	}
+	if cfg.NumShards < 1 {
+		problem("numShards", "muss mindestens 1 sein, nicht %d", cfg.NumShards)
+	}
+	seen := make(map[int]bool)
+	for _, id := range cfg.ShardIDs {
+		if id < 0 || id >= cfg.NumShards {
+			problem("shardIDs", "Shard %d gibt es bei %d Shards nicht", id, cfg.NumShards)
+		} else if seen[id] {
+			problem("shardIDs", "Shard %d ist doppelt", id)
+		}
+		seen[id] = true
+	}
+
+	if cfg.Port < 1024 || cfg.Port > 65535 {
+		problem("port", "%d liegt nicht zwischen 1024 und 65535", cfg.Port)
+	}
+	if cfg.ExtPort != "" && cfg.ExtPort != "protocol" {
+		num, err := strconv.Atoi(cfg.ExtPort)
+		if err != nil || num > 65535 || (num < 1024 && num != 80 && num != 443) {
+			problem("extPort", "%q ist weder protocol noch 80, 443 oder ein Port zwischen 1024 und 65535", cfg.ExtPort)
+		}
+	}
+	if u, err := url.Parse(cfg.ServerURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problem("serverURL", "%q muss mit http:// oder https:// beginnen", cfg.ServerURL)
+	} else if strings.Trim(u.Path, "/") != "" || u.RawQuery != "" {
+		problem("serverURL", "%q darf nur aus Protokoll, Host und Port bestehen", cfg.ServerURL)
+	}
+
+	if (cfg.FirestoreProjectID == "") != (cfg.GoogleCredentials == "") {
+		problem("firestoreProjectID", "für Firestore braucht es Projekt und Zugangsdaten (%s)", cfg.describe("googleCredentials"))
+	}
+	if cfg.AdminPort < 1 || cfg.AdminPort > 65535 {
+		problem("adminPort", "%d ist kein gültiger Port", cfg.AdminPort)
+	}
+	if cfg.AdminPort == cfg.Port {
+		problem("adminPort", "muss sich von %s unterscheiden", cfg.describe("port"))
+	}
+	if cfg.ShutdownTimeout < 1 {
+		problem("shutdownTimeout", "muss mindestens 1 Sekunde sein, nicht %d", cfg.ShutdownTimeout)
+	}
+	if cfg.StatusEditWindowMs < 250 || cfg.StatusEditWindowMs > 10000 {
+		problem("statusEditWindowMs", "%d liegt nicht zwischen 250 und 10000", cfg.StatusEditWindowMs)
+	}
+
+	switch strings.ToLower(cfg.Log.Level) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		problem("log.level", "%q ist weder debug, info, warn noch error", cfg.Log.Level)
+	}
+	switch strings.ToLower(cfg.Log.Format) {
+	case logging.FormatConsole, logging.FormatJSON:
+	default:
+		problem("log.format", "%q ist weder %s noch %s", cfg.Log.Format, logging.FormatConsole, logging.FormatJSON)
+	}
+	if cfg.Log.MaxSizeMB < 1 {
+		problem("log.maxSizeMB", "muss mindestens 1 sein, nicht %d", cfg.Log.MaxSizeMB)
+	}
+	if cfg.Log.MaxAgeDays < 0 {
+		problem("log.maxAgeDays", "darf nicht negativ sein")
+	}
+	if cfg.Log.MaxBackups < 0 {
+		problem("log.maxBackups", "darf nicht negativ sein")
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// Shards are the shards this process runs
+func (cfg *Config) Shards() []int {
+	if len(cfg.ShardIDs) > 0 {
+		return cfg.ShardIDs
+	}
+	shards := make([]int, cfg.NumShards)
+	for i := range shards {
+		shards[i] = i
+	}
+	return shards
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func envOf(vars map[string]string) LookupEnv {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLayers(t *testing.T) {
+	file := writeFile(t, "config.yaml", "port: 9000\nadminPort: 9001\nnumShards: 4\nlog:\n  level: debug\n")
+	env := envOf(map[string]string{
+		"CONFIG_FILE":       file,
+		"DISCORD_BOT_TOKEN": "token",
+		"PORT":              "9100",
+		"SHARD_IDS":         "1, 3",
+	})
+
+	cfg, err := Load([]string{"-port", "9200", "-log-max-size-mb", "5"}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 9200 {
+		t.Errorf("der Port von der Kommandozeile sollte gewinnen, nicht %d", cfg.Port)
+	}
+	if cfg.AdminPort != 9001 || cfg.NumShards != 4 || cfg.Log.Level != "debug" {
+		t.Errorf("die Werte aus der Datei fehlen: %+v", cfg)
+	}
+	if len(cfg.ShardIDs) != 2 || cfg.ShardIDs[1] != 3 {
+		t.Errorf("SHARD_IDS falsch gelesen: %v", cfg.ShardIDs)
+	}
+	if cfg.Log.MaxSizeMB != 5 || cfg.ShutdownTimeout != 20 {
+		t.Errorf("Flag oder Standardwert fehlt: %+v", cfg.Log)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSecretFromFile(t *testing.T) {
+	secret := writeFile(t, "token", "geheim\n")
+	cfg, err := Load(nil, envOf(map[string]string{"DISCORD_BOT_TOKEN_FILE": secret}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DiscordToken != "geheim" {
+		t.Errorf("das Token sollte aus der Datei kommen, nicht %q", cfg.DiscordToken)
+	}
+
+	out := bytes.Buffer{}
+	if err := cfg.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "geheim") || !strings.Contains(out.String(), "discordToken: "+Redacted) {
+		t.Errorf("das Token darf nicht ausgegeben werden:\n%s", out.String())
+	}
+	if cfg.DiscordToken != "geheim" {
+		t.Error("die Ausgabe darf die Konfiguration selbst nicht ändern")
+	}
+
+	if _, err := Load([]string{"-discord-token", "x"}, envOf(nil)); err == nil {
+		t.Error("Tokens dürfen nicht auf der Kommandozeile stehen")
+	}
+}
+
+func TestPreciseErrors(t *testing.T) {
+	if _, err := Load(nil, envOf(map[string]string{"PORT": "8123,8124"})); err == nil || !strings.HasPrefix(err.Error(), "PORT:") {
+		t.Errorf("der Fehler sollte PORT nennen: %v", err)
+	}
+	if _, err := Load([]string{"-config", "gibtsnicht.yaml"}, envOf(nil)); err == nil {
+		t.Error("eine angegebene, aber fehlende Datei ist ein Fehler")
+	}
+	if _, err := Load(nil, envOf(map[string]string{"CONFIG_FILE": writeFile(t, "c.yaml", "prot: 1\n")})); err == nil {
+		t.Error("ein unbekannter Schlüssel in der Datei ist ein Fehler")
+	}
+
+	cfg, _ := Load(nil, envOf(map[string]string{"NUM_SHARDS": "2", "SHARD_IDS": "2", "SERVER_URL": "example.com"}))
+	err := cfg.Validate()
+	problems, ok := err.(ValidationError)
+	if !ok || len(problems) != 3 {
+		t.Fatalf("erwartet drei Probleme, bekommen %v", err)
+	}
+	for i, prefix := range []string{"discordToken (DISCORD_BOT_TOKEN)", "shardIDs (SHARD_IDS)", "serverURL (SERVER_URL)"} {
+		if !strings.HasPrefix(problems[i], prefix) {
+			t.Errorf("%q sollte mit %q beginnen", problems[i], prefix)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Redacted replaces the secrets in Print
+const Redacted = "<verborgen>"
+
+// LookupEnv is how the environment is read, os.LookupEnv outside of tests
+type LookupEnv func(key string) (string, bool)
+
+// field is one setting, found through the struct tags
+type field struct {
+	//the path in the YAML file, e.g. log.level
+	key    string
+	env    string
+	secret bool
+	desc   string
+	value  reflect.Value
+}
+
+// flagName is the key as a command line flag, e.g. log-max-size-mb for log.maxSizeMB
+func (f field) flagName() string {
+	name := strings.Builder{}
+	runes := []rune(f.key)
+	for i, r := range runes {
+		switch {
+		case r == '.':
+			name.WriteRune('-')
+		case r >= 'A' && r <= 'Z':
+			//only the start of a word, so ID stays one word
+			if i > 0 && runes[i-1] != '.' && !(runes[i-1] >= 'A' && runes[i-1] <= 'Z') {
+				name.WriteRune('-')
+			}
+			name.WriteRune(r - 'A' + 'a')
+		default:
+			name.WriteRune(r)
+		}
+	}
+	return name.String()
+}
+
+func fields(v reflect.Value, prefix string) []field {
+	all := make([]field, 0)
+	for i := 0; i < v.NumField(); i++ {
+		structField := v.Type().Field(i)
+		key := prefix + structField.Tag.Get("yaml")
+		if structField.Type.Kind() == reflect.Struct {
+			all = append(all, fields(v.Field(i), key+".")...)
+			continue
+		}
+		all = append(all, field{
+			key:    key,
+			env:    structField.Tag.Get("env"),
+			secret: structField.Tag.Get("secret") == "true",
+			desc:   structField.Tag.Get("desc"),
+			value:  v.Field(i),
+		})
+	}
+	return all
+}
+
+func (cfg *Config) fields() []field {
+	return fields(reflect.ValueOf(cfg).Elem(), "")
+}
+
+// describe names the setting the way it can be given, for error messages
+func (cfg *Config) describe(key string) string {
+	for _, f := range cfg.fields() {
+		if f.key == key {
+			return fmt.Sprintf("%s (%s)", key, f.env)
+		}
+	}
+	return key
+}
+
+func setValue(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		num, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q ist keine Zahl", raw)
+		}
+		v.SetInt(int64(num))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q ist weder true noch false", raw)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		nums := make([]int, 0)
+		for _, part := range strings.Split(raw, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			num, err := strconv.Atoi(part)
+			if err != nil {
+				return fmt.Errorf("%q ist keine Zahl", part)
+			}
+			nums = append(nums, num)
+		}
+		v.Set(reflect.ValueOf(nums))
+	default:
+		return fmt.Errorf("Typ %s wird nicht unterstützt", v.Type())
+	}
+	return nil
+}
+
+// flagValue remembers what was given on the command line, so it can be applied after the file and the environment
+type flagValue struct {
+	raw *string
+}
+
+func (fv flagValue) String() string {
+	if fv.raw == nil {
+		return ""
+	}
+	return *fv.raw
+}
+
+func (fv flagValue) Set(raw string) error {
+	*fv.raw = raw
+	return nil
+}
+
+// Load reads the configuration file, the environment and the command line arguments (without the program
+// name) over the defaults. It doesn't validate the result; that's up to Validate
+func Load(args []string, lookupEnv LookupEnv) (*Config, error) {
+	cfg := Default()
+	fieldList := cfg.fields()
+
+	flags := flag.NewFlagSet("amongusdiscord", flag.ContinueOnError)
+	configFile := flags.String("config", "", fmt.Sprintf("YAML-Datei mit der Konfiguration (CONFIG_FILE), sonst %s falls vorhanden", DefaultFile))
+	given := make(map[string]*string)
+	for _, f := range fieldList {
+		//tokens on the command line would end up in the process list
+		if f.secret {
+			continue
+		}
+		raw := new(string)
+		given[f.key] = raw
+		flags.Var(flagValue{raw: raw}, f.flagName(), fmt.Sprintf("%s (%s)", f.desc, f.env))
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unerwartetes Argument %q", flags.Arg(0))
+	}
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+	explicit := path != ""
+	if !explicit {
+		path = DefaultFile
+	}
+	if err := cfg.loadFile(path, explicit); err != nil {
+		return nil, err
+	}
+
+	for _, f := range fieldList {
+		raw, ok := lookupEnv(f.env)
+		if raw == "" && f.secret {
+			fileRaw, err := readSecretFile(f.env, lookupEnv)
+			if err != nil {
+				return nil, err
+			}
+			raw, ok = fileRaw, fileRaw != ""
+		}
+		if !ok || raw == "" {
+			continue
+		}
+		if err := setValue(f.value, raw); err != nil {
+			return nil, fmt.Errorf("%s: %s", f.env, err)
+		}
+	}
+
+	var err error
+	flags.Visit(func(fl *flag.Flag) {
+		for _, f := range fieldList {
+			if f.flagName() == fl.Name {
+				if setErr := setValue(f.value, *given[f.key]); setErr != nil && err == nil {
+					err = fmt.Errorf("-%s: %s", fl.Name, setErr)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// a missing file is only a problem if it was asked for
+func (cfg *Config) loadFile(path string, explicit bool) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return nil
+}
+
+func readSecretFile(env string, lookupEnv LookupEnv) (string, error) {
+	path, ok := lookupEnv(env + "_FILE")
+	if !ok || path == "" {
+		return "", nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s_FILE: %s", env, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Print writes the configuration as YAML, with the secrets that are set replaced by Redacted
+func (cfg *Config) Print(w io.Writer) error {
+	redacted := *cfg
+	for _, f := range redacted.fields() {
+		if f.secret && f.value.String() != "" {
+			f.value.SetString(Redacted)
+		}
+	}
+	data, err := yaml.Marshal(&redacted)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
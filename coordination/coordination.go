@@ -0,0 +1,63 @@
+package coordination
+
+import "errors"
+
+// ErrUnknownProcess is returned when an event is forwarded to a process that isn't listening for any
+var ErrUnknownProcess = errors.New("unbekannter Prozess")
+
+// ErrClaimed is returned when a shard or capture is claimed that another process already holds
+var ErrClaimed = errors.New("gehört schon einem anderen Prozess")
+
+// CaptureEvent is something a capture sent, on its way from the process that holds the capture's socket
+// to the process that runs the guild's shard
+type CaptureEvent struct {
+	//unique across all processes
+	ConnID  string `json:"connID"`
+	GuildID string `json:"guildID"`
+	//connectCode, lobby, state, player or disconnect
+	Name string `json:"name"`
+	Data string `json:"data"`
+}
+
+// Broadcast is a message for every guild on every shard, e.g. that the bot is shutting down
+type Broadcast struct {
+	Type int `json:"type"`
+	Data int `json:"data"`
+}
+
+// Coordinator is what the processes of one bot share, so that a capture can connect to any of them.
+// It knows the link codes, which process runs which shard and holds which guild's capture, and it carries
+// the events and broadcasts between the processes. The memory implementation is for a single process,
+// the Redis one for several
+type Coordinator interface {
+	// SetLinkCodes makes the connect code (and the lobby code, if there is one) point to the guild
+	SetLinkCodes(guildID, connectCode, gameCode string) error
+	// GuildForCode returns "" if no guild has the connect or lobby code
+	GuildForCode(code string) (string, error)
+	RemoveLinkCodes(guildID string) error
+
+	// ClaimShard fails with ErrClaimed if another process holds the shard. The claim is kept until it's released
+	// or the coordinator is closed
+	ClaimShard(shardID int, processID string) error
+	// ShardOwner returns "" if no process has claimed the shard
+	ShardOwner(shardID int) (string, error)
+	// ReleaseShard only releases the shard if the process still owns it
+	ReleaseShard(shardID int, processID string) error
+
+	// SetCaptureOwner fails with ErrClaimed if another process holds the guild's capture. The claim is kept until
+	// it's removed or the coordinator is closed
+	SetCaptureOwner(guildID, processID string) error
+	// CaptureOwner returns "" if no process holds a capture for the guild
+	CaptureOwner(guildID string) (string, error)
+	// RemoveCaptureOwner only removes the owner if it's still the process
+	RemoveCaptureOwner(guildID, processID string) error
+
+	// Forward hands the event to the process, which gets it through its OnCaptureEvent handler
+	Forward(processID string, event CaptureEvent) error
+	OnCaptureEvent(processID string, handler func(CaptureEvent)) error
+	// Broadcast hands the message to the OnBroadcast handlers of every process, this one included
+	Broadcast(msg Broadcast) error
+	OnBroadcast(handler func(Broadcast)) error
+
+	Close() error
+}
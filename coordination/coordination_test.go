@@ -0,0 +1,160 @@
+package coordination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testCoordinators runs through everything the bot needs, with two processes that share a backend
+func testCoordinators(t *testing.T, first, second Coordinator) {
+	if err := first.SetLinkCodes("100", "AAAA1111", "ABCDEF"); err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range []string{"AAAA1111", "ABCDEF"} {
+		if guildID, err := second.GuildForCode(code); err != nil || guildID != "100" {
+			t.Errorf("%s sollte zur Gilde 100 gehören, nicht %q (%v)", code, guildID, err)
+		}
+	}
+	if guildID, _ := second.GuildForCode("ZZZZ9999"); guildID != "" {
+		t.Errorf("ein unbekannter Code sollte zu keiner Gilde gehören, nicht zu %s", guildID)
+	}
+	//the lobby code is reused by another guild, so only the connect code goes away with the first one
+	first.SetLinkCodes("200", "BBBB2222", "ABCDEF")
+	if err := second.RemoveLinkCodes("100"); err != nil {
+		t.Fatal(err)
+	}
+	if guildID, _ := first.GuildForCode("AAAA1111"); guildID != "" {
+		t.Error("der Verbindungscode sollte entfernt sein")
+	}
+	if guildID, _ := first.GuildForCode("ABCDEF"); guildID != "200" {
+		t.Error("der Lobby-Code der anderen Gilde sollte bleiben")
+	}
+
+	if err := first.ClaimShard(0, "eins"); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.ClaimShard(0, "eins"); err != nil {
+		t.Errorf("der eigene Shard darf noch einmal beansprucht werden: %s", err)
+	}
+	if err := second.ClaimShard(0, "zwei"); err != ErrClaimed {
+		t.Errorf("Shard 0 gehört schon eins, Fehler war %v", err)
+	}
+	second.ClaimShard(1, "zwei")
+	second.ReleaseShard(0, "zwei")
+	if owner, _ := second.ShardOwner(0); owner != "eins" {
+		t.Errorf("Shard 0 sollte noch zu eins gehören, nicht zu %q", owner)
+	}
+	second.ReleaseShard(1, "zwei")
+	if owner, _ := first.ShardOwner(1); owner != "" {
+		t.Errorf("Shard 1 sollte freigegeben sein, gehört aber %q", owner)
+	}
+
+	first.SetCaptureOwner("100", "eins")
+	if err := second.SetCaptureOwner("100", "zwei"); err != ErrClaimed {
+		t.Errorf("die Erfassung gehört schon eins, Fehler war %v", err)
+	}
+	second.RemoveCaptureOwner("100", "zwei")
+	if owner, _ := second.CaptureOwner("100"); owner != "eins" {
+		t.Errorf("die Erfassung sollte noch bei eins sein, nicht bei %q", owner)
+	}
+
+	events := make(chan CaptureEvent, 1)
+	if err := second.OnCaptureEvent("zwei", func(event CaptureEvent) { events <- event }); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Forward("drei", CaptureEvent{}); err != ErrUnknownProcess {
+		t.Errorf("an einen unbekannten Prozess sollte nichts gehen, Fehler war %v", err)
+	}
+	sent := CaptureEvent{ConnID: "eins:1", GuildID: "100", Name: "state", Data: "1"}
+	if err := first.Forward("zwei", sent); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		if event != sent {
+			t.Errorf("erwartet %+v, bekommen %+v", sent, event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("das Ereignis ist nicht angekommen")
+	}
+
+	broadcasts := make(chan Broadcast, 2)
+	first.OnBroadcast(func(msg Broadcast) { broadcasts <- msg })
+	second.OnBroadcast(func(msg Broadcast) { broadcasts <- msg })
+	if err := first.Broadcast(Broadcast{Type: 0, Data: 30}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-broadcasts:
+			if msg.Data != 30 {
+				t.Errorf("falsche Rundsendung %+v", msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("die Rundsendung ist nicht bei beiden Prozessen angekommen")
+		}
+	}
+}
+
+func TestMemoryCoordinator(t *testing.T) {
+	mc := NewMemoryCoordinator()
+	testCoordinators(t, mc, mc)
+}
+
+func TestRedisCoordinator(t *testing.T) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	first, err := NewRedisCoordinator("redis://"+server.Addr(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := NewRedisCoordinator("redis://"+server.Addr(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	testCoordinators(t, first, second)
+
+	if ttl := server.TTL(DefaultRedisPrefix + "code:BBBB2222"); ttl != LinkCodeTTL {
+		t.Errorf("der Code sollte nach %s ablaufen, nicht nach %s", LinkCodeTTL, ttl)
+	}
+
+	shard := DefaultRedisPrefix + "shard:0"
+	if ttl := server.TTL(shard); ttl != ClaimTTL {
+		t.Errorf("der Shard sollte nach %s ablaufen, nicht nach %s", ClaimTTL, ttl)
+	}
+	server.FastForward(ClaimRefreshInterval)
+	first.refreshClaims()
+	if ttl := server.TTL(shard); ttl != ClaimTTL {
+		t.Errorf("der Anspruch sollte verlängert sein, läuft aber nach %s ab", ttl)
+	}
+	//a released claim must not come back with the next refresh
+	first.ReleaseShard(0, "eins")
+	first.refreshClaims()
+	if server.Exists(shard) {
+		t.Error("der freigegebene Shard wurde wieder beansprucht")
+	}
+
+	//a process that stopped refreshing loses its claim to the next one
+	server.FastForward(ClaimTTL)
+	if err := second.SetCaptureOwner("100", "zwei"); err != nil {
+		t.Errorf("die abgelaufene Erfassung sollte frei sein: %s", err)
+	}
+	first.refreshClaims()
+	if owner, _ := first.CaptureOwner("100"); owner != "zwei" {
+		t.Errorf("die Erfassung sollte bei zwei bleiben, nicht bei %q", owner)
+	}
+	first.lock.Lock()
+	defer first.lock.Unlock()
+	if len(first.claims) != 0 {
+		t.Errorf("eins sollte keine Ansprüche mehr halten: %v", first.claims)
+	}
+}
@@ -0,0 +1,145 @@
+package coordination
+
+import "sync"
+
+// MemoryCoordinator keeps everything in this process, which is all a bot needs that runs as one process
+type MemoryCoordinator struct {
+	codes        map[string]string
+	guildCodes   map[string][]string
+	shards       map[int]string
+	captures     map[string]string
+	eventHandler map[string]func(CaptureEvent)
+	broadcasts   []func(Broadcast)
+	lock         sync.RWMutex
+}
+
+func NewMemoryCoordinator() *MemoryCoordinator {
+	return &MemoryCoordinator{
+		codes:        make(map[string]string),
+		guildCodes:   make(map[string][]string),
+		shards:       make(map[int]string),
+		captures:     make(map[string]string),
+		eventHandler: make(map[string]func(CaptureEvent)),
+	}
+}
+
+func (mc *MemoryCoordinator) SetLinkCodes(guildID, connectCode, gameCode string) error {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	for _, code := range []string{connectCode, gameCode} {
+		if code == "" {
+			continue
+		}
+		mc.codes[code] = guildID
+		mc.guildCodes[guildID] = append(mc.guildCodes[guildID], code)
+	}
+	return nil
+}
+
+func (mc *MemoryCoordinator) GuildForCode(code string) (string, error) {
+	mc.lock.RLock()
+	defer mc.lock.RUnlock()
+	return mc.codes[code], nil
+}
+
+func (mc *MemoryCoordinator) RemoveLinkCodes(guildID string) error {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	for _, code := range mc.guildCodes[guildID] {
+		//the code might have been given to another guild since
+		if mc.codes[code] == guildID {
+			delete(mc.codes, code)
+		}
+	}
+	delete(mc.guildCodes, guildID)
+	return nil
+}
+
+func (mc *MemoryCoordinator) ClaimShard(shardID int, processID string) error {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	if owner, ok := mc.shards[shardID]; ok && owner != processID {
+		return ErrClaimed
+	}
+	mc.shards[shardID] = processID
+	return nil
+}
+
+func (mc *MemoryCoordinator) ShardOwner(shardID int) (string, error) {
+	mc.lock.RLock()
+	defer mc.lock.RUnlock()
+	return mc.shards[shardID], nil
+}
+
+func (mc *MemoryCoordinator) ReleaseShard(shardID int, processID string) error {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	if mc.shards[shardID] == processID {
+		delete(mc.shards, shardID)
+	}
+	return nil
+}
+
+func (mc *MemoryCoordinator) SetCaptureOwner(guildID, processID string) error {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	if owner, ok := mc.captures[guildID]; ok && owner != processID {
+		return ErrClaimed
+	}
+	mc.captures[guildID] = processID
+	return nil
+}
+
+func (mc *MemoryCoordinator) CaptureOwner(guildID string) (string, error) {
+	mc.lock.RLock()
+	defer mc.lock.RUnlock()
+	return mc.captures[guildID], nil
+}
+
+func (mc *MemoryCoordinator) RemoveCaptureOwner(guildID, processID string) error {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	if mc.captures[guildID] == processID {
+		delete(mc.captures, guildID)
+	}
+	return nil
+}
+
+func (mc *MemoryCoordinator) Forward(processID string, event CaptureEvent) error {
+	mc.lock.RLock()
+	handler, ok := mc.eventHandler[processID]
+	mc.lock.RUnlock()
+	if !ok {
+		return ErrUnknownProcess
+	}
+	handler(event)
+	return nil
+}
+
+func (mc *MemoryCoordinator) OnCaptureEvent(processID string, handler func(CaptureEvent)) error {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	mc.eventHandler[processID] = handler
+	return nil
+}
+
+func (mc *MemoryCoordinator) Broadcast(msg Broadcast) error {
+	mc.lock.RLock()
+	handlers := append([]func(Broadcast){}, mc.broadcasts...)
+	mc.lock.RUnlock()
+	for _, handler := range handlers {
+		handler(msg)
+	}
+	return nil
+}
+
+func (mc *MemoryCoordinator) OnBroadcast(handler func(Broadcast)) error {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	mc.broadcasts = append(mc.broadcasts, handler)
+	return nil
+}
+
+func (mc *MemoryCoordinator) Close() error {
+	return nil
+}
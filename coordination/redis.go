@@ -0,0 +1,302 @@
+package coordination
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/denverquane/amongusdiscord/logging"
+	"github.com/go-redis/redis/v8"
+)
+
+const DefaultRedisPrefix = "amongusdiscord:"
+
+// LinkCodeTTL is how long a link code is kept. The process that made it may be gone by then, so codes
+// can't be left for it to clean up
+const LinkCodeTTL = 24 * time.Hour
+
+// ClaimTTL is how long a shard or capture stays claimed by a process that stopped refreshing it, e.g. because
+// it crashed. Until then no other process can take it over
+const ClaimTTL = 30 * time.Second
+
+// ClaimRefreshInterval is how often a process renews the claims it holds
+const ClaimRefreshInterval = ClaimTTL / 3
+
+// sets the key if nobody has it yet or renews it if it already has the value, and returns 1 if the value is
+// the key's owner now
+var claim = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0`)
+
+// renews the key, but only if it still has the value; unlike claim it never sets a key that's gone
+var expireIfEqual = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// deletes the key, but only if it still has the value
+var deleteIfEqual = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// RedisCoordinator shares the state through Redis, so that the bot can run as several processes
+type RedisCoordinator struct {
+	client  *redis.Client
+	prefix  string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	pubsubs []*redis.PubSub
+	//the shards and captures this process holds, by key, so they're renewed
+	claims map[string]string
+	lock   sync.Mutex
+}
+
+// NewRedisCoordinator connects to Redis with a URL like redis://:password@localhost:6379/0
+func NewRedisCoordinator(redisURL, prefix string) (*RedisCoordinator, error) {
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	if prefix == "" {
+		prefix = DefaultRedisPrefix
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	rc := &RedisCoordinator{
+		client: redis.NewClient(options),
+		prefix: prefix,
+		ctx:    ctx,
+		cancel: cancel,
+		claims: make(map[string]string),
+	}
+	if err := rc.client.Ping(ctx).Err(); err != nil {
+		rc.Close()
+		return nil, err
+	}
+	go rc.keepClaims()
+	return rc, nil
+}
+
+func (rc *RedisCoordinator) key(parts ...string) string {
+	key := rc.prefix
+	for i, part := range parts {
+		if i > 0 {
+			key += ":"
+		}
+		key += part
+	}
+	return key
+}
+
+func (rc *RedisCoordinator) get(key string) (string, error) {
+	value, err := rc.client.Get(rc.ctx, key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return value, err
+}
+
+func (rc *RedisCoordinator) SetLinkCodes(guildID, connectCode, gameCode string) error {
+	pipe := rc.client.TxPipeline()
+	for _, code := range []string{connectCode, gameCode} {
+		if code == "" {
+			continue
+		}
+		pipe.Set(rc.ctx, rc.key("code", code), guildID, LinkCodeTTL)
+		pipe.SAdd(rc.ctx, rc.key("guildcodes", guildID), code)
+	}
+	pipe.Expire(rc.ctx, rc.key("guildcodes", guildID), LinkCodeTTL)
+	_, err := pipe.Exec(rc.ctx)
+	return err
+}
+
+func (rc *RedisCoordinator) GuildForCode(code string) (string, error) {
+	return rc.get(rc.key("code", code))
+}
+
+func (rc *RedisCoordinator) RemoveLinkCodes(guildID string) error {
+	codes, err := rc.client.SMembers(rc.ctx, rc.key("guildcodes", guildID)).Result()
+	if err != nil {
+		return err
+	}
+	for _, code := range codes {
+		//the code might have been given to another guild since
+		if err := deleteIfEqual.Run(rc.ctx, rc.client, []string{rc.key("code", code)}, guildID).Err(); err != nil {
+			return err
+		}
+	}
+	return rc.client.Del(rc.ctx, rc.key("guildcodes", guildID)).Err()
+}
+
+func (rc *RedisCoordinator) ClaimShard(shardID int, processID string) error {
+	return rc.claim(rc.key("shard", strconv.Itoa(shardID)), processID)
+}
+
+func (rc *RedisCoordinator) ShardOwner(shardID int) (string, error) {
+	return rc.get(rc.key("shard", strconv.Itoa(shardID)))
+}
+
+func (rc *RedisCoordinator) ReleaseShard(shardID int, processID string) error {
+	return rc.release(rc.key("shard", strconv.Itoa(shardID)), processID)
+}
+
+func (rc *RedisCoordinator) SetCaptureOwner(guildID, processID string) error {
+	return rc.claim(rc.key("capture", guildID), processID)
+}
+
+func (rc *RedisCoordinator) CaptureOwner(guildID string) (string, error) {
+	return rc.get(rc.key("capture", guildID))
+}
+
+func (rc *RedisCoordinator) RemoveCaptureOwner(guildID, processID string) error {
+	return rc.release(rc.key("capture", guildID), processID)
+}
+
+func (rc *RedisCoordinator) claim(key, processID string) error {
+	claimed, err := claim.Run(rc.ctx, rc.client, []string{key}, processID, ClaimTTL.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if claimed == 0 {
+		return ErrClaimed
+	}
+	rc.lock.Lock()
+	rc.claims[key] = processID
+	rc.lock.Unlock()
+	return nil
+}
+
+func (rc *RedisCoordinator) release(key, processID string) error {
+	rc.lock.Lock()
+	if rc.claims[key] == processID {
+		delete(rc.claims, key)
+	}
+	rc.lock.Unlock()
+	return deleteIfEqual.Run(rc.ctx, rc.client, []string{key}, processID).Err()
+}
+
+// keepClaims renews the claims until the coordinator is closed
+func (rc *RedisCoordinator) keepClaims() {
+	ticker := time.NewTicker(ClaimRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rc.ctx.Done():
+			return
+		case <-ticker.C:
+			rc.refreshClaims()
+		}
+	}
+}
+
+// refreshClaims renews every claim this process holds. A claim that ran out and was taken by another process
+// is given up
+func (rc *RedisCoordinator) refreshClaims() {
+	rc.lock.Lock()
+	claims := make(map[string]string, len(rc.claims))
+	for key, processID := range rc.claims {
+		claims[key] = processID
+	}
+	rc.lock.Unlock()
+
+	for key, processID := range claims {
+		renewed, err := expireIfEqual.Run(rc.ctx, rc.client, []string{key}, processID, ClaimTTL.Milliseconds()).Int()
+		if err != nil {
+			logging.Warnf("%s konnte nicht verlängert werden: %s", key, err)
+			continue
+		}
+		if renewed == 0 {
+			logging.Errorf("%s gehört nicht mehr %s, der Anspruch ist abgelaufen", key, processID)
+			rc.lock.Lock()
+			if rc.claims[key] == processID {
+				delete(rc.claims, key)
+			}
+			rc.lock.Unlock()
+		}
+	}
+}
+
+func (rc *RedisCoordinator) Forward(processID string, event CaptureEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	receivers, err := rc.client.Publish(rc.ctx, rc.key("events", processID), payload).Result()
+	if err != nil {
+		return err
+	}
+	if receivers == 0 {
+		return ErrUnknownProcess
+	}
+	return nil
+}
+
+func (rc *RedisCoordinator) OnCaptureEvent(processID string, handler func(CaptureEvent)) error {
+	return rc.subscribe(rc.key("events", processID), func(payload string) {
+		event := CaptureEvent{}
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			logging.Errorf("Ungültiges Capture-Ereignis von Redis: %s", err)
+			return
+		}
+		handler(event)
+	})
+}
+
+func (rc *RedisCoordinator) Broadcast(msg Broadcast) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return rc.client.Publish(rc.ctx, rc.key("broadcast"), payload).Err()
+}
+
+func (rc *RedisCoordinator) OnBroadcast(handler func(Broadcast)) error {
+	return rc.subscribe(rc.key("broadcast"), func(payload string) {
+		msg := Broadcast{}
+		if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+			logging.Errorf("Ungültige Rundsendung von Redis: %s", err)
+			return
+		}
+		handler(msg)
+	})
+}
+
+// subscribe only returns once the subscription is confirmed, so nothing published afterwards is missed
+func (rc *RedisCoordinator) subscribe(channel string, handler func(payload string)) error {
+	pubsub := rc.client.Subscribe(rc.ctx, channel)
+	if _, err := pubsub.Receive(rc.ctx); err != nil {
+		pubsub.Close()
+		return err
+	}
+	rc.lock.Lock()
+	rc.pubsubs = append(rc.pubsubs, pubsub)
+	rc.lock.Unlock()
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			handler(msg.Payload)
+		}
+	}()
+	return nil
+}
+
+func (rc *RedisCoordinator) Close() error {
+	rc.lock.Lock()
+	for _, pubsub := range rc.pubsubs {
+		pubsub.Close()
+	}
+	rc.pubsubs = nil
+	rc.lock.Unlock()
+	rc.cancel()
+	return rc.client.Close()
+}
@@ -0,0 +1,362 @@
+package discord
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/coordination"
+	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/logging"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultShutdownDelay is how many seconds running games get to finish when a shutdown doesn't say otherwise
+const DefaultShutdownDelay = 30
+
+type adminAPI struct {
+	token       string
+	bots        []*Bot
+	coordinator coordination.Coordinator
+}
+
+type guildSummaryJSON struct {
+	GuildID         string `json:"guildID"`
+	GameRunning     bool   `json:"gameRunning"`
+	CaptureLinked   bool   `json:"captureLinked"`
+	Phase           string `json:"phase"`
+	Room            string `json:"room"`
+	Region          string `json:"region"`
+	DetectedPlayers int    `json:"detectedPlayers"`
+	LinkedPlayers   int    `json:"linkedPlayers"`
+}
+
+type shardJSON struct {
+	ShardID           int                `json:"shardID"`
+	ConnectedCaptures int                `json:"connectedCaptures"`
+	Guilds            []guildSummaryJSON `json:"guilds"`
+}
+
+type linkJSON struct {
+	UserID     string `json:"userID"`
+	UserName   string `json:"userName"`
+	PlayerName string `json:"playerName"`
+	Color      string `json:"color"`
+	Alive      bool   `json:"alive"`
+}
+
+type trackingJSON struct {
+	ChannelID   string `json:"channelID"`
+	ChannelName string `json:"channelName"`
+	ForGhosts   bool   `json:"forGhosts"`
+}
+
+type guildDetailJSON struct {
+	guildSummaryJSON
+	ShardID        int            `json:"shardID"`
+	QueueDepth     int            `json:"queueDepth"`
+	CaptureProcess string         `json:"captureProcess,omitempty"`
+	Links          []linkJSON     `json:"links"`
+	Tracking       []trackingJSON `json:"tracking"`
+}
+
+// AdminServer serves /metrics, and the token-authenticated admin API under /api. Without a token the API
+// stays disabled, since it can end games and shut the bot down. Broadcasts go through the coordinator, so
+// they reach the shards of the other processes too
+func AdminServer(port, token string, bots []*Bot, coordinator coordination.Coordinator) {
+	api := &adminAPI{
+		token:       token,
+		bots:        bots,
+		coordinator: coordinator,
+	}
+	if token == "" {
+		logging.Warn("Kein ADMIN_API_TOKEN bereitgestellt, die Admin-API ist deaktiviert")
+	}
+
+	logging.Infof("Admin-API und Metriken laufen auf Port %s", port)
+	logging.Fatalf("%s", http.ListenAndServe(":"+port, api.router()))
+}
+
+func (api *adminAPI) router() *mux.Router {
+	router := mux.NewRouter()
+	router.Handle("/metrics", promhttp.Handler())
+	if api.token == "" {
+		return router
+	}
+
+	apiRouter := router.PathPrefix("/api").Subrouter()
+	apiRouter.Use(api.authenticate)
+	apiRouter.HandleFunc("/shards", api.listShards).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/guilds/{guildID}", api.getGuild).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/guilds/{guildID}/end", api.endGame).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/guilds/{guildID}/pause", api.pauseGame).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/guilds/{guildID}/unmute", api.unmuteGuild).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/broadcast", api.broadcast).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/shutdown", api.shutdown).Methods(http.MethodPost)
+
+	//kept for the scripts that used the old endpoint, but it needs the token now too
+	router.Handle("/graceful", api.authenticate(http.HandlerFunc(api.shutdown)))
+	return router
+}
+
+func (api *adminAPI) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(api.token)) != 1 {
+			logging.Warnf("Nicht autorisierte Anfrage an %s von %s", r.URL.Path, r.RemoteAddr)
+			writeJSONError(w, http.StatusUnauthorized, "Ungültiges oder fehlendes Token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		logging.Error(err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// findGuild looks the guild up on every shard
+func (api *adminAPI) findGuild(guildID string) (*Bot, *GuildState) {
+	for _, bot := range api.bots {
+		if bot == nil {
+			continue
+		}
+		if guild, ok := bot.AllGuilds.Get(guildID); ok {
+			return bot, guild
+		}
+	}
+	return nil, nil
+}
+
+// guildFromRequest writes the error response itself if there's no such guild
+func (api *adminAPI) guildFromRequest(w http.ResponseWriter, r *http.Request) (*Bot, *GuildState) {
+	bot, guild := api.findGuild(mux.Vars(r)["guildID"])
+	if guild == nil {
+		writeJSONError(w, http.StatusNotFound, "Keine Gilde mit dieser ID auf einem der Shards")
+	}
+	return bot, guild
+}
+
+func summarizeGuild(guild *GuildState) guildSummaryJSON {
+	room, region := guild.AmongUsData.GetRoomRegion()
+	return guildSummaryJSON{
+		GuildID:         guild.PGD().GuildID,
+		GameRunning:     guild.IsGameRunning(),
+		CaptureLinked:   guild.IsLinked(),
+		Phase:           phaseLabel(guild.AmongUsData.GetPhase()),
+		Room:            room,
+		Region:          region,
+		DetectedPlayers: guild.AmongUsData.NumDetectedPlayers(),
+		LinkedPlayers:   guild.UserData.GetCountLinked(),
+	}
+}
+
+func (api *adminAPI) listShards(w http.ResponseWriter, r *http.Request) {
+	shards := make([]shardJSON, 0, len(api.bots))
+	for _, bot := range api.bots {
+		if bot == nil {
+			continue
+		}
+		shard := shardJSON{
+			ShardID:           bot.shardID,
+			ConnectedCaptures: bot.AllConns.Len(),
+			Guilds:            make([]guildSummaryJSON, 0, bot.AllGuilds.Len()),
+		}
+		for _, guild := range bot.AllGuilds.All() {
+			shard.Guilds = append(shard.Guilds, summarizeGuild(guild))
+		}
+		shards = append(shards, shard)
+	}
+	writeJSON(w, http.StatusOK, shards)
+}
+
+func (api *adminAPI) getGuild(w http.ResponseWriter, r *http.Request) {
+	bot, guild := api.guildFromRequest(w, r)
+	if guild == nil {
+		return
+	}
+	detail := guildDetailJSON{
+		guildSummaryJSON: summarizeGuild(guild),
+		ShardID:          bot.shardID,
+		QueueDepth:       bot.EventBus.Depth(guild.PGD().GuildID),
+		Links:            make([]linkJSON, 0),
+		Tracking:         make([]trackingJSON, 0),
+	}
+	if owner, err := bot.Coordinator.CaptureOwner(guild.PGD().GuildID); err == nil {
+		detail.CaptureProcess = owner
+	}
+	for _, user := range guild.UserData.GetLinkedUsers() {
+		detail.Links = append(detail.Links, linkJSON{
+			UserID:     user.GetID(),
+			UserName:   user.GetUserName(),
+			PlayerName: user.GetPlayerName(),
+			Color:      game.GetColorStringForInt(user.GetColor()),
+			Alive:      user.IsAlive(),
+		})
+	}
+	for _, channel := range guild.Tracking.Channels() {
+		detail.Tracking = append(detail.Tracking, trackingJSON{
+			ChannelID:   channel.channelID,
+			ChannelName: channel.channelName,
+			ForGhosts:   channel.forGhosts,
+		})
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+// endGame ends the game once the guild's listener gets to it, after the capture events already waiting
+func (api *adminAPI) endGame(w http.ResponseWriter, r *http.Request) {
+	bot, guild := api.guildFromRequest(w, r)
+	if guild == nil {
+		return
+	}
+	api.pushControl(w, bot, guild, EndGameCommand)
+}
+
+// pauseGame pauses the game, or resumes it with {"paused": false}, like endGame through the listener
+func (api *adminAPI) pauseGame(w http.ResponseWriter, r *http.Request) {
+	bot, guild := api.guildFromRequest(w, r)
+	if guild == nil {
+		return
+	}
+	body := struct {
+		Paused *bool `json:"paused"`
+	}{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Ungültiges JSON: "+err.Error())
+			return
+		}
+	}
+	command := PauseGameCommand
+	if body.Paused != nil && !*body.Paused {
+		command = ResumeGameCommand
+	}
+	api.pushControl(w, bot, guild, command)
+}
+
+// pushControl hands the command to the guild's listener, so it doesn't race with the capture events it's applying
+func (api *adminAPI) pushControl(w http.ResponseWriter, bot *Bot, guild *GuildState, command ControlCommand) {
+	if !bot.PushGuildControlUpdate(guild.PGD().GuildID, command) {
+		writeJSONError(w, http.StatusServiceUnavailable, "Die Gilde nimmt gerade keine Updates an")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]int{"queued": bot.EventBus.Depth(guild.PGD().GuildID)})
+}
+
+func (api *adminAPI) unmuteGuild(w http.ResponseWriter, r *http.Request) {
+	bot, guild := api.guildFromRequest(w, r)
+	if guild == nil {
+		return
+	}
+	count, err := bot.unmuteEveryone(guild)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	guild.logger().Infof("%d Benutzer über die Admin-API entstummt", count)
+	writeJSON(w, http.StatusOK, map[string]int{"unmuted": count})
+}
+
+func (api *adminAPI) broadcast(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		Message string `json:"message"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Ungültiges JSON: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(body.Message) == "" {
+		writeJSONError(w, http.StatusBadRequest, "Die Nachricht darf nicht leer sein")
+		return
+	}
+
+	sent := 0
+	for _, bot := range api.bots {
+		if bot == nil {
+			continue
+		}
+		for _, guild := range bot.AllGuilds.All() {
+			channelID := guild.GameStateMsg.ChannelID()
+			if channelID == "" {
+				continue
+			}
+			if sendMessage(bot.SessionManager.GetPrimarySession(), channelID, "**"+body.Message+"**") != nil {
+				sent++
+			}
+		}
+	}
+	logging.Infof("Nachricht über die Admin-API an %d Spielkanäle gesendet", sent)
+	writeJSON(w, http.StatusOK, map[string]int{"sent": sent})
+}
+
+// shutdown ends every game after the delay, {"delay": seconds}; the default is DefaultShutdownDelay
+func (api *adminAPI) shutdown(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		Delay *int `json:"delay"`
+	}{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Ungültiges JSON: "+err.Error())
+			return
+		}
+	}
+	delay := DefaultShutdownDelay
+	if body.Delay != nil {
+		if *body.Delay < 0 {
+			writeJSONError(w, http.StatusBadRequest, "Die Verzögerung darf nicht negativ sein")
+			return
+		}
+		delay = *body.Delay
+	}
+
+	err := api.coordinator.Broadcast(coordination.Broadcast{
+		Type: int(GRACEFUL_SHUTDOWN),
+		Data: delay,
+	})
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Rundsendung fehlgeschlagen: "+err.Error())
+		return
+	}
+	logging.Infof("Herunterfahren über die Admin-API in %d Sekunden angefordert", delay)
+	writeJSON(w, http.StatusAccepted, map[string]int{"delay": delay})
+}
+
+// unmuteEveryone lifts the server mute and deafen from everyone in the guild's voice channels
+func (bot *Bot) unmuteEveryone(guild *GuildState) (int, error) {
+	g, err := bot.SessionManager.GetPrimarySession().CachedGuild(guild.PGD().GuildID)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, voiceState := range g.VoiceStates {
+		if !voiceState.Mute && !voiceState.Deaf {
+			continue
+		}
+		userData, err := guild.UserData.GetUser(voiceState.UserID)
+		if err != nil {
+			userData = game.MakeUserDataFromDiscordUser(&discordgo.User{ID: voiceState.UserID}, "")
+		}
+		s := bot.SessionManager.GetSessionForRequest()
+		outcome := guildMemberUpdate(s, UserPatchParameters{guild.PGD().GuildID, userData, false, false, ""})
+		memberUpdates.WithLabelValues(outcome, bot.SessionManager.sessionLabel(s)).Inc()
+		if outcome != MemberUpdateFailed {
+			guild.UserData.SetApplied(voiceState.UserID, false, false)
+			count++
+		}
+	}
+	return count, nil
+}
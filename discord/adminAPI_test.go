@@ -0,0 +1,141 @@
+package discord
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/denverquane/amongusdiscord/coordination"
+	"github.com/denverquane/amongusdiscord/game"
+)
+
+func testAdminAPI() *adminAPI {
+	coordinator := coordination.NewMemoryCoordinator()
+	bot := &Bot{
+		shardID:     1,
+		AllConns:    MakeConnRegistry(),
+		AllGuilds:   MakeGuildRegistry(),
+		Coordinator: coordinator,
+	}
+	bot.AllConns.Set("socket", "123")
+	guild := &GuildState{
+		persistentGuildData: PGDDefault("123"),
+		UserData:            MakeUserDataSet(),
+		Tracking:            MakeTracking(),
+		AmongUsData:         game.NewAmongUsData(),
+	}
+	guild.SetGameRunning(true)
+	guild.Tracking.AddTrackedChannel("456", "Among Us", false)
+	bot.AllGuilds.Set("123", guild)
+	return &adminAPI{token: "geheim", bots: []*Bot{bot}, coordinator: coordinator}
+}
+
+func TestAdminAPIRequiresToken(t *testing.T) {
+	router := testAdminAPI().router()
+
+	for _, auth := range []string{"", "Bearer falsch"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/shards", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("mit %q: Status %d, erwartet %d", auth, rec.Code, http.StatusUnauthorized)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/graceful", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("/graceful ohne Token: Status %d", rec.Code)
+	}
+}
+
+func TestAdminAPIDisabledWithoutToken(t *testing.T) {
+	api := testAdminAPI()
+	api.token = ""
+	req := httptest.NewRequest(http.MethodGet, "/api/shards", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	api.router().ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Status %d, erwartet %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestAdminAPIGuilds(t *testing.T) {
+	router := testAdminAPI().router()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/shards", nil)
+	req.Header.Set("Authorization", "Bearer geheim")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status %d: %s", rec.Code, rec.Body.String())
+	}
+	var shards []shardJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &shards); err != nil {
+		t.Fatal(err)
+	}
+	if len(shards) != 1 || shards[0].ShardID != 1 || shards[0].ConnectedCaptures != 1 || len(shards[0].Guilds) != 1 || !shards[0].Guilds[0].GameRunning {
+		t.Errorf("unerwartete Shards: %+v", shards)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/guilds/123", nil)
+	req.Header.Set("Authorization", "Bearer geheim")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	var detail guildDetailJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &detail); err != nil {
+		t.Fatal(err)
+	}
+	if detail.GuildID != "123" || len(detail.Tracking) != 1 || detail.Tracking[0].ChannelName != "Among Us" {
+		t.Errorf("unerwartete Gilde: %+v", detail)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/guilds/999", nil)
+	req.Header.Set("Authorization", "Bearer geheim")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unbekannte Gilde: Status %d", rec.Code)
+	}
+}
+
+func TestAdminAPIControlsGoThroughTheListener(t *testing.T) {
+	api := testAdminAPI()
+	router := api.router()
+	bot := api.bots[0]
+	guild, _ := bot.AllGuilds.Get("123")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/guilds/123/pause", nil)
+	req.Header.Set("Authorization", "Bearer geheim")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("ohne Warteschlange: Status %d, erwartet %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	bot.EventBus = MakeEventBus()
+	queue := bot.EventBus.Register("123")
+	for _, path := range []string{"/pause", "/end"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/guilds/123"+path, nil)
+		req.Header.Set("Authorization", "Bearer geheim")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("%s: Status %d, erwartet %d", path, rec.Code, http.StatusAccepted)
+		}
+	}
+	if !guild.IsGameRunning() {
+		t.Error("erst der Listener darf das Spiel pausieren")
+	}
+	for _, want := range []ControlCommand{PauseGameCommand, EndGameCommand} {
+		if update, _ := queue.Next(); update.Type != ControlUpdate || update.Control != want {
+			t.Errorf("erwartet Befehl %d, bekommen %+v", want, update)
+		}
+	}
+}
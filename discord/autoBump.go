@@ -0,0 +1,46 @@
+package discord
+
+import (
+	"time"
+
+	"github.com/denverquane/amongusdiscord/game"
+)
+
+// MinBumpInterval is how long the status message stays where it is at least, so a busy channel doesn't have it
+// posted again with every few messages
+const MinBumpInterval = 15 * time.Second
+
+// MaxAutoBump is the most messages the AutoBump setting can wait for; by then nobody would find the message anyway
+const MaxAutoBump = 100
+
+// refreshStatusMessage deletes the status message and posts it again at the bottom of the channel. The mirrors
+// stay, nobody chats below them
+func (guild *GuildState) refreshStatusMessage(s DiscordClient, channelID string) {
+	guild.GameStateMsg.DeleteMessage(s) //delete the old message
+	//a mirror in the channel the message moves to would be a second copy
+	guild.GameStateMsg.RemoveMirror(s, channelID)
+
+	//create a new instance of the new one; mirrors that are still there aren't posted again
+	guild.createStatusMessage(s, channelID, guild.GameStateMsg.leaderID)
+
+	//add the controls to the refreshed message if in the right stage
+	if guild.AmongUsData.GetPhase() != game.MENU {
+		guild.addLinkControls(s)
+	}
+}
+
+// autoBump posts the status message again at the bottom of its channel, if the guild wants that and enough messages
+// were posted below it. At phase changes, any message at all is enough. Returns true if it was bumped
+func (guild *GuildState) autoBump(s DiscordClient, phaseChange bool) bool {
+	threshold := guild.PGD().AutoBump
+	if threshold <= 0 {
+		return false
+	}
+	channelID := guild.GameStateMsg.ChannelID()
+	if channelID == "" || !guild.GameStateMsg.ClaimBump(threshold, phaseChange, MinBumpInterval) {
+		return false
+	}
+	guild.logger().Debugf("Statusmeldung wird nach unten geholt (Phasenwechsel? %v)", phaseChange)
+	guild.refreshStatusMessage(s, channelID)
+	return true
+}
@@ -0,0 +1,63 @@
+package discord
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func messageFrom(posted *discordgo.Message, authorID string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{Message: &discordgo.Message{
+		ID:        posted.ID,
+		ChannelID: posted.ChannelID,
+		GuildID:   flowGuildID,
+		Content:   posted.Content,
+		Author:    &discordgo.User{ID: authorID},
+	}}
+}
+
+func TestAutoBump(t *testing.T) {
+	bot, fake := fakeBot(t)
+	guild, _ := bot.AllGuilds.Get(flowGuildID)
+	guild.PGD().AutoBump = 2
+
+	sendCommand(bot, fake, ".au new ABCDEF eu")
+	first := guild.GameStateMsg.message.ID
+	chat := func() {
+		posted, _ := fake.ChannelMessageSend(flowTextID, "hallo")
+		bot.handleMessageCreate(guild, fake, messageFrom(posted, flowAliceID))
+	}
+
+	chat()
+	chat()
+	if guild.GameStateMsg.message.ID != first {
+		t.Fatal("die Statusmeldung wurde gleich nach dem Posten schon wieder nach unten geholt")
+	}
+
+	guild.GameStateMsg.created = time.Now().Add(-MinBumpInterval)
+	chat()
+	bumped := guild.GameStateMsg.message.ID
+	if bumped == first {
+		t.Fatal("die Statusmeldung sollte nach unten geholt worden sein")
+	}
+	messages := fake.Messages(flowTextID)
+	if messages[len(messages)-1].ID != bumped {
+		t.Error("die Statusmeldung sollte die letzte Nachricht sein")
+	}
+	waitFor(t, "die alte Statusmeldung ist gelöscht", func() bool {
+		for _, msg := range fake.Messages(flowTextID) {
+			if msg.ID == first {
+				return false
+			}
+		}
+		return true
+	})
+
+	//at phase changes, one message below is enough
+	guild.GameStateMsg.created = time.Now().Add(-MinBumpInterval)
+	chat()
+	if guild.GameStateMsg.message.ID != bumped || !guild.autoBump(fake, true) {
+		t.Error("beim Phasenwechsel sollte schon eine Nachricht reichen")
+	}
+}
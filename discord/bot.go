@@ -1,27 +1,17 @@
 package discord
 
 import (
-	"encoding/json"
-	"fmt"
-	"log"
-	"net/http"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/coordination"
 	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/logging"
 	"github.com/denverquane/amongusdiscord/storage"
-	socketio "github.com/googollee/go-socket.io"
-	"github.com/gorilla/mux"
 )
 
-type GameOrLobbyCode struct {
-	gameCode    string
-	connectCode string
-}
-
 type BcastMsgType int
 
 const (
@@ -45,13 +35,13 @@ type SocketStatus struct {
 }
 
 type SessionManager struct {
-	PrimarySession *discordgo.Session
-	AltSession     *discordgo.Session
+	PrimarySession DiscordClient
+	AltSession     DiscordClient
 	count          int
 	countLock      sync.Mutex
 }
 
-func NewSessionManager(primary, secondary *discordgo.Session) SessionManager {
+func NewSessionManager(primary, secondary DiscordClient) SessionManager {
 	return SessionManager{
 		PrimarySession: primary,
 		AltSession:     secondary,
@@ -60,11 +50,11 @@ func NewSessionManager(primary, secondary *discordgo.Session) SessionManager {
 	}
 }
 
-func (sm *SessionManager) GetPrimarySession() *discordgo.Session {
+func (sm *SessionManager) GetPrimarySession() DiscordClient {
 	return sm.PrimarySession
 }
 
-func (sm *SessionManager) GetSessionForRequest() *discordgo.Session {
+func (sm *SessionManager) GetSessionForRequest() DiscordClient {
 	if sm.AltSession == nil {
 		return sm.PrimarySession
 	}
@@ -73,10 +63,10 @@ func (sm *SessionManager) GetSessionForRequest() *discordgo.Session {
 
 	sm.count++
 	if sm.count%2 == 0 {
-		log.Println("Primärsitzung für Anfrage verwenden")
+		logging.Debug("Primärsitzung für Anfrage verwenden")
 		return sm.PrimarySession
 	} else {
-		log.Println("Verwenden der sekundären Sitzung zur Anforderung")
+		logging.Debug("Verwenden der sekundären Sitzung zur Anforderung")
 		return sm.AltSession
 	}
 }
@@ -92,118 +82,128 @@ func (sm *SessionManager) Close() {
 }
 
 type Bot struct {
-	url                     string
-	socketPort              string
-	extPort                 string
-	AllConns                map[string]string
-	AllGuilds               map[string]*GuildState
-	LinkCodes               map[GameOrLobbyCode]string
-	GamePhaseUpdateChannels map[string]*chan game.Phase
-
-	PlayerUpdateChannels map[string]*chan game.Player
-
-	SocketUpdateChannels map[string]*chan SocketStatus
+	shardID int
+	//set once Shutdown starts; accessed atomically
+	shuttingDown int32
 
-	GlobalBroadcastChannels map[string]*chan BroadcastMessage
+	url        string
+	socketPort string
+	extPort    string
+	AllConns   ConnRegistry
+	AllGuilds  GuildRegistry
+	//every guild's listener reads its updates from here
+	EventBus EventBus
 
-	LobbyUpdateChannels map[string]*chan LobbyStatus
-
-	LinkCodeLock sync.RWMutex
-
-	ChannelsMapLock sync.RWMutex
+	//the link codes, and the other processes if there are any
+	Coordinator coordination.Coordinator
 
 	SessionManager SessionManager
+	//where the emojis are uploaded to; every guild's own if it's empty
+	emojiGuildID string
 
 	StorageInterface storage.StorageInterface
 }
 
-func (bot *Bot) PushGuildSocketUpdate(guildID string, status SocketStatus) {
-	bot.ChannelsMapLock.RLock()
-	*(bot.SocketUpdateChannels)[guildID] <- status
-	bot.ChannelsMapLock.RUnlock()
+// PushGuildSocketUpdate and the other Push functions queue an update for the guild's listener without
+// blocking. They return false if the update was dropped, e.g. because the guild isn't set up yet
+func (bot *Bot) PushGuildSocketUpdate(guildID string, status SocketStatus) bool {
+	return bot.EventBus.Publish(guildID, GuildUpdate{Type: SocketUpdate, Socket: status})
+}
+
+func (bot *Bot) PushGuildPlayerUpdate(guildID string, status game.Player) bool {
+	return bot.EventBus.Publish(guildID, GuildUpdate{Type: PlayerUpdate, Player: status})
 }
 
-func (bot *Bot) PushGuildPlayerUpdate(guildID string, status game.Player) {
-	bot.ChannelsMapLock.RLock()
-	*(bot.PlayerUpdateChannels)[guildID] <- status
-	bot.ChannelsMapLock.RUnlock()
+func (bot *Bot) PushGuildPhaseUpdate(guildID string, status game.Phase) bool {
+	return bot.EventBus.Publish(guildID, GuildUpdate{Type: PhaseUpdate, Phase: status})
 }
 
-func (bot *Bot) PushGuildPhaseUpdate(guildID string, status game.Phase) {
-	bot.ChannelsMapLock.RLock()
-	*(bot.GamePhaseUpdateChannels)[guildID] <- status
-	bot.ChannelsMapLock.RUnlock()
+func (bot *Bot) PushGuildLobbyUpdate(guildID string, status LobbyStatus) bool {
+	return bot.EventBus.Publish(guildID, GuildUpdate{Type: LobbyUpdate, Lobby: status})
 }
 
-func (bot *Bot) PushGuildLobbyUpdate(guildID string, status LobbyStatus) {
-	bot.ChannelsMapLock.RLock()
-	*(bot.LobbyUpdateChannels)[guildID] <- status
-	bot.ChannelsMapLock.RUnlock()
+func (bot *Bot) PushGuildControlUpdate(guildID string, command ControlCommand) bool {
+	return bot.EventBus.Publish(guildID, GuildUpdate{Type: ControlUpdate, Control: command})
+}
+
+// PushGlobalBroadcast queues the message for every guild on this shard
+func (bot *Bot) PushGlobalBroadcast(msg BroadcastMessage) int {
+	return bot.EventBus.Broadcast(GuildUpdate{Type: BroadcastUpdate, Broadcast: msg})
 }
 
 var Version string
 
 // MakeAndStartBot does what it sounds like
 //TODO collapse these fields into proper structs?
-func MakeAndStartBot(version, token, token2, url, port, extPort, emojiGuildID string, numShards, shardID int, storageClient storage.StorageInterface) *Bot {
+func MakeAndStartBot(version, token, token2, url, port, extPort, emojiGuildID string, numShards, shardID int, storageClient storage.StorageInterface, coordinator coordination.Coordinator) *Bot {
 	Version = version
+	logger := logging.WithFields(logging.Fields{logging.FieldShard: shardID})
 
 	var altDiscordSession *discordgo.Session = nil
 
 	dg, err := discordgo.New("Bot " + token)
 	if err != nil {
-		log.Println("Fehler beim Erstellen der Discord-Sitzung,", err)
+		logger.Errorf("Fehler beim Erstellen der Discord-Sitzung: %s", err)
 		return nil
 	}
 	if token2 != "" {
 		altDiscordSession, err = discordgo.New("Bot " + token2)
 		if err != nil {
-			log.Println("Fehler beim Erstellen der 2. Discord-Sitzung,", err)
+			logger.Errorf("Fehler beim Erstellen der 2. Discord-Sitzung: %s", err)
 			return nil
 		}
 	}
 
 	if numShards > 1 {
-		log.Printf("Identifizieren mit der Discord-API mit %d Gesamt-Shards und Shard-ID =%d\n", numShards, shardID)
+		logger.Infof("Identifizieren mit der Discord-API mit %d Gesamt-Shards und Shard-ID =%d", numShards, shardID)
 		dg.ShardCount = numShards
 		dg.ShardID = shardID
 		if altDiscordSession != nil {
-			log.Printf("Identifizieren der Discord-API für den 2. Bot mit %d Gesamt-Shards und Shard-ID =%d\n", numShards, shardID)
+			logger.Infof("Identifizieren der Discord-API für den 2. Bot mit %d Gesamt-Shards und Shard-ID =%d", numShards, shardID)
 			altDiscordSession.ShardCount = numShards
 			altDiscordSession.ShardID = shardID
 		}
 	}
 
+	var altClient DiscordClient = nil
+	if altDiscordSession != nil {
+		altClient = NewSessionClient(altDiscordSession)
+	}
+
 	bot := Bot{
-		url:                     url,
-		socketPort:              port,
-		extPort:                 extPort,
-		AllConns:                make(map[string]string),
-		AllGuilds:               make(map[string]*GuildState),
-		LinkCodes:               make(map[GameOrLobbyCode]string),
-		GamePhaseUpdateChannels: make(map[string]*chan game.Phase),
-		PlayerUpdateChannels:    make(map[string]*chan game.Player),
-		SocketUpdateChannels:    make(map[string]*chan SocketStatus),
-		GlobalBroadcastChannels: make(map[string]*chan BroadcastMessage),
-		LobbyUpdateChannels:     make(map[string]*chan LobbyStatus),
-		LinkCodeLock:            sync.RWMutex{},
-		ChannelsMapLock:         sync.RWMutex{},
-		SessionManager:          NewSessionManager(dg, altDiscordSession),
-		StorageInterface:        storageClient,
+		shardID:          shardID,
+		url:              url,
+		socketPort:       port,
+		extPort:          extPort,
+		emojiGuildID:     emojiGuildID,
+		AllConns:         MakeConnRegistry(),
+		AllGuilds:        MakeGuildRegistry(),
+		EventBus:         MakeEventBus(),
+		Coordinator:      coordinator,
+		SessionManager:   NewSessionManager(NewSessionClient(dg), altClient),
+		StorageInterface: storageClient,
+	}
+
+	bot.registerMetrics(shardID)
+
+	if watchable, ok := storageClient.(storage.WatchableStorage); ok {
+		watchable.OnGuildDataChanged(bot.reloadGuildData)
 	}
 
 	dg.AddHandler(bot.voiceStateChange())
 	// Register the messageCreate func as a callback for MessageCreate events.
 	dg.AddHandler(bot.messageCreate())
 	dg.AddHandler(bot.reactionCreate())
-	dg.AddHandler(bot.newGuild(emojiGuildID))
+	dg.AddHandler(bot.newGuild())
+	dg.AddHandler(bot.rateLimit())
+	dg.AddHandler(bot.interactionCreate())
 
 	dg.Identify.Intents = discordgo.MakeIntent(discordgo.IntentsGuildVoiceStates | discordgo.IntentsGuildMessages | discordgo.IntentsGuilds | discordgo.IntentsGuildMessageReactions)
 
 	//Open a websocket connection to Discord and begin listening.
 	err = dg.Open()
 	if err != nil {
-		log.Println("Bot konnte mit Fehler nicht mit den Discord-Servern verbunden werden:", err)
+		logger.Errorf("Bot konnte mit Fehler nicht mit den Discord-Servern verbunden werden: %s", err)
 		return nil
 	}
 
@@ -212,213 +212,94 @@ func MakeAndStartBot(version, token, token2, url, port, extPort, emojiGuildID st
 		altDiscordSession.Identify.Intents = discordgo.MakeIntent(discordgo.IntentsGuilds)
 		err = altDiscordSession.Open()
 		if err != nil {
-			log.Println("Der 2. Bot konnte fehlerhaft nicht mit den Discord-Servern verbunden werden:", err)
+			logger.Errorf("Der 2. Bot konnte fehlerhaft nicht mit den Discord-Servern verbunden werden: %s", err)
 			return nil
 		}
 	}
 
-	// Wait here until CTRL-C or other term signal is received.
-
-	bot.Run()
-
 	return &bot
 }
 
-func (bot *Bot) Run() {
-	go bot.socketioServer(bot.socketPort)
-}
-
 func (bot *Bot) Close() {
 	bot.SessionManager.Close()
 }
 
-func (bot *Bot) guildIDForCode(code string) string {
-	if code == "" {
-		return ""
-	}
-	bot.LinkCodeLock.RLock()
-	defer bot.LinkCodeLock.RUnlock()
-	for codes, gid := range bot.LinkCodes {
-		if code != "" {
-			if codes.gameCode == code || codes.connectCode == code {
-				return gid
-			}
-		}
+// captureConnected links the capture connection to the guild whose connect code it sent
+func (bot *Bot) captureConnected(connID, guildID, code string) {
+	//only link the socket to guilds that we actually have a record of
+	if guild, ok := bot.AllGuilds.Get(guildID); ok {
+		bot.AllConns.Set(connID, guildID)
+		guild.SetLinked(true)
+
+		bot.PushGuildSocketUpdate(guildID, SocketStatus{
+			GuildID:   guildID,
+			Connected: true,
+		})
 	}
-	return ""
+
+	bot.socketLogger(connID).Infof("Zugehörige Websocket-ID %s mit guildID %s unter Verwendung von Code %s", connID, guildID, code)
+	//s.Emit("reply", "set guildID successfully")
 }
 
-func (bot *Bot) socketioServer(port string) {
-	server, err := socketio.NewServer(nil)
-	if err != nil {
-		log.Fatal(err)
+func (bot *Bot) captureLobby(connID, guildID string, lobby game.Lobby) {
+	if guild, ok := bot.AllGuilds.Get(guildID); ok { // Game is connected -> update its room code
+		guild.logger().WithField(logging.FieldSocket, connID).Infof("Raumcode %s von der Erfassung erhalten", lobby.LobbyCode)
+	} else {
+		bot.PushGuildSocketUpdate(guildID, SocketStatus{
+			GuildID:   guildID,
+			Connected: true,
+		})
+		bot.socketLogger(connID).Info("Assoziierte Lobby mit bestehendem Spiel!")
 	}
-	server.OnConnect("/", func(s socketio.Conn) error {
-		s.SetContext("")
-		log.Println("verbunden:", s.ID())
-		return nil
-	})
-	server.OnEvent("/", "connectCode", func(s socketio.Conn, msg string) {
-		log.Printf("Verbindungscode erhalten: \"%s\"", msg)
-		guildID := bot.guildIDForCode(msg)
-		if guildID == "" {
-			log.Printf("Keine Gilde hat den aktuellen Verbindungscode von %s\n", msg)
-			return
-		}
-		//only link the socket to guilds that we actually have a record of
-		if guild, ok := bot.AllGuilds[guildID]; ok {
-			bot.AllConns[s.ID()] = guildID
-			guild.Linked = true
-
-			bot.PushGuildSocketUpdate(guildID, SocketStatus{
-				GuildID:   guildID,
-				Connected: true,
-			})
-		}
-
-		log.Printf("Zugehörige Websocket-ID %s mit guildID %s unter Verwendung von Code %s\n", s.ID(), guildID, msg)
-		//s.Emit("reply", "set guildID successfully")
-	})
-	server.OnEvent("/", "lobby", func(s socketio.Conn, msg string) {
-		log.Println("lobby:", msg)
-		lobby := game.Lobby{}
-		err := json.Unmarshal([]byte(msg), &lobby)
-		if err != nil {
-			log.Println(err)
-		} else {
-			guildID := ""
-
-			//TODO race condition
-			if gid, ok := bot.AllConns[s.ID()]; ok {
-				guildID = gid
-			} else {
-				guildID = bot.guildIDForCode(lobby.LobbyCode)
-			}
-
-			if guildID != "" {
-				if guild, ok := bot.AllGuilds[guildID]; ok { // Game is connected -> update its room code
-					log.Println("Raumcode erhalten", msg, "für die Gilde", guild.PersistentGuildData.GuildID, "von der Erfassung")
-				} else {
-					bot.PushGuildSocketUpdate(guildID, SocketStatus{
-						GuildID:   guildID,
-						Connected: true,
-					})
-					log.Println("Assoziierte Lobby mit bestehendem Spiel!")
-				}
-				//we went to lobby, so set the phase. Also adds the initial reaction emojis
-				bot.PushGuildPhaseUpdate(guildID, game.LOBBY)
-				if bot.AllConns[s.ID()] != guildID {
-					bot.AllConns[s.ID()] = guildID
-				}
-				bot.PushGuildLobbyUpdate(guildID, LobbyStatus{
-					GuildID: guildID,
-					Lobby:   lobby,
-				})
-			} else {
-				log.Println("Ich habe keine Aufzeichnung von Spielen mit einer Lobby oder einem Verbindungscode von " + lobby.LobbyCode)
-			}
-		}
-	})
-	server.OnEvent("/", "state", func(s socketio.Conn, msg string) {
-		log.Println("Phase von der Erfassung erhalten: ", msg)
-		phase, err := strconv.Atoi(msg)
-		if err != nil {
-			log.Println(err)
-		} else {
-			if gid, ok := bot.AllConns[s.ID()]; ok && gid != "" {
-				log.Println("Phasenereignis auf Kanal schieben")
-				bot.PushGuildPhaseUpdate(gid, game.Phase(phase))
-			} else {
-				log.Println("Dieser Websocket ist keiner Gilde zugeordnet")
-			}
-		}
-	})
-	server.OnEvent("/", "player", func(s socketio.Conn, msg string) {
-		log.Println("Spieler von Capture erhalten: ", msg)
-		player := game.Player{}
-		err := json.Unmarshal([]byte(msg), &player)
-		if err != nil {
-			log.Println(err)
-		} else {
-			if gid, ok := bot.AllConns[s.ID()]; ok && gid != "" {
-				bot.PushGuildPlayerUpdate(gid, player)
-			} else {
-				log.Println("Dieser Websocket ist keiner Gilde zugeordnet")
-			}
-		}
-	})
-	server.OnError("/", func(s socketio.Conn, e error) {
-		log.Println("Fehler:", e)
-	})
-	server.OnDisconnect("/", func(s socketio.Conn, reason string) {
-		log.Println("Client-Verbindung geschlossen: ", reason)
-
-		previousGid := bot.AllConns[s.ID()]
-		delete(bot.AllConns, s.ID())
-		bot.LinkCodeLock.Lock()
-		for i, v := range bot.LinkCodes {
-			//delete the association between the link code and the guild
-			if v == previousGid {
-				delete(bot.LinkCodes, i)
-				break
-			}
-		}
-		bot.LinkCodeLock.Unlock()
-
-		for gid, guild := range bot.AllGuilds {
-			if gid == previousGid {
-				bot.LinkCodeLock.Lock()
-				guild.Linked = false
-				bot.LinkCodeLock.Unlock()
-				bot.PushGuildSocketUpdate(gid, SocketStatus{
-					GuildID:   gid,
-					Connected: false,
-				})
-
-				log.Printf("Zugeordnete Websocket-ID %s mit guildID %s\n", s.ID(), gid)
-			}
-		}
+	//we went to lobby, so set the phase. Also adds the initial reaction emojis
+	bot.PushGuildPhaseUpdate(guildID, game.LOBBY)
+	if gid, _ := bot.AllConns.GuildID(connID); gid != guildID {
+		bot.AllConns.Set(connID, guildID)
+	}
+	bot.PushGuildLobbyUpdate(guildID, LobbyStatus{
+		GuildID: guildID,
+		Lobby:   lobby,
 	})
-	go server.Serve()
-	defer server.Close()
-
-	//http.Handle("/socket.io/", server)
-
-	router := mux.NewRouter()
-	router.Handle("/socket.io/", server)
-
-	log.Printf("Serving at localhost:%s...\n", port)
-	log.Fatal(http.ListenAndServe(":"+port, router))
 }
 
-func MessagesServer(port string, bots []*Bot) {
+func (bot *Bot) captureDisconnected(connID string) {
+	previousGid := bot.AllConns.Remove(connID)
+	if previousGid == "" {
+		return
+	}
+	//delete the association between the link codes and the guild
+	if err := bot.Coordinator.RemoveLinkCodes(previousGid); err != nil {
+		bot.guildLogger(previousGid).Errorf("Verbindungscodes konnten nicht entfernt werden: %s", err)
+	}
 
-	http.HandleFunc("/graceful", func(w http.ResponseWriter, r *http.Request) {
-		for _, bot := range bots {
-			bot.ChannelsMapLock.RLock()
-			for _, v := range bot.GlobalBroadcastChannels {
-				*v <- BroadcastMessage{
-					Type: GRACEFUL_SHUTDOWN,
-					Data: 30,
-				}
-			}
-			bot.ChannelsMapLock.RUnlock()
-		}
-	})
+	if guild, ok := bot.AllGuilds.Get(previousGid); ok {
+		guild.SetLinked(false)
+		bot.PushGuildSocketUpdate(previousGid, SocketStatus{
+			GuildID:   previousGid,
+			Connected: false,
+		})
 
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+		guild.logger().WithField(logging.FieldSocket, connID).Info("Websocket-Verbindung der Gilde getrennt")
+	}
 }
 
-func (bot *Bot) updatesListener() func(dg *discordgo.Session, guildID string, socketUpdates *chan SocketStatus, phaseUpdates *chan game.Phase, playerUpdates *chan game.Player, lobbyUpdates *chan LobbyStatus, globalUpdates *chan BroadcastMessage) {
-	return func(dg *discordgo.Session, guildID string, socketUpdates *chan SocketStatus, phaseUpdates *chan game.Phase, playerUpdates *chan game.Player, lobbyUpdates *chan LobbyStatus, globalUpdates *chan BroadcastMessage) {
+func (bot *Bot) updatesListener() func(dg DiscordClient, guildID string, queue *GuildQueue) {
+	return func(dg DiscordClient, guildID string, queue *GuildQueue) {
 		for {
-			select {
+			update, ok := queue.Next()
+			if !ok {
+				bot.guildLogger(guildID).Debug("Warteschlange geschlossen, Listener wird beendet")
+				return
+			}
+			switch update.Type {
 
-			case phase := <-*phaseUpdates:
+			case PhaseUpdate:
+				phase := update.Phase
+				phaseTime := time.Now()
 
-				log.Printf("PhaseUpdate-Nachricht für die Gilde erhalten %s\n", guildID)
-				if guild, ok := bot.AllGuilds[guildID]; ok {
-					if !guild.GameRunning {
+				bot.guildLogger(guildID).Debugf("PhaseUpdate-Nachricht erhalten: %s", phaseLabel(phase))
+				if guild, ok := bot.AllGuilds.Get(guildID); ok {
+					if !guild.IsGameRunning() {
 						//completely ignore events if the game is ended/paused
 						break
 					}
@@ -427,37 +308,50 @@ func (bot *Bot) updatesListener() func(dg *discordgo.Session, guildID string, so
 						if guild.AmongUsData.GetPhase() == game.MENU {
 							break
 						}
-						log.Println("Übergang zum Menü erkannt")
+						guild.logger().Info("Übergang zum Menü erkannt")
+						observePhaseTransition(guild.AmongUsData.GetPhase(), phase)
+						bot.finishGame(guild, dg)
 						guild.AmongUsData.SetRoomRegion("Unprovided", "Unprovided")
 						guild.AmongUsData.SetPhase(phase)
-						guild.GameStateMsg.Edit(dg, gameStateResponse(guild))
-						guild.GameStateMsg.RemoveAllReactions(dg)
+						guild.GameStateMsg.EditNow(dg, gameStateResponse(guild))
+						guild.removeLinkControls(dg)
 						break
 					case game.LOBBY:
 						if guild.AmongUsData.GetPhase() == game.LOBBY {
 							break
 						}
-						log.Println("Übergang zur Lobby festgestellt")
+						guild.logger().Info("Übergang zur Lobby festgestellt")
+
+						oldPhase := guild.AmongUsData.GetPhase()
+						observePhaseTransition(oldPhase, phase)
+						if oldPhase == game.TASKS || oldPhase == game.DISCUSS {
+							//a round just finished; remember who played as who
+							bot.recordLinkHistory(guild)
+							bot.finishGame(guild, dg)
+						}
 
-						delay := guild.PersistentGuildData.Delays.GetDelay(guild.AmongUsData.GetPhase(), game.LOBBY)
+						delay := guild.PGD().Delays.GetDelay(oldPhase, game.LOBBY)
 
 						guild.AmongUsData.SetAllAlive()
 						guild.AmongUsData.SetPhase(phase)
 
 						//going back to the lobby, we have no preference on who gets applied first
-						guild.handleTrackedMembers(&bot.SessionManager, delay, NoPriority)
+						guild.handleTrackedMembersForPhase(&bot.SessionManager, delay, NoPriority, phaseTime)
 
-						guild.GameStateMsg.Edit(dg, gameStateResponse(guild))
+						if !guild.autoBump(dg, true) {
+							guild.GameStateMsg.EditNow(dg, gameStateResponse(guild))
 
-						guild.GameStateMsg.AddAllReactions(dg, guild.StatusEmojis[true])
+							guild.addLinkControls(dg)
+						}
 						break
 					case game.TASKS:
 						if guild.AmongUsData.GetPhase() == game.TASKS {
 							break
 						}
-						log.Println("Übergang zu Aufgaben erkannt")
+						guild.logger().Info("Übergang zu Aufgaben erkannt")
 						oldPhase := guild.AmongUsData.GetPhase()
-						delay := guild.PersistentGuildData.Delays.GetDelay(oldPhase, game.TASKS)
+						observePhaseTransition(oldPhase, phase)
+						delay := guild.PGD().Delays.GetDelay(oldPhase, game.TASKS)
 						//when going from discussion to tasks, we should mute alive players FIRST
 						priority := AlivePriority
 
@@ -469,50 +363,64 @@ func (bot *Bot) updatesListener() func(dg *discordgo.Session, guildID string, so
 
 						guild.AmongUsData.SetPhase(phase)
 
-						guild.handleTrackedMembers(&bot.SessionManager, delay, priority)
+						if oldPhase == game.LOBBY {
+							guild.GameEventLog.Start(guild.AmongUsData.NameColorMappings())
+							guild.GameEventLog.LinkUsers(guild.UserData.GetLinkedUsers())
+						}
 
-						guild.GameStateMsg.Edit(dg, gameStateResponse(guild))
+						guild.handleTrackedMembersForPhase(&bot.SessionManager, delay, priority, phaseTime)
+
+						if !guild.autoBump(dg, true) {
+							guild.GameStateMsg.EditNow(dg, gameStateResponse(guild))
+						}
 						break
 					case game.DISCUSS:
 						if guild.AmongUsData.GetPhase() == game.DISCUSS {
 							break
 						}
-						log.Println("Übergang zur Diskussion festgestellt")
+						guild.logger().Info("Übergang zur Diskussion festgestellt")
+						observePhaseTransition(guild.AmongUsData.GetPhase(), phase)
 
-						delay := guild.PersistentGuildData.Delays.GetDelay(guild.AmongUsData.GetPhase(), game.DISCUSS)
+						delay := guild.PGD().Delays.GetDelay(guild.AmongUsData.GetPhase(), game.DISCUSS)
 
 						guild.AmongUsData.SetPhase(phase)
+						guild.GameEventLog.AddMeeting()
 
-						guild.handleTrackedMembers(&bot.SessionManager, delay, DeadPriority)
+						guild.handleTrackedMembersForPhase(&bot.SessionManager, delay, DeadPriority, phaseTime)
 
-						guild.GameStateMsg.Edit(dg, gameStateResponse(guild))
+						if !guild.autoBump(dg, true) {
+							guild.GameStateMsg.EditNow(dg, gameStateResponse(guild))
+						}
 						break
 					default:
-						log.Printf("Unentdeckter neuer Zustand: %d\n", phase)
+						guild.logger().Warnf("Unentdeckter neuer Zustand: %d", phase)
 					}
 				}
 
-			case player := <-*playerUpdates:
-				log.Printf("PlayerUpdate-Nachricht für Gilde erhalten %s\n", guildID)
-				if guild, ok := bot.AllGuilds[guildID]; ok {
-					if !guild.GameRunning {
+			case PlayerUpdate:
+				player := update.Player
+				bot.guildLogger(guildID).Debugf("PlayerUpdate-Nachricht erhalten für %s", player.Name)
+				if guild, ok := bot.AllGuilds.Get(guildID); ok {
+					if !guild.IsGameRunning() {
 						break
 					}
 
 					//	this updates the copies in memory
 					//	(player's associations to amongus data are just pointers to these structs)
 					if player.Name != "" {
+						guild.GameEventLog.AddPlayerEvent(player)
+
 						if player.Action == game.EXILED {
-							log.Println("Erkanntes Spieler-EXILE-Ereignis, als tot markiert")
+							guild.logger().Debug("Erkanntes Spieler-EXILE-Ereignis, als tot markiert")
 							player.IsDead = true
 						}
 						if player.IsDead == true && guild.AmongUsData.GetPhase() == game.LOBBY {
-							log.Println("Ich habe ein totes Ereignis erhalten, aber wir sind in der Lobby, also ignoriere ich es")
+							guild.logger().Debug("Ich habe ein totes Ereignis erhalten, aber wir sind in der Lobby, also ignoriere ich es")
 							player.IsDead = false
 						}
 
 						if player.Disconnected || player.Action == game.LEFT {
-							log.Println("Ich habe entdeckt, dass " + player.Name + " die Verbindung getrennt hat oder verlassen hat! " +
+							guild.logger().Info("Ich habe entdeckt, dass " + player.Name + " die Verbindung getrennt hat oder verlassen hat! " +
 								"Ich entferne die verknüpften Spieldaten. Sie müssen neu verknüpfen")
 
 							guild.UserData.ClearPlayerDataByPlayerName(player.Name)
@@ -522,10 +430,10 @@ func (bot *Bot) updatesListener() func(dg *discordgo.Session, guildID string, so
 							updated, isAliveUpdated := guild.AmongUsData.ApplyPlayerUpdate(player)
 
 							if player.Action == game.JOINED {
-								log.Println("Es wurde festgestellt, dass ein Spieler beigetreten ist und die Benutzerdatenzuordnungen aktualisiert wurden")
+								guild.logger().Debug("Es wurde festgestellt, dass ein Spieler beigetreten ist und die Benutzerdatenzuordnungen aktualisiert wurden")
 								data := guild.AmongUsData.GetByName(player.Name)
 								if data == nil {
-									log.Println("Keine Spielerdaten gefunden für " + player.Name)
+									guild.logger().Warn("Keine Spielerdaten gefunden für " + player.Name)
 								}
 
 								guild.UserData.UpdatePlayerMappingByName(player.Name, data)
@@ -535,17 +443,19 @@ func (bot *Bot) updatesListener() func(dg *discordgo.Session, guildID string, so
 								data := guild.AmongUsData.GetByName(player.Name)
 								paired := guild.UserData.AttemptPairingByMatchingNames(player.Name, data)
 								if paired {
-									log.Println("Erfolgreich verknüpfter Discord-Benutzer mit übereinstimmenden Namen mit dem Spieler verbunden!")
+									guild.logger().Info("Erfolgreich verknüpfter Discord-Benutzer mit übereinstimmenden Namen mit dem Spieler verbunden!")
+								} else if player.Action == game.JOINED && guild.attemptAutoLinkFromHistory(player.Name, data) {
+									guild.logger().Info("Discord-Benutzer anhand des Verknüpfungsverlaufs automatisch mit " + player.Name + " verbunden!")
 								}
 
 								//log.Println("Player update received caused an update in cached state")
 								if isAliveUpdated && guild.AmongUsData.GetPhase() == game.TASKS {
-									if guild.PersistentGuildData.UnmuteDeadDuringTasks {
+									if guild.PGD().UnmuteDeadDuringTasks {
 										// unmute players even if in tasks because UnmuteDeadDuringTasks is true
 										guild.handleTrackedMembers(&bot.SessionManager, 0, NoPriority)
 										guild.GameStateMsg.Edit(dg, gameStateResponse(guild))
 									} else {
-										log.Println("NICHT die Discord-Statusmeldung aktualisieren; würde Infos leaken")
+										guild.logger().Debug("NICHT die Discord-Statusmeldung aktualisieren; würde Infos leaken")
 									}
 								} else {
 									guild.GameStateMsg.Edit(dg, gameStateResponse(guild))
@@ -558,25 +468,42 @@ func (bot *Bot) updatesListener() func(dg *discordgo.Session, guildID string, so
 					}
 				}
 				break
-			case socketUpdate := <-*socketUpdates:
-				if guild, ok := bot.AllGuilds[socketUpdate.GuildID]; ok {
+			case SocketUpdate:
+				socketUpdate := update.Socket
+				if guild, ok := bot.AllGuilds.Get(socketUpdate.GuildID); ok {
 					//this automatically updates the game state message on connect or disconnect
 					guild.GameStateMsg.Edit(dg, gameStateResponse(guild))
 				}
 				break
 
-			case worldUpdate := <-*globalUpdates:
-				if guild, ok := bot.AllGuilds[guildID]; ok {
+			case BroadcastUpdate:
+				worldUpdate := update.Broadcast
+				if guild, ok := bot.AllGuilds.Get(guildID); ok {
 					if worldUpdate.Type == GRACEFUL_SHUTDOWN {
-						log.Printf("Es wurde eine ordnungsgemäße Meldung zum Herunterfahren empfangen, in %d Sekunden wird heruntergefahren", worldUpdate.Data)
+						guild.logger().Infof("Es wurde eine ordnungsgemäße Meldung zum Herunterfahren empfangen, in %d Sekunden wird heruntergefahren", worldUpdate.Data)
 
 						go bot.gracefulShutdownWorker(dg, guild, worldUpdate.Data)
 					}
 				}
 
-			case lobbyUpdate := <-*lobbyUpdates:
-				if guild, ok := bot.AllGuilds[lobbyUpdate.GuildID]; ok {
-					guild.Linked = true
+			case ControlUpdate:
+				if guild, ok := bot.AllGuilds.Get(guildID); ok {
+					switch update.Control {
+					case EndGameCommand:
+						guild.logger().Info("Spiel über die Admin-API beendet")
+						bot.handleGameEndMessage(guild, dg)
+					case PauseGameCommand, ResumeGameCommand:
+						paused := update.Control == PauseGameCommand
+						guild.logger().Infof("Spiel über die Admin-API pausiert: %v", paused)
+						guild.SetGameRunning(!paused)
+						guild.GameStateMsg.Edit(dg, gameStateResponse(guild))
+					}
+				}
+
+			case LobbyUpdate:
+				lobbyUpdate := update.Lobby
+				if guild, ok := bot.AllGuilds.Get(lobbyUpdate.GuildID); ok {
+					guild.SetLinked(true)
 					guild.AmongUsData.SetRoomRegion(lobbyUpdate.Lobby.LobbyCode, lobbyUpdate.Lobby.Region.ToString()) // Set new room code
 					guild.GameStateMsg.Edit(dg, gameStateResponse(guild))                                             // Update game state message
 				}
@@ -585,9 +512,9 @@ func (bot *Bot) updatesListener() func(dg *discordgo.Session, guildID string, so
 	}
 }
 
-func (bot *Bot) gracefulShutdownWorker(s *discordgo.Session, guild *GuildState, seconds int) {
+func (bot *Bot) gracefulShutdownWorker(s DiscordClient, guild *GuildState, seconds int) {
 	if guild.GameStateMsg.message != nil {
-		sendMessage(s, guild.GameStateMsg.message.ChannelID, fmt.Sprintf("**Ich muss offline gehen, um ein Upgrade durchzuführen! Euer Spiel/eure Lobby wird in %d Sekunden beendet!**", seconds))
+		sendMessage(s, guild.GameStateMsg.message.ChannelID, guild.tr("shutdown.upgrade", seconds))
 	}
 
 	time.Sleep(time.Duration(seconds) * time.Second)
@@ -597,12 +524,10 @@ func (bot *Bot) gracefulShutdownWorker(s *discordgo.Session, guild *GuildState,
 
 // Gets called whenever a voice state change occurs
 func (bot *Bot) voiceStateChange() func(s *discordgo.Session, m *discordgo.VoiceStateUpdate) {
-	return func(s *discordgo.Session, m *discordgo.VoiceStateUpdate) {
-		for id, socketGuild := range bot.AllGuilds {
-			if id == m.GuildID {
-				socketGuild.voiceStateChange(s, m)
-				break
-			}
+	return func(_ *discordgo.Session, m *discordgo.VoiceStateUpdate) {
+		s := bot.SessionManager.GetPrimarySession()
+		if guild, ok := bot.AllGuilds.Get(m.GuildID); ok {
+			guild.voiceStateChange(s, m)
 		}
 	}
 }
@@ -610,43 +535,50 @@ func (bot *Bot) voiceStateChange() func(s *discordgo.Session, m *discordgo.Voice
 // This function will be called (due to AddHandler above) every time a new
 // message is created on any channel that the authenticated bot has access to.
 func (bot *Bot) messageCreate() func(s *discordgo.Session, m *discordgo.MessageCreate) {
-	return func(s *discordgo.Session, m *discordgo.MessageCreate) {
-		for id, socketGuild := range bot.AllGuilds {
-			if id == m.GuildID {
-				bot.handleMessageCreate(socketGuild, s, m)
-				break
-			}
+	return func(_ *discordgo.Session, m *discordgo.MessageCreate) {
+		s := bot.SessionManager.GetPrimarySession()
+		if guild, ok := bot.AllGuilds.Get(m.GuildID); ok {
+			bot.handleMessageCreate(guild, s, m)
 		}
 	}
 }
 
 //this function is called whenever a reaction is created in a guild
 func (bot *Bot) reactionCreate() func(s *discordgo.Session, m *discordgo.MessageReactionAdd) {
-	return func(s *discordgo.Session, m *discordgo.MessageReactionAdd) {
-		for id, socketGuild := range bot.AllGuilds {
-			if id == m.GuildID {
-				bot.handleReactionGameStartAdd(socketGuild, s, m)
-				break
+	return func(_ *discordgo.Session, m *discordgo.MessageReactionAdd) {
+		s := bot.SessionManager.GetPrimarySession()
+		if guild, ok := bot.AllGuilds.Get(m.GuildID); ok {
+			if !bot.handleLeaderboardReaction(guild, s, m) {
+				bot.handleReactionGameStartAdd(guild, s, m)
 			}
 		}
 	}
 }
 
-func (bot *Bot) newGuild(emojiGuildID string) func(s *discordgo.Session, m *discordgo.GuildCreate) {
-	return func(s *discordgo.Session, m *discordgo.GuildCreate) {
+// emojiGuild is the guild the emojis for the guild are uploaded to
+func (bot *Bot) emojiGuild(guildID string) string {
+	if bot.emojiGuildID == "" {
+		return guildID
+	}
+	return bot.emojiGuildID
+}
+
+func (bot *Bot) newGuild() func(s *discordgo.Session, m *discordgo.GuildCreate) {
+	return func(_ *discordgo.Session, m *discordgo.GuildCreate) {
+		s := bot.SessionManager.GetPrimarySession()
 
+		logger := bot.guildLogger(m.Guild.ID)
 		var pgd *PersistentGuildData = nil
 
 		data, err := bot.StorageInterface.GetGuildData(m.Guild.ID)
 		if err != nil {
-			log.Printf("Gilden-Daten für %s konnten nicht aus storageDriver geladen werden. Verwenden Sie stattdessen die Standardkonfiguration\n", m.Guild.ID)
-			log.Printf("Genauer Fehler: %s", err)
+			logger.WithError(err).Warn("Gilden-Daten konnten nicht aus storageDriver geladen werden. Verwenden Sie stattdessen die Standardkonfiguration")
 		} else {
 			tempPgd, err := FromData(data)
 			if err != nil {
-				log.Printf("Gilden-Daten für %s konnten nicht gemarshallt werden. Verwende stattdessen die Standardkonfiguration\n", m.Guild.ID)
+				logger.WithError(err).Warn("Gilden-Daten konnten nicht gemarshallt werden. Verwende stattdessen die Standardkonfiguration")
 			} else {
-				log.Printf("Konfiguration von storagedriver für erfolgreich geladen für %s\n", m.Guild.ID)
+				logger.Info("Konfiguration von storagedriver erfolgreich geladen")
 				pgd = tempPgd
 			}
 		}
@@ -654,109 +586,123 @@ func (bot *Bot) newGuild(emojiGuildID string) func(s *discordgo.Session, m *disc
 			pgd = PGDDefault(m.Guild.ID)
 			data, err := pgd.ToData()
 			if err != nil {
-				log.Printf("Fehler beim Marshalling von %s PGD zur Zuordnung(!): %s\n", m.Guild.ID, err)
+				logger.Errorf("Fehler beim Marshalling der PGD zur Zuordnung(!): %s", err)
 			} else {
 				err := bot.StorageInterface.WriteGuildData(m.Guild.ID, data)
 				if err != nil {
-					log.Printf("Fehler beim Schreiben von %s PGD in die Speicherschnittstelle: %s\n", m.Guild.ID, err)
+					logger.Errorf("Fehler beim Schreiben der PGD in die Speicherschnittstelle: %s", err)
 				} else {
-					log.Printf("%s PGD wurde erfolgreich in die Speicherschnittstelle geschrieben!", m.Guild.ID)
+					logger.Info("PGD wurde erfolgreich in die Speicherschnittstelle geschrieben!")
 				}
 			}
 		}
 
-		log.Printf("Zur neuen Gilde hinzugefügt, ID %s, Name %s", m.Guild.ID, m.Guild.Name)
-		bot.AllGuilds[m.ID] = &GuildState{
-			PersistentGuildData: pgd,
-
-			Linked: false,
+		logger.Infof("Zur neuen Gilde hinzugefügt, Name %s", m.Guild.Name)
+		guild := &GuildState{
+			persistentGuildData: pgd,
 
 			UserData:     MakeUserDataSet(),
 			Tracking:     MakeTracking(),
+			LinkHistory:  MakeLinkHistory(),
 			GameStateMsg: MakeGameStateMessage(),
 
-			StatusEmojis:  emptyStatusEmojis(),
-			SpecialEmojis: map[string]Emoji{},
+			emojis: fallbackGuildEmojis(),
 
-			GameRunning: false,
+			AmongUsData:  game.NewAmongUsData(),
+			GameEventLog: MakeGameEventLog(),
+			Leaderboards: MakeLeaderboardMessages(),
 
-			AmongUsData: game.NewAmongUsData(),
+			shardID: bot.shardID,
 		}
+		guild.applyEditWindow()
+		bot.AllGuilds.Set(m.ID, guild)
 
-		if emojiGuildID == "" {
-			log.Println("[Dies ist kein Fehler] Für Emojis wurde keine explizite Gilden-ID bereitgestellt. mit dem aktuellen Gildenstandard")
-			emojiGuildID = m.Guild.ID
-		}
-		allEmojis, err := s.GuildEmojis(emojiGuildID)
+		historyData, err := bot.StorageInterface.GetLinkHistory(m.Guild.ID)
 		if err != nil {
-			log.Println(err)
+			logger.Infof("Kein Verknüpfungsverlauf geladen: %s", err)
 		} else {
-			bot.AllGuilds[m.Guild.ID].addAllMissingEmojis(s, m.Guild.ID, true, allEmojis)
-
-			bot.AllGuilds[m.Guild.ID].addAllMissingEmojis(s, m.Guild.ID, false, allEmojis)
+			err = guild.LinkHistory.LoadData(historyData)
+			if err != nil {
+				logger.Errorf("Verknüpfungsverlauf konnte nicht gelesen werden: %s", err)
+			}
+		}
 
-			bot.AllGuilds[m.Guild.ID].addSpecialEmojis(s, m.Guild.ID, allEmojis)
+		_, err = guild.syncEmojis(s, bot.emojiGuild(m.Guild.ID))
+		if err != nil {
+			logger.Error(err)
 		}
 
-		socketUpdates := make(chan SocketStatus)
-		playerUpdates := make(chan game.Player)
-		phaseUpdates := make(chan game.Phase)
-		lobbyUpdates := make(chan LobbyStatus)
-		globalUpdates := make(chan BroadcastMessage)
+		queue := bot.EventBus.Register(m.Guild.ID)
 
-		bot.ChannelsMapLock.Lock()
-		bot.SocketUpdateChannels[m.Guild.ID] = &socketUpdates
-		bot.PlayerUpdateChannels[m.Guild.ID] = &playerUpdates
-		bot.GamePhaseUpdateChannels[m.Guild.ID] = &phaseUpdates
-		bot.LobbyUpdateChannels[m.Guild.ID] = &lobbyUpdates
-		bot.GlobalBroadcastChannels[m.Guild.ID] = &globalUpdates
-		bot.ChannelsMapLock.Unlock()
+		go bot.updatesListener()(s, m.Guild.ID, queue)
 
-		go bot.updatesListener()(s, m.Guild.ID, &socketUpdates, &phaseUpdates, &playerUpdates, &lobbyUpdates, &globalUpdates)
+	}
+}
 
+// reloadGuildData swaps in guild settings that were edited outside of the bot (e.g. by hand in the config file)
+func (bot *Bot) reloadGuildData(guildID string, data map[string]interface{}) {
+	guild, ok := bot.AllGuilds.Get(guildID)
+	if !ok {
+		//not a guild this shard knows about
+		return
 	}
+	pgd, err := FromData(data)
+	if err != nil {
+		bot.guildLogger(guildID).Errorf("Neu geladene Konfiguration ist ungültig, behalte die alte: %s", err)
+		return
+	}
+	err = pgd.Validate()
+	if err != nil {
+		bot.guildLogger(guildID).Errorf("Neu geladene Konfiguration ist ungültig, behalte die alte: %s", err)
+		return
+	}
+	//the file name decides which guild the config belongs to, not its contents
+	pgd.GuildID = guildID
+	guild.SetPGD(pgd)
+	guild.applyEditWindow()
+	bot.guildLogger(guildID).Info("Konfiguration neu geladen")
 }
 
 func newAltGuild(s *discordgo.Session, m *discordgo.GuildCreate) {
 	//TODO ensure that the 2nd bot is also present in the same guilds as the original bot (to ensure it can also issue requests)
 }
 
-func (bot *Bot) handleMessageCreate(guild *GuildState, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (bot *Bot) handleMessageCreate(guild *GuildState, s DiscordClient, m *discordgo.MessageCreate) {
 	// Ignore all messages created by the bot itself
-	if m.Author.ID == s.State.User.ID {
+	if m.Author.ID == s.BotUserID() {
 		return
 	}
 
-	g, err := s.State.Guild(guild.PersistentGuildData.GuildID)
+	g, err := s.CachedGuild(guild.PGD().GuildID)
 	if err != nil {
-		log.Println(err)
+		guild.logger().Error(err)
 		return
 	}
 
 	contents := m.Content
 
-	if strings.HasPrefix(contents, guild.PersistentGuildData.CommandPrefix) {
+	if strings.HasPrefix(contents, guild.PGD().CommandPrefix) {
 		//either BOTH the admin/roles are empty, or the user fulfills EITHER perm "bucket"
-		perms := len(guild.PersistentGuildData.AdminUserIDs) == 0 && len(guild.PersistentGuildData.PermissionedRoleIDs) == 0
+		perms := len(guild.PGD().AdminUserIDs) == 0 && len(guild.PGD().PermissionedRoleIDs) == 0
 		if !perms {
 			perms = guild.HasAdminPermissions(m.Author.ID) || guild.HasRolePermissions(s, m.Author.ID)
 		}
 		if !perms && g.OwnerID != m.Author.ID {
-			s.ChannelMessageSend(m.ChannelID, "Der Benutzer verfügt nicht über die erforderlichen Berechtigungen, um diesen Befehl auszuführen!")
+			s.ChannelMessageSend(m.ChannelID, guild.tr("commands.noPermission"))
 		} else {
 			oldLen := len(contents)
-			contents = strings.Replace(contents, guild.PersistentGuildData.CommandPrefix+" ", "", 1)
+			contents = strings.Replace(contents, guild.PGD().CommandPrefix+" ", "", 1)
 			if len(contents) == oldLen { //didn't have a space
-				contents = strings.Replace(contents, guild.PersistentGuildData.CommandPrefix, "", 1)
+				contents = strings.Replace(contents, guild.PGD().CommandPrefix, "", 1)
 			}
 
 			if len(contents) == 0 {
-				if len(guild.PersistentGuildData.CommandPrefix) <= 1 {
+				if len(guild.PGD().CommandPrefix) <= 1 {
 					// prevent bot from spamming help message whenever the single character
 					// prefix is sent by mistake
 					return
 				} else {
-					s.ChannelMessageSend(m.ChannelID, helpResponse(Version, guild.PersistentGuildData.CommandPrefix))
+					s.ChannelMessageSend(m.ChannelID, helpResponse(Version, guild.PGD().CommandPrefix, guild.PGD().Language))
 				}
 			} else {
 				args := strings.Split(contents, " ")
@@ -773,6 +719,9 @@ func (bot *Bot) handleMessageCreate(guild *GuildState, s *discordgo.Session, m *
 		if guild.GameStateMsg.SameChannel(m.ChannelID) {
 			deleteMessage(s, m.ChannelID, m.Message.ID)
 		}
+	} else {
+		guild.GameStateMsg.CountMessage(m.ChannelID)
+		guild.autoBump(s, false)
 	}
 
 }
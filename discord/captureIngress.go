@@ -0,0 +1,307 @@
+package discord
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/denverquane/amongusdiscord/coordination"
+	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/logging"
+	socketio "github.com/googollee/go-socket.io"
+	"github.com/gorilla/mux"
+)
+
+// CaptureIngress is the one socket.io server that all the shards of this process share, so there's only ever
+// one port and one URL for the capture. The capture only knows its connect code, so each event is handed to
+// the shard that owns the code's guild; if another process runs that shard, the event goes there through
+// the coordinator
+type CaptureIngress struct {
+	processID   string
+	numShards   int
+	bots        map[int]*Bot
+	coordinator coordination.Coordinator
+
+	//the captures connected to this process, and their guilds
+	conns ConnRegistry
+}
+
+// NewCaptureIngress claims the bots' shards for this process, and starts taking events from the other processes
+func NewCaptureIngress(processID string, numShards int, bots []*Bot, coordinator coordination.Coordinator) (*CaptureIngress, error) {
+	ingress := CaptureIngress{
+		processID:   processID,
+		numShards:   numShards,
+		bots:        make(map[int]*Bot),
+		coordinator: coordinator,
+		conns:       MakeConnRegistry(),
+	}
+	for _, bot := range bots {
+		//shards that failed to start are nil
+		if bot == nil {
+			continue
+		}
+		ingress.bots[bot.shardID] = bot
+		if err := coordinator.ClaimShard(bot.shardID, processID); err != nil {
+			return nil, err
+		}
+	}
+	if err := coordinator.OnCaptureEvent(processID, ingress.handle); err != nil {
+		return nil, err
+	}
+	if err := coordinator.OnBroadcast(ingress.broadcast); err != nil {
+		return nil, err
+	}
+	return &ingress, nil
+}
+
+// ListenAndServe runs the socket.io server on the port
+func (ingress *CaptureIngress) ListenAndServe(port string) {
+	router, err := ingress.router()
+	if err != nil {
+		logging.Fatalf("%s", err)
+	}
+
+	logging.Infof("Socket.io-Server für %d Shards läuft auf localhost:%s...", len(ingress.bots), port)
+	logging.Fatalf("%s", http.ListenAndServe(":"+port, router))
+}
+
+// Close gives the shards up, so no more events are sent to this process
+func (ingress *CaptureIngress) Close() {
+	for shardID := range ingress.bots {
+		if err := ingress.coordinator.ReleaseShard(shardID, ingress.processID); err != nil {
+			logging.Warnf("Shard %d konnte nicht freigegeben werden: %s", shardID, err)
+		}
+	}
+}
+
+// shardForGuild is how Discord spreads the guilds over the shards
+func shardForGuild(guildID string, numShards int) int {
+	id, err := strconv.ParseUint(guildID, 10, 64)
+	if err != nil || numShards < 2 {
+		return 0
+	}
+	return int((id >> 22) % uint64(numShards))
+}
+
+func (ingress *CaptureIngress) isShuttingDown() bool {
+	for _, bot := range ingress.bots {
+		if bot.isShuttingDown() {
+			return true
+		}
+	}
+	return false
+}
+
+func captureLogger(connID string) *logging.Logger {
+	return logging.WithFields(logging.Fields{logging.FieldSocket: connID})
+}
+
+// route hands the event to the shard here, or to the process that runs it
+func (ingress *CaptureIngress) route(event coordination.CaptureEvent) {
+	shardID := shardForGuild(event.GuildID, ingress.numShards)
+	if _, ok := ingress.bots[shardID]; ok {
+		ingress.handle(event)
+		return
+	}
+	owner, err := ingress.coordinator.ShardOwner(shardID)
+	if err != nil {
+		captureLogger(event.ConnID).Errorf("Besitzer von Shard %d konnte nicht abgefragt werden: %s", shardID, err)
+		return
+	}
+	if owner == "" {
+		captureLogger(event.ConnID).Warnf("Kein Prozess betreibt Shard %d, das Ereignis %s geht verloren", shardID, event.Name)
+		return
+	}
+	//the socket IDs are only unique within one process
+	event.ConnID = ingress.processID + ":" + event.ConnID
+	if err := ingress.coordinator.Forward(owner, event); err != nil {
+		captureLogger(event.ConnID).Errorf("Ereignis %s konnte nicht an %s weitergeleitet werden: %s", event.Name, owner, err)
+	}
+}
+
+// handle passes an event for one of this process's shards to the bot
+func (ingress *CaptureIngress) handle(event coordination.CaptureEvent) {
+	shardID := shardForGuild(event.GuildID, ingress.numShards)
+	bot, ok := ingress.bots[shardID]
+	if !ok {
+		captureLogger(event.ConnID).Warnf("Ereignis %s für Shard %d erhalten, der hier nicht läuft", event.Name, shardID)
+		return
+	}
+
+	switch event.Name {
+	case "connectCode":
+		bot.captureConnected(event.ConnID, event.GuildID, event.Data)
+	case "lobby":
+		lobby := game.Lobby{}
+		if err := json.Unmarshal([]byte(event.Data), &lobby); err != nil {
+			bot.socketLogger(event.ConnID).Error(err)
+			return
+		}
+		bot.captureLobby(event.ConnID, event.GuildID, lobby)
+	case "state":
+		phase, err := strconv.Atoi(event.Data)
+		if err != nil {
+			bot.socketLogger(event.ConnID).Error(err)
+			return
+		}
+		bot.socketLogger(event.ConnID).Debug("Phasenereignis auf Kanal schieben")
+		bot.PushGuildPhaseUpdate(event.GuildID, game.Phase(phase))
+	case "player":
+		player := game.Player{}
+		if err := json.Unmarshal([]byte(event.Data), &player); err != nil {
+			bot.socketLogger(event.ConnID).Error(err)
+			return
+		}
+		bot.PushGuildPlayerUpdate(event.GuildID, player)
+	case "disconnect":
+		bot.captureDisconnected(event.ConnID)
+	}
+}
+
+func (ingress *CaptureIngress) broadcast(msg coordination.Broadcast) {
+	for _, bot := range ingress.bots {
+		bot.PushGlobalBroadcast(BroadcastMessage{
+			Type: BcastMsgType(msg.Type),
+			Data: msg.Data,
+		})
+	}
+}
+
+// onConnectCode links the capture to the code's guild. It returns false if a capture on another process already
+// drives the guild; the connection has to be refused then, so two captures never drive the same guild
+func (ingress *CaptureIngress) onConnectCode(connID, code string) bool {
+	captureLogger(connID).Infof("Verbindungscode erhalten: \"%s\"", code)
+	guildID, err := ingress.coordinator.GuildForCode(code)
+	if err != nil {
+		captureLogger(connID).Errorf("Verbindungscode konnte nicht nachgeschlagen werden: %s", err)
+		return true
+	}
+	if guildID == "" {
+		captureLogger(connID).Warnf("Keine Gilde hat den aktuellen Verbindungscode von %s", code)
+		return true
+	}
+	//a capture that links itself again with the code of another guild
+	if previous, ok := ingress.conns.GuildID(connID); ok && previous != guildID {
+		ingress.onDisconnect(connID, "neu verknüpft")
+	}
+	err = ingress.coordinator.SetCaptureOwner(guildID, ingress.processID)
+	if err == coordination.ErrClaimed {
+		captureLogger(connID).Warnf("Die Gilde %s hat schon eine Erfassung bei einem anderen Prozess, die Verbindung wird abgelehnt", guildID)
+		return false
+	}
+	if err != nil {
+		captureLogger(connID).Warnf("Erfassung konnte nicht diesem Prozess zugeordnet werden: %s", err)
+	}
+	ingress.conns.Set(connID, guildID)
+	ingress.route(coordination.CaptureEvent{ConnID: connID, GuildID: guildID, Name: "connectCode", Data: code})
+	return true
+}
+
+func (ingress *CaptureIngress) onLobby(connID, msg string) {
+	captureLogger(connID).Debugf("lobby: %s", msg)
+	lobby := game.Lobby{}
+	err := json.Unmarshal([]byte(msg), &lobby)
+	if err != nil {
+		captureLogger(connID).Error(err)
+		return
+	}
+	guildID, ok := ingress.conns.GuildID(connID)
+	if !ok {
+		guildID, err = ingress.coordinator.GuildForCode(lobby.LobbyCode)
+		if err != nil {
+			captureLogger(connID).Errorf("Lobby-Code konnte nicht nachgeschlagen werden: %s", err)
+			return
+		}
+	}
+	if guildID == "" {
+		captureLogger(connID).Warn("Ich habe keine Aufzeichnung von Spielen mit einer Lobby oder einem Verbindungscode von " + lobby.LobbyCode)
+		return
+	}
+	ingress.conns.Set(connID, guildID)
+	ingress.route(coordination.CaptureEvent{ConnID: connID, GuildID: guildID, Name: "lobby", Data: msg})
+}
+
+func (ingress *CaptureIngress) onState(connID, msg string) {
+	captureLogger(connID).Debugf("Phase von der Erfassung erhalten: %s", msg)
+	if guildID, ok := ingress.conns.GuildID(connID); ok {
+		ingress.route(coordination.CaptureEvent{ConnID: connID, GuildID: guildID, Name: "state", Data: msg})
+	} else {
+		captureLogger(connID).Warn("Dieser Websocket ist keiner Gilde zugeordnet")
+	}
+}
+
+func (ingress *CaptureIngress) onPlayer(connID, msg string) {
+	captureLogger(connID).Debugf("Spieler von Capture erhalten: %s", msg)
+	if guildID, ok := ingress.conns.GuildID(connID); ok {
+		ingress.route(coordination.CaptureEvent{ConnID: connID, GuildID: guildID, Name: "player", Data: msg})
+	} else {
+		captureLogger(connID).Warn("Dieser Websocket ist keiner Gilde zugeordnet")
+	}
+}
+
+func (ingress *CaptureIngress) onDisconnect(connID, reason string) {
+	captureLogger(connID).Infof("Client-Verbindung geschlossen: %s", reason)
+	guildID := ingress.conns.Remove(connID)
+	if guildID == "" {
+		return
+	}
+	if err := ingress.coordinator.RemoveCaptureOwner(guildID, ingress.processID); err != nil {
+		captureLogger(connID).Warnf("Zuordnung der Erfassung konnte nicht entfernt werden: %s", err)
+	}
+	ingress.route(coordination.CaptureEvent{ConnID: connID, GuildID: guildID, Name: "disconnect"})
+}
+
+func (ingress *CaptureIngress) router() (*mux.Router, error) {
+	server, err := socketio.NewServer(nil)
+	if err != nil {
+		return nil, err
+	}
+	server.OnConnect("/", func(s socketio.Conn) error {
+		socketEvents.WithLabelValues("connect").Inc()
+		if ingress.isShuttingDown() {
+			return errors.New("der Bot fährt gerade herunter")
+		}
+		s.SetContext("")
+		captureLogger(s.ID()).Info("verbunden")
+		return nil
+	})
+	//every event is counted, and then handed to the shard it belongs to
+	server.OnEvent("/", "connectCode", func(s socketio.Conn, msg string) {
+		socketEvents.WithLabelValues("connectCode").Inc()
+		if ingress.isShuttingDown() {
+			return
+		}
+		if !ingress.onConnectCode(s.ID(), msg) {
+			s.Close()
+		}
+	})
+	events := map[string]func(connID, msg string){
+		"lobby":  ingress.onLobby,
+		"state":  ingress.onState,
+		"player": ingress.onPlayer,
+	}
+	for name, handler := range events {
+		name, handler := name, handler
+		server.OnEvent("/", name, func(s socketio.Conn, msg string) {
+			socketEvents.WithLabelValues(name).Inc()
+			if ingress.isShuttingDown() {
+				return
+			}
+			handler(s.ID(), msg)
+		})
+	}
+	server.OnError("/", func(s socketio.Conn, e error) {
+		socketEvents.WithLabelValues("error").Inc()
+		captureLogger(s.ID()).Errorf("Fehler: %s", e)
+	})
+	server.OnDisconnect("/", func(s socketio.Conn, reason string) {
+		socketEvents.WithLabelValues("disconnect").Inc()
+		ingress.onDisconnect(s.ID(), reason)
+	})
+	go server.Serve()
+
+	router := mux.NewRouter()
+	router.Handle("/socket.io/", server)
+	return router, nil
+}
@@ -0,0 +1,100 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/denverquane/amongusdiscord/coordination"
+	"github.com/denverquane/amongusdiscord/game"
+)
+
+// on two shards, guild 100 is on shard 0 and guild 4194304 (1<<22) on shard 1
+const (
+	shardZeroGuild = "100"
+	shardOneGuild  = "4194304"
+)
+
+// shardWithGuild is a shard that handed out the connect code for its one guild
+func shardWithGuild(shardID int, guildID, connectCode string, coordinator coordination.Coordinator) (*Bot, *GuildQueue) {
+	bot := &Bot{
+		shardID:     shardID,
+		AllConns:    MakeConnRegistry(),
+		AllGuilds:   MakeGuildRegistry(),
+		EventBus:    MakeEventBus(),
+		Coordinator: coordinator,
+	}
+	bot.AllGuilds.Set(guildID, &GuildState{
+		shardID:             shardID,
+		persistentGuildData: PGDDefault(guildID),
+		UserData:            MakeUserDataSet(),
+		Tracking:            MakeTracking(),
+		AmongUsData:         game.NewAmongUsData(),
+	})
+	coordinator.SetLinkCodes(guildID, connectCode, "ABCDEF")
+	return bot, bot.EventBus.Register(guildID)
+}
+
+// the capture connects to the process that runs shard 0, but the code is for a guild on the other process
+func TestCaptureIngressForwardsToOtherProcess(t *testing.T) {
+	coordinator := coordination.NewMemoryCoordinator()
+	first, firstQueue := shardWithGuild(0, shardZeroGuild, "AAAA1111", coordinator)
+	second, secondQueue := shardWithGuild(1, shardOneGuild, "BBBB2222", coordinator)
+	ingress, err := NewCaptureIngress("eins", 2, []*Bot{first}, coordinator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewCaptureIngress("zwei", 2, []*Bot{second, nil}, coordinator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ingress.onConnectCode("1", "BBBB2222") {
+		t.Fatal("die erste Erfassung der Gilde sollte angenommen werden")
+	}
+	if gid, ok := second.AllConns.GuildID("eins:1"); !ok || gid != shardOneGuild {
+		t.Fatal("die Verbindung sollte beim zweiten Prozess zur Gilde gehören")
+	}
+	guild, _ := second.AllGuilds.Get(shardOneGuild)
+	if !guild.IsLinked() {
+		t.Error("die Gilde sollte verknüpft sein")
+	}
+	if owner, _ := coordinator.CaptureOwner(shardOneGuild); owner != "eins" {
+		t.Errorf("die Erfassung sollte beim ersten Prozess sein, nicht bei %q", owner)
+	}
+	//a second capture for the same guild, on the other process
+	if other.onConnectCode("2", "BBBB2222") {
+		t.Error("die Gilde hat schon eine Erfassung, die zweite sollte abgelehnt werden")
+	}
+	if _, ok := other.conns.GuildID("2"); ok {
+		t.Error("die abgelehnte Verbindung sollte keiner Gilde zugeordnet sein")
+	}
+	ingress.onState("1", "1")
+	ingress.onPlayer("1", `{"Action":0,"Name":"alice","Color":0}`)
+
+	if firstQueue.Len() != 0 {
+		t.Errorf("der erste Shard sollte nichts bekommen, hat aber %d Updates", firstQueue.Len())
+	}
+	expected := []GuildUpdateType{SocketUpdate, PhaseUpdate, PlayerUpdate}
+	for _, updateType := range expected {
+		update, _ := secondQueue.Next()
+		if update.Type != updateType {
+			t.Errorf("erwartet %s, bekommen %s", guildUpdateLabels[updateType], guildUpdateLabels[update.Type])
+		}
+	}
+
+	ingress.onDisconnect("1", "transport close")
+	if second.AllConns.Len() != 0 || guild.IsLinked() {
+		t.Error("nach dem Trennen sollte die Verbindung weg sein")
+	}
+	if gid, _ := coordinator.GuildForCode("BBBB2222"); gid != "" {
+		t.Error("der Verbindungscode sollte nach dem Trennen nicht mehr gelten")
+	}
+}
+
+func TestShardForGuild(t *testing.T) {
+	if shard := shardForGuild(shardOneGuild, 2); shard != 1 {
+		t.Errorf("%s sollte auf Shard 1 sein, nicht %d", shardOneGuild, shard)
+	}
+	if shard := shardForGuild(shardOneGuild, 1); shard != 0 {
+		t.Errorf("mit einem Shard ist alles auf Shard 0, nicht %d", shard)
+	}
+}
@@ -0,0 +1,98 @@
+package discord
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// DiscordClient is everything the bot asks of Discord. SessionClient is the real thing; FakeDiscord (in the tests)
+// keeps a guild in memory so the game flow can be tested without a bot account
+type DiscordClient interface {
+	ChannelMessageSend(channelID string, content string) (*discordgo.Message, error)
+	ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error)
+	ChannelMessageEdit(channelID, messageID, content string) (*discordgo.Message, error)
+	ChannelMessageEditEmbed(channelID, messageID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error)
+	ChannelMessageDelete(channelID, messageID string) error
+	MessageReactionAdd(channelID, messageID, emojiID string) error
+	MessageReactionRemove(channelID, messageID, emojiID, userID string) error
+	MessageReactionsRemoveAll(channelID, messageID string) error
+	UserChannelCreate(recipientID string) (*discordgo.Channel, error)
+	// ChannelMessageEditComponents replaces the buttons and select menus of a message; none removes them
+	ChannelMessageEditComponents(channelID, messageID string, components []MessageComponent) error
+	// InteractionRespond answers a click on a button or a choice in a select menu
+	InteractionRespond(interaction *Interaction, response *InteractionResponse) error
+
+	Guild(guildID string) (*discordgo.Guild, error)
+	GuildChannels(guildID string) ([]*discordgo.Channel, error)
+	GuildMember(guildID, userID string) (*discordgo.Member, error)
+	GuildMembers(guildID string, after string, limit int) ([]*discordgo.Member, error)
+	GuildRoles(guildID string) ([]*discordgo.Role, error)
+	GuildEmojis(guildID string) ([]*discordgo.Emoji, error)
+	GuildEmojiCreate(guildID, name, image string, roles []string) (*discordgo.Emoji, error)
+	GuildEmojiDelete(guildID, emojiID string) error
+	// PatchMember server mutes/deafens and nicknames a member
+	PatchMember(guildID, userID string, patch MemberPatch) error
+
+	// CachedGuild and CachedMember read from what the gateway told us, without a request
+	CachedGuild(guildID string) (*discordgo.Guild, error)
+	CachedMember(guildID, userID string) (*discordgo.Member, error)
+	// BotUserID is the ID of the bot's own user, so it can ignore its own messages and reactions
+	BotUserID() string
+
+	Close() error
+}
+
+// MemberPatch is what PatchMember changes. A nil Nick leaves the nickname alone, an empty one removes it
+type MemberPatch struct {
+	Deaf bool    `json:"deaf"`
+	Mute bool    `json:"mute"`
+	Nick *string `json:"nick,omitempty"`
+}
+
+var _ DiscordClient = &SessionClient{}
+
+// SessionClient is a DiscordClient backed by a discordgo session
+type SessionClient struct {
+	*discordgo.Session
+}
+
+func NewSessionClient(s *discordgo.Session) *SessionClient {
+	return &SessionClient{Session: s}
+}
+
+func (sc *SessionClient) PatchMember(guildID, userID string, patch MemberPatch) error {
+	_, err := sc.RequestWithBucketID("PATCH", discordgo.EndpointGuildMember(guildID, userID), patch, discordgo.EndpointGuildMember(guildID, ""))
+	return err
+}
+
+func (sc *SessionClient) ChannelMessageEditComponents(channelID, messageID string, components []MessageComponent) error {
+	if components == nil {
+		components = []MessageComponent{}
+	}
+	data := struct {
+		Components []MessageComponent `json:"components"`
+	}{components}
+	_, err := sc.RequestWithBucketID("PATCH", componentsAPI+"channels/"+channelID+"/messages/"+messageID, data, discordgo.EndpointChannelMessage(channelID, ""))
+	return err
+}
+
+func (sc *SessionClient) InteractionRespond(interaction *Interaction, response *InteractionResponse) error {
+	endpoint := componentsAPI + "interactions/" + interaction.ID + "/" + interaction.Token + "/callback"
+	_, err := sc.RequestWithBucketID("POST", endpoint, response, componentsAPI+"interactions/"+interaction.ID)
+	return err
+}
+
+func (sc *SessionClient) CachedGuild(guildID string) (*discordgo.Guild, error) {
+	return sc.State.Guild(guildID)
+}
+
+func (sc *SessionClient) CachedMember(guildID, userID string) (*discordgo.Member, error) {
+	return sc.State.Member(guildID, userID)
+}
+
+func (sc *SessionClient) BotUserID() string {
+	if sc.State == nil || sc.State.User == nil {
+		return ""
+	}
+	return sc.State.User.ID
+}
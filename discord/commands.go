@@ -1,11 +1,9 @@
 package discord
 
 import (
-	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"github.com/denverquane/amongusdiscord/game"
 	"github.com/denverquane/amongusdiscord/storage"
-	"log"
 	"strings"
 )
 
@@ -22,50 +20,78 @@ const (
 	Refresh
 	Settings
 	Pause
+	Aliases
+	Stats
+	Leaderboard
+	Emojis
+	Mirror
+	Debug
 	Null
 )
 
 var CommandTypeStringMapping = map[string]CommandType{
-	"help":     Help,
-	"track":    Track,
-	"link":     Link,
-	"unlink":   Unlink,
-	"new":      New,
-	"end":      End,
-	"force":    Force,
-	"refresh":  Refresh,
-	"settings": Settings,
-	"pause":    Pause,
-	"":         Null,
+	"help":        Help,
+	"track":       Track,
+	"link":        Link,
+	"unlink":      Unlink,
+	"new":         New,
+	"end":         End,
+	"force":       Force,
+	"refresh":     Refresh,
+	"settings":    Settings,
+	"pause":       Pause,
+	"aliases":     Aliases,
+	"stats":       Stats,
+	"leaderboard": Leaderboard,
+	"lb":          Leaderboard,
+	"emojis":      Emojis,
+	"emoji":       Emojis,
+	"mirror":      Mirror,
+	"debug":       Debug,
+	"":            Null,
+}
+
+// CommandTypeShortcutMapping holds the single-letter shortcuts. Not every command gets one; newer commands
+// would collide with the letters the older ones already use
+var CommandTypeShortcutMapping = map[string]CommandType{
+	"h": Help,
+	"t": Track,
+	"l": Link,
+	"u": Unlink,
+	"n": New,
+	"e": End,
+	"f": Force,
+	"r": Refresh,
+	"s": Settings,
+	"p": Pause,
+	"a": Aliases,
 }
 
 func GetCommandType(arg string) CommandType {
-	for str, cmd := range CommandTypeStringMapping {
-		if len(arg) == 1 && cmd != Null {
-			if str[0] == arg[0] {
-				return cmd
-			}
-		} else {
-			if strings.ToLower(arg) == str {
-				return cmd
-			}
+	arg = strings.ToLower(arg)
+	if len(arg) == 1 {
+		if cmd, ok := CommandTypeShortcutMapping[arg]; ok {
+			return cmd
 		}
+		return Null
+	}
+	if cmd, ok := CommandTypeStringMapping[arg]; ok {
+		return cmd
 	}
-
 	return Null
 }
 
-func (bot *Bot) HandleCommand(guild *GuildState, s *discordgo.Session, g *discordgo.Guild, storageInterface storage.StorageInterface, m *discordgo.MessageCreate, args []string) {
+func (bot *Bot) HandleCommand(guild *GuildState, s DiscordClient, g *discordgo.Guild, storageInterface storage.StorageInterface, m *discordgo.MessageCreate, args []string) {
 	switch GetCommandType(args[0]) {
 
 	case Help:
-		s.ChannelMessageSend(m.ChannelID, helpResponse(Version, guild.PersistentGuildData.CommandPrefix))
+		s.ChannelMessageSend(m.ChannelID, helpResponse(Version, guild.PGD().CommandPrefix, guild.PGD().Language))
 		break
 
 	case Track:
 		if len(args[1:]) == 0 {
 			//TODO print usage of this command specifically
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Du hast diesen Befehl falsch verwendet! Bitte beziehe dich auf `%s help` für die ordnungsgemäße Verwendung von Befehlen", guild.PersistentGuildData.CommandPrefix))
+			s.ChannelMessageSend(m.ChannelID, guild.tr("commands.misuse", guild.PGD().CommandPrefix))
 		} else {
 			// have to explicitly check for true. Otherwise, processing the 2-word VC names gets really ugly...
 			forGhosts := false
@@ -79,7 +105,7 @@ func (bot *Bot) HandleCommand(guild *GuildState, s *discordgo.Session, g *discor
 
 			channels, err := s.GuildChannels(m.GuildID)
 			if err != nil {
-				log.Println(err)
+				guild.logger().Error(err)
 			}
 
 			guild.trackChannelResponse(channelName, channels, forGhosts)
@@ -91,8 +117,9 @@ func (bot *Bot) HandleCommand(guild *GuildState, s *discordgo.Session, g *discor
 	case Link:
 		if len(args[1:]) < 2 {
 			//TODO print usage of this command specifically
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Du hast diesen Befehl falsch verwendet! Bitte beziehe dich auf `%s help` für die ordnungsgemäße Verwendung von Befehlen", guild.PersistentGuildData.CommandPrefix))
+			s.ChannelMessageSend(m.ChannelID, guild.tr("commands.misuse", guild.PGD().CommandPrefix))
 		} else {
+			args[1] = meToMention(args[1], m.Author.ID)
 			guild.linkPlayerResponse(s, m.GuildID, args[1:])
 
 			guild.GameStateMsg.Edit(s, gameStateResponse(guild))
@@ -101,14 +128,14 @@ func (bot *Bot) HandleCommand(guild *GuildState, s *discordgo.Session, g *discor
 
 	case Unlink:
 		if len(args[1:]) == 0 {
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Du hast diesen Befehl falsch verwendet! Bitte beziehe dich auf `%s help` für die ordnungsgemäße Verwendung von Befehlen", guild.PersistentGuildData.CommandPrefix))
+			s.ChannelMessageSend(m.ChannelID, guild.tr("commands.misuse", guild.PGD().CommandPrefix))
 		} else {
 
-			userID, err := extractUserIDFromMention(args[1])
+			userID, err := extractUserIDFromMention(meToMention(args[1], m.Author.ID))
 			if err != nil {
-				log.Println(err)
+				guild.logger().Error(err)
 			} else {
-				log.Printf("Spieler entfernen %s", userID)
+				guild.logger().Infof("Spieler entfernen %s", userID)
 				guild.UserData.ClearPlayerData(userID)
 
 				//make sure that any players we remove/unlink get auto-unmuted/undeafened
@@ -121,13 +148,13 @@ func (bot *Bot) HandleCommand(guild *GuildState, s *discordgo.Session, g *discor
 		break
 
 	case New:
-		room, region := getRoomAndRegionFromArgs(args[1:])
+		room, region := getRoomAndRegionFromArgs(guild.inputAliases(), args[1:])
 
 		bot.handleNewGameMessage(guild, s, m, g, room, region)
 		break
 
 	case End:
-		log.Println("Der Benutzer gab end ein, um das aktuelle Spiel zu beenden")
+		guild.logger().Info("Der Benutzer gab end ein, um das aktuelle Spiel zu beenden")
 
 		bot.handleGameEndMessage(guild, s)
 
@@ -138,11 +165,11 @@ func (bot *Bot) HandleCommand(guild *GuildState, s *discordgo.Session, g *discor
 
 	case Force:
 		if len(args[1:]) < 1 {
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Du hast diesen Befehl falsch verwendet! Bitte beziehe dich auf `%s help` für die ordnungsgemäße Verwendung von Befehlen", guild.PersistentGuildData.CommandPrefix))
+			s.ChannelMessageSend(m.ChannelID, guild.tr("commands.misuse", guild.PGD().CommandPrefix))
 		}
-		phase := getPhaseFromString(args[1])
+		phase := guild.inputAliases().Phase(args[1])
 		if phase == game.UNINITIALIZED {
-			s.ChannelMessageSend(m.ChannelID, "Entschuldigung, ich habe die Spielphase, die du erzwingen wolltest, nicht verstanden")
+			s.ChannelMessageSend(m.ChannelID, guild.tr("commands.unknownPhase"))
 		} else {
 			//TODO this is ugly, but only for debug really
 			bot.PushGuildPhaseUpdate(m.GuildID, phase)
@@ -150,18 +177,7 @@ func (bot *Bot) HandleCommand(guild *GuildState, s *discordgo.Session, g *discor
 		break
 
 	case Refresh:
-		guild.GameStateMsg.Delete(s) //delete the old message
-
-		//create a new instance of the new one
-		guild.GameStateMsg.CreateMessage(s, gameStateResponse(guild), m.ChannelID, guild.GameStateMsg.leaderID)
-
-		//add the emojis to the refreshed message if in the right stage
-		if guild.AmongUsData.GetPhase() != game.MENU {
-			for _, e := range guild.StatusEmojis[true] {
-				guild.GameStateMsg.AddReaction(s, e.FormatForReaction())
-			}
-			guild.GameStateMsg.AddReaction(s, "❌")
-		}
+		guild.refreshStatusMessage(s, m.ChannelID)
 		break
 
 	case Settings:
@@ -169,11 +185,35 @@ func (bot *Bot) HandleCommand(guild *GuildState, s *discordgo.Session, g *discor
 		return // to prevent the user's message from being deleted
 
 	case Pause:
-		guild.GameRunning = !guild.GameRunning
+		guild.ToggleGameRunning()
 		guild.GameStateMsg.Edit(s, gameStateResponse(guild))
 		break
+
+	case Aliases:
+		bot.handleAliasesCommand(guild, s, m, args)
+		break
+
+	case Stats:
+		bot.handleStatsCommand(guild, s, m, args)
+		break
+
+	case Leaderboard:
+		bot.handleLeaderboardCommand(guild, s, m, args)
+		break
+
+	case Emojis:
+		bot.handleEmojisCommand(guild, s, m, args)
+		break
+
+	case Mirror:
+		bot.handleMirrorCommand(guild, s, m, args)
+		break
+
+	case Debug:
+		guild.handleDebugCommand(s, m, args)
+		break
 	default:
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Du hast diesen Befehl falsch verwendet! Bitte beziehe dich auf `%s help` für die ordnungsgemäße Verwendung von Befehlen", guild.PersistentGuildData.CommandPrefix))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("commands.misuse", guild.PGD().CommandPrefix))
 
 	}
 }
@@ -0,0 +1,110 @@
+package discord
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// discordgo speaks API v6, which knows neither message components nor interactions
+var componentsAPI = discordgo.EndpointDiscord + "api/v9/"
+
+// ComponentType is what kind of message component it is
+type ComponentType int
+
+const (
+	ComponentActionRow  ComponentType = 1
+	ComponentButton     ComponentType = 2
+	ComponentSelectMenu ComponentType = 3
+)
+
+// ButtonStyle is the color of a button
+type ButtonStyle int
+
+const (
+	ButtonPrimary   ButtonStyle = 1
+	ButtonSecondary ButtonStyle = 2
+	ButtonSuccess   ButtonStyle = 3
+	ButtonDanger    ButtonStyle = 4
+)
+
+// MessageComponent is a button, a select menu, or an action row holding them
+type MessageComponent struct {
+	Type        ComponentType      `json:"type"`
+	CustomID    string             `json:"custom_id,omitempty"`
+	Label       string             `json:"label,omitempty"`
+	Style       ButtonStyle        `json:"style,omitempty"`
+	Emoji       *ComponentEmoji    `json:"emoji,omitempty"`
+	Placeholder string             `json:"placeholder,omitempty"`
+	Options     []SelectOption     `json:"options,omitempty"`
+	Components  []MessageComponent `json:"components,omitempty"`
+}
+
+// SelectOption is one choice in a select menu
+type SelectOption struct {
+	Label string          `json:"label"`
+	Value string          `json:"value"`
+	Emoji *ComponentEmoji `json:"emoji,omitempty"`
+}
+
+// ComponentEmoji is a custom emoji by its ID, or a unicode one by its name
+type ComponentEmoji struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// InteractionType is what the user did
+type InteractionType int
+
+const InteractionMessageComponent InteractionType = 3
+
+// Interaction is a click on a button or a choice in a select menu, as the gateway sends it
+type Interaction struct {
+	ID        string            `json:"id"`
+	Type      InteractionType   `json:"type"`
+	Token     string            `json:"token"`
+	GuildID   string            `json:"guild_id"`
+	ChannelID string            `json:"channel_id"`
+	Member    *discordgo.Member `json:"member"`
+	Message   *struct {
+		ID string `json:"id"`
+	} `json:"message"`
+	Data struct {
+		CustomID      string        `json:"custom_id"`
+		ComponentType ComponentType `json:"component_type"`
+		Values        []string      `json:"values"`
+	} `json:"data"`
+}
+
+// UserID is who interacted, or an empty string outside of a guild
+func (i *Interaction) UserID() string {
+	if i.Member == nil || i.Member.User == nil {
+		return ""
+	}
+	return i.Member.User.ID
+}
+
+// InteractionResponseType is how the bot answers an interaction
+type InteractionResponseType int
+
+const InteractionResponseChannelMessage InteractionResponseType = 4
+
+// only the user who interacted sees the answer
+const messageFlagEphemeral = 1 << 6
+
+// InteractionResponse answers an interaction; Discord shows an error if there's none within 3 seconds
+type InteractionResponse struct {
+	Type InteractionResponseType  `json:"type"`
+	Data *InteractionResponseData `json:"data,omitempty"`
+}
+
+type InteractionResponseData struct {
+	Content string `json:"content"`
+	Flags   int    `json:"flags,omitempty"`
+}
+
+// ephemeralResponse is an answer only the user who interacted can see
+func ephemeralResponse(content string) *InteractionResponse {
+	return &InteractionResponse{
+		Type: InteractionResponseChannelMessage,
+		Data: &InteractionResponseData{Content: content, Flags: messageFlagEphemeral},
+	}
+}
@@ -0,0 +1,60 @@
+package discord
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// editWindow is the guild's own window for collecting changes to the status message, or 0 for the default
+func (guild *GuildState) editWindow() time.Duration {
+	return time.Duration(guild.PGD().EditWindow) * time.Millisecond
+}
+
+// applyEditWindow hands the guild's window to its status message, after the settings changed
+func (guild *GuildState) applyEditWindow() {
+	guild.GameStateMsg.SetEditWindow(guild.editWindow())
+}
+
+// rateLimit widens the edit window of the status message that was rate limited. discordgo sends RateLimit as a
+// value, so a handler for *discordgo.RateLimit would never be called
+func (bot *Bot) rateLimit() func(s *discordgo.Session, event interface{}) {
+	return func(_ *discordgo.Session, event interface{}) {
+		var rl discordgo.RateLimit
+		switch e := event.(type) {
+		case discordgo.RateLimit:
+			rl = e
+		case *discordgo.RateLimit:
+			rl = *e
+		default:
+			return
+		}
+		channelID := channelFromURL(rl.URL)
+		if channelID == "" {
+			return
+		}
+		retryAfter := time.Duration(0)
+		if rl.TooManyRequests != nil {
+			//discordgo reads it as milliseconds
+			retryAfter = rl.TooManyRequests.RetryAfter * time.Millisecond
+		}
+		for _, guild := range bot.AllGuilds.All() {
+			if guild.GameStateMsg.RateLimited(channelID, retryAfter) {
+				guild.logger().Infof("Statusmeldung wurde gebremst, Änderungen werden jetzt %s lang gesammelt", guild.GameStateMsg.EditWindow())
+				return
+			}
+		}
+	}
+}
+
+// channelFromURL finds the channel ID in a request URL like .../channels/<id>/messages/<id>
+func channelFromURL(url string) string {
+	parts := strings.Split(url, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == "channels" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
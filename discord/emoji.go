@@ -2,219 +2,486 @@ package discord
 
 import (
 	"encoding/base64"
-	"github.com/denverquane/amongusdiscord/game"
-	"io/ioutil"
-	"log"
-	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/assets"
+	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/locale"
 )
 
 // Emoji struct for discord
 type Emoji struct {
 	Name string
 	ID   string
+	//Fallback is shown instead, as long as the guild doesn't have the emoji (e.g. because it's out of emoji slots)
+	Fallback string
 }
 
 // FormatForReaction does what it sounds like
 func (e *Emoji) FormatForReaction() string {
+	if e.ID == "" {
+		return e.Fallback
+	}
 	return "<:" + e.Name + ":" + e.ID
 }
 
 // FormatForInline does what it sounds like
 func (e *Emoji) FormatForInline() string {
+	if e.ID == "" {
+		return e.Fallback
+	}
 	return "<:" + e.Name + ":" + e.ID + ">"
 }
 
-// GetDiscordCDNUrl does what it sounds like
-func (e *Emoji) GetDiscordCDNUrl() string {
-	return "https://cdn.discordapp.com/emojis/" + e.ID + ".png"
+// Matches is true if someone reacted with this emoji, or with its fallback if the guild doesn't have it
+func (e *Emoji) Matches(reaction discordgo.Emoji) bool {
+	if e.ID == "" {
+		return reaction.ID == "" && reaction.Name == e.Fallback
+	}
+	return reaction.ID == e.ID
 }
 
-// DownloadAndBase64Encode does what it sounds like
-func (e *Emoji) DownloadAndBase64Encode() string {
-	url := e.GetDiscordCDNUrl()
-	response, err := http.Get(url)
+// ImageData is the emoji's embedded PNG, as the data URI Discord wants for uploads
+func (e *Emoji) ImageData() (string, error) {
+	png, err := assets.Emojis.ReadFile("emojis/" + e.Name + ".png")
 	if err != nil {
-		log.Println(err)
+		return "", err
 	}
-	defer response.Body.Close()
-	bytes, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		log.Println(err)
-	}
-	encodedStr := base64.StdEncoding.EncodeToString(bytes)
-	return "data:image/png;base64," + encodedStr
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), nil
+}
+
+// EmojiSlots is how many static emojis a guild can have, by its boost level
+var EmojiSlots = map[discordgo.PremiumTier]int{
+	discordgo.PremiumTierNone: 50,
+	discordgo.PremiumTier1:    100,
+	discordgo.PremiumTier2:    150,
+	discordgo.PremiumTier3:    250,
 }
 
-func emptyStatusEmojis() AlivenessEmojis {
+// fallbackStatusEmojis are the status emojis of a guild that doesn't have any of the bot's emojis (yet)
+func fallbackStatusEmojis() AlivenessEmojis {
 	topMap := make(AlivenessEmojis)
-	topMap[true] = make([]Emoji, 12) //12 colors for alive/dead
-	topMap[false] = make([]Emoji, 12)
+	for alive, emojis := range GlobalAlivenessEmojis {
+		topMap[alive] = append([]Emoji{}, emojis...)
+	}
 	return topMap
 }
 
-func (guild *GuildState) addSpecialEmojis(s *discordgo.Session, guildID string, serverEmojis []*discordgo.Emoji) {
-	for _, emoji := range GlobalSpecialEmojis {
-		alreadyExists := false
-		for _, v := range serverEmojis {
-			if v.Name == emoji.Name {
-				emoji.ID = v.ID
-				guild.SpecialEmojis[v.Name] = emoji
-				alreadyExists = true
-				break
-			}
+func fallbackSpecialEmojis() map[string]Emoji {
+	special := make(map[string]Emoji, len(GlobalSpecialEmojis))
+	for k, v := range GlobalSpecialEmojis {
+		special[k] = v
+	}
+	return special
+}
+
+// allGlobalEmojis are all the bot's emojis, in the order they're uploaded: the ones for linking come first,
+// in case the guild runs out of slots
+func allGlobalEmojis() []Emoji {
+	all := append([]Emoji{}, GlobalAlivenessEmojis[true]...)
+	keys := make([]string, 0, len(GlobalSpecialEmojis))
+	for k := range GlobalSpecialEmojis {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		all = append(all, GlobalSpecialEmojis[k])
+	}
+	return append(all, GlobalAlivenessEmojis[false]...)
+}
+
+// EmojiReport is how the bot's emojis stand in a guild
+type EmojiReport struct {
+	//Present have the guild's IDs
+	Present   []Emoji
+	Missing   []Emoji
+	Added     int
+	FreeSlots int
+}
+
+func emojiNames(emojis []Emoji) string {
+	names := make([]string, len(emojis))
+	for i, e := range emojis {
+		names[i] = e.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func getEmojiReport(s DiscordClient, guildID string) (EmojiReport, error) {
+	g, err := s.Guild(guildID)
+	if err != nil {
+		return EmojiReport{}, err
+	}
+	serverEmojis, err := s.GuildEmojis(guildID)
+	if err != nil {
+		return EmojiReport{}, err
+	}
+	static := 0
+	byName := make(map[string]*discordgo.Emoji, len(serverEmojis))
+	for _, v := range serverEmojis {
+		if !v.Animated {
+			static++
 		}
-		if !alreadyExists {
-			b64 := emoji.DownloadAndBase64Encode()
-			em, err := s.GuildEmojiCreate(guildID, emoji.Name, b64, nil)
-			if err != nil {
-				log.Println(err)
-			} else {
-				log.Printf("Emoji %s erfolgreich hinzugefügt!\n", emoji.Name)
-				emoji.ID = em.ID
-				guild.SpecialEmojis[em.Name] = emoji
-			}
+		byName[v.Name] = v
+	}
+	report := EmojiReport{FreeSlots: EmojiSlots[g.PremiumTier] - static}
+	if report.FreeSlots < 0 {
+		report.FreeSlots = 0
+	}
+	for _, emoji := range allGlobalEmojis() {
+		if v, ok := byName[emoji.Name]; ok {
+			emoji.ID = v.ID
+			report.Present = append(report.Present, emoji)
+		} else {
+			report.Missing = append(report.Missing, emoji)
+		}
+	}
+	return report, nil
+}
+
+// syncEmojis uploads the emojis the guild is missing, as far as its slots allow, and uses them from then on.
+// The ones that couldn't be uploaded keep showing their fallback
+func (guild *GuildState) syncEmojis(s DiscordClient, emojiGuildID string) (EmojiReport, error) {
+	report, err := getEmojiReport(s, emojiGuildID)
+	if err != nil {
+		guild.useEmojis(nil)
+		return report, err
+	}
+	missing := report.Missing
+	report.Missing = nil
+	for _, emoji := range missing {
+		if report.FreeSlots == 0 {
+			report.Missing = append(report.Missing, emoji)
+			continue
+		}
+		image, err := emoji.ImageData()
+		if err != nil {
+			guild.logger().Error(err)
+			report.Missing = append(report.Missing, emoji)
+			continue
+		}
+		em, err := s.GuildEmojiCreate(emojiGuildID, emoji.Name, image, nil)
+		if err != nil {
+			guild.logger().Error(err)
+			report.Missing = append(report.Missing, emoji)
+			continue
+		}
+		guild.logger().Infof("Emoji %s erfolgreich hinzugefügt!", emoji.Name)
+		emoji.ID = em.ID
+		report.Present = append(report.Present, emoji)
+		report.Added++
+		report.FreeSlots--
+	}
+	if len(report.Missing) > 0 {
+		guild.logger().Warnf("In der Gilde %s fehlen %d Emojis, statt ihnen werden normale Emojis angezeigt: %s", emojiGuildID, len(report.Missing), emojiNames(report.Missing))
+	}
+	guild.useEmojis(report.Present)
+	return report, nil
+}
+
+// removeEmojis deletes the bot's emojis from the guild, and goes back to the fallbacks
+func (guild *GuildState) removeEmojis(s DiscordClient, emojiGuildID string) (int, error) {
+	report, err := getEmojiReport(s, emojiGuildID)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, emoji := range report.Present {
+		err := s.GuildEmojiDelete(emojiGuildID, emoji.ID)
+		if err != nil {
+			guild.logger().Error(err)
+			continue
 		}
+		removed++
 	}
+	guild.useEmojis(nil)
+	return removed, nil
 }
 
-func (guild *GuildState) addAllMissingEmojis(s *discordgo.Session, guildID string, alive bool, serverEmojis []*discordgo.Emoji) {
-	for i, emoji := range GlobalAlivenessEmojis[alive] {
-		alreadyExists := false
-		for _, v := range serverEmojis {
+// useEmojis switches the guild to the emojis it has, and to the fallbacks for all the others. Without all the
+// emojis for linking, the guild switches to the text status
+func (guild *GuildState) useEmojis(present []Emoji) {
+	status := fallbackStatusEmojis()
+	special := fallbackSpecialEmojis()
+	for _, emoji := range present {
+		for _, alive := range []bool{true, false} {
+			for i, v := range status[alive] {
+				if v.Name == emoji.Name {
+					status[alive][i] = emoji
+				}
+			}
+		}
+		for k, v := range special {
 			if v.Name == emoji.Name {
-				emoji.ID = v.ID
-				guild.StatusEmojis[alive][i] = emoji
-				alreadyExists = true
-				break
+				special[k] = emoji
 			}
 		}
-		if !alreadyExists {
-			b64 := emoji.DownloadAndBase64Encode()
-			em, err := s.GuildEmojiCreate(guildID, emoji.Name, b64, nil)
-			if err != nil {
-				log.Println(err)
-			} else {
-				log.Printf("Emoji %s erfolgreich hinzugefügt!\n", emoji.Name)
-				emoji.ID = em.ID
-				guild.StatusEmojis[alive][i] = emoji
+	}
+	textStatus := false
+	for _, emoji := range status[true] {
+		if emoji.ID == "" {
+			textStatus = true
+		}
+	}
+	if previous := guild.setEmojis(&GuildEmojis{Status: status, Special: special, TextStatus: textStatus}); textStatus && !previous.TextStatus {
+		guild.logger().Warn("Es fehlen Emojis zum Verknüpfen, die Statusmeldung zeigt nur noch Text")
+	}
+}
+
+// GuildEmojis are the emojis a guild shows. They're never changed in place, only swapped as a whole, so whoever
+// renders the status message sees one consistent set while the emojis are synced
+type GuildEmojis struct {
+	Status  AlivenessEmojis
+	Special map[string]Emoji
+	//TextStatus is set if the guild can't link by reactions because emojis are missing; the status message then
+	//shows colored squares with text, and players link with commands
+	TextStatus bool
+}
+
+func fallbackGuildEmojis() *GuildEmojis {
+	return &GuildEmojis{Status: fallbackStatusEmojis(), Special: fallbackSpecialEmojis()}
+}
+
+// Emojis returns the guild's current emojis; they must not be changed
+func (guild *GuildState) Emojis() *GuildEmojis {
+	guild.emojiLock.RLock()
+	emojis := guild.emojis
+	guild.emojiLock.RUnlock()
+	if emojis == nil {
+		return fallbackGuildEmojis()
+	}
+	return emojis
+}
+
+// setEmojis swaps the guild's emojis, and returns the ones it had before
+func (guild *GuildState) setEmojis(emojis *GuildEmojis) *GuildEmojis {
+	guild.emojiLock.Lock()
+	previous := guild.emojis
+	guild.emojis = emojis
+	guild.emojiLock.Unlock()
+	if previous == nil {
+		return fallbackGuildEmojis()
+	}
+	return previous
+}
+
+// ColorSquares stand for the colors in the text status. Not every color has a square of its own, so the text
+// status always names the color, too
+var ColorSquares = []string{
+	game.Red:    "🟥",
+	game.Blue:   "🟦",
+	game.Green:  "🟩",
+	game.Pink:   "🌸",
+	game.Orange: "🟧",
+	game.Yellow: "🟨",
+	game.Black:  "⬛",
+	game.White:  "⬜",
+	game.Purple: "🟪",
+	game.Brown:  "🟫",
+	game.Cyan:   "💠",
+	game.Lime:   "🍏",
+}
+
+// textStatusEmojis are colored squares with the color's name, e.g. "🟥 Rot", instead of the emojis
+func textStatusEmojis(lang string) AlivenessEmojis {
+	topMap := fallbackStatusEmojis()
+	for alive, emojis := range topMap {
+		for color := range emojis {
+			label := ColorSquares[color] + " " + locale.T(lang, "color."+game.GetColorStringForInt(color))
+			if !alive {
+				label += " " + locale.T(lang, "status.dead")
 			}
+			topMap[alive][color] = Emoji{Name: GlobalAlivenessEmojis[alive][color].Name, Fallback: label}
+		}
+	}
+	return topMap
+}
+
+// statusEmojis are what the status message and summaries show for the players
+func (guild *GuildState) statusEmojis() AlivenessEmojis {
+	emojis := guild.Emojis()
+	if emojis.TextStatus {
+		return textStatusEmojis(guild.PGD().Language)
+	}
+	return emojis.Status
+}
+
+// addLinkReactions adds the reactions players link with to the status message, unless the guild uses the text status
+func (guild *GuildState) addLinkReactions(s DiscordClient) {
+	emojis := guild.Emojis()
+	if emojis.TextStatus {
+		return
+	}
+	guild.GameStateMsg.AddAllReactions(s, emojis.Status[true])
+}
+
+// handleEmojisCommand shows how the bot's emojis stand in the guild, uploads the missing ones or removes them
+func (bot *Bot) handleEmojisCommand(guild *GuildState, s DiscordClient, m *discordgo.MessageCreate, args []string) {
+	emojiGuildID := bot.emojiGuild(m.GuildID)
+	action := "status"
+	if len(args) > 1 {
+		action = strings.ToLower(args[1])
+	}
+	//the emojis on the bot's own server are shared by every guild, so one of them shouldn't change them
+	if emojiGuildID != m.GuildID && action != "status" {
+		s.ChannelMessageSend(m.ChannelID, guild.tr("emojis.sharedGuild"))
+		return
+	}
+
+	switch action {
+	case "status":
+		report, err := getEmojiReport(s, emojiGuildID)
+		if err != nil {
+			guild.logger().Error(err)
+			s.ChannelMessageSend(m.ChannelID, guild.tr("emojis.failed"))
+			return
+		}
+		msg := guild.tr("emojis.status", len(report.Present), len(report.Present)+len(report.Missing), report.FreeSlots)
+		if len(report.Missing) > 0 {
+			msg += "\n" + guild.tr("emojis.missing", emojiNames(report.Missing))
+		}
+		s.ChannelMessageSend(m.ChannelID, msg)
+	case "sync":
+		report, err := guild.syncEmojis(s, emojiGuildID)
+		if err != nil {
+			guild.logger().Error(err)
+			s.ChannelMessageSend(m.ChannelID, guild.tr("emojis.failed"))
+			return
+		}
+		msg := guild.tr("emojis.synced", report.Added)
+		if len(report.Missing) > 0 {
+			msg += "\n" + guild.tr("emojis.noSlots", len(report.Missing), guild.PGD().CommandPrefix)
+		}
+		s.ChannelMessageSend(m.ChannelID, msg)
+		guild.GameStateMsg.Edit(s, gameStateResponse(guild))
+	case "remove":
+		removed, err := guild.removeEmojis(s, emojiGuildID)
+		if err != nil {
+			guild.logger().Error(err)
+			s.ChannelMessageSend(m.ChannelID, guild.tr("emojis.failed"))
+			return
 		}
+		guild.logger().Infof("%d Emojis entfernt von %s", removed, m.Author.ID)
+		s.ChannelMessageSend(m.ChannelID, guild.tr("emojis.removed", removed, guild.PGD().CommandPrefix))
+		guild.GameStateMsg.Edit(s, gameStateResponse(guild))
+	default:
+		s.ChannelMessageSend(m.ChannelID, guild.tr("emojis.unknown", args[1]))
 	}
 }
 
-// GlobalSpecialEmojis map
+// GlobalSpecialEmojis are the other emojis the bot uploads, by what they're for
 var GlobalSpecialEmojis = map[string]Emoji{
 	"alarm": {
-		Name: "aualarm",
-		ID:   "756595863048159323",
+		Name:     "aualarm",
+		Fallback: ":x:",
 	},
 }
 
 // AlivenessEmojis map
 type AlivenessEmojis map[bool][]Emoji
 
-// GlobalAlivenessEmojis keys are IsAlive, Color
+// GlobalAlivenessEmojis keys are IsAlive, Color. The IDs are filled in per guild
 var GlobalAlivenessEmojis = AlivenessEmojis{
 	true: []Emoji{
 		game.Red: {
-			Name: "aured",
-			ID:   "762392085768175646",
+			Name:     "aured",
+			Fallback: "🔴",
 		},
 		game.Blue: {
-			Name: "aublue",
-			ID:   "762392085629632512",
+			Name:     "aublue",
+			Fallback: "🔵",
 		},
 		game.Green: {
-			Name: "augreen",
-			ID:   "762392085889417226",
+			Name:     "augreen",
+			Fallback: "🟢",
 		},
 		game.Pink: {
-			Name: "aupink",
-			ID:   "762392085726363648",
+			Name:     "aupink",
+			Fallback: "🌸",
 		},
 		game.Orange: {
-			Name: "auorange",
-			ID:   "762392085264728095",
+			Name:     "auorange",
+			Fallback: "🟠",
 		},
 		game.Yellow: {
-			Name: "auyellow",
-			ID:   "762392085541158923",
+			Name:     "auyellow",
+			Fallback: "🟡",
 		},
 		game.Black: {
-			Name: "aublack",
-			ID:   "762392086493790249",
+			Name:     "aublack",
+			Fallback: "⚫",
 		},
 		game.White: {
-			Name: "auwhite",
-			ID:   "762392085990866974",
+			Name:     "auwhite",
+			Fallback: "⚪",
 		},
 		game.Purple: {
-			Name: "aupurple",
-			ID:   "762392085973303376",
+			Name:     "aupurple",
+			Fallback: "🟣",
 		},
 		game.Brown: {
-			Name: "aubrown",
-			ID:   "762392086023634986",
+			Name:     "aubrown",
+			Fallback: "🟤",
 		},
 		game.Cyan: {
-			Name: "aucyan",
-			ID:   "762392087945281557",
+			Name:     "aucyan",
+			Fallback: "💎",
 		},
 		game.Lime: {
-			Name: "aulime",
-			ID:   "762392088121442334",
+			Name:     "aulime",
+			Fallback: "🍏",
 		},
 	},
 	false: []Emoji{
 		game.Red: {
-			Name: "aureddead",
-			ID:   "762397192362393640",
+			Name:     "aureddead",
+			Fallback: "💀",
 		},
 		game.Blue: {
-			Name: "aubluedead",
-			ID:   "762397192349679616",
+			Name:     "aubluedead",
+			Fallback: "💀",
 		},
 		game.Green: {
-			Name: "augreendead",
-			ID:   "762397192060272724",
+			Name:     "augreendead",
+			Fallback: "💀",
 		},
 		game.Pink: {
-			Name: "aupinkdead",
-			ID:   "762397192643805194",
+			Name:     "aupinkdead",
+			Fallback: "💀",
 		},
 		game.Orange: {
-			Name: "auorangedead",
-			ID:   "762397192333819904",
+			Name:     "auorangedead",
+			Fallback: "💀",
 		},
 		game.Yellow: {
-			Name: "auyellowdead",
-			ID:   "762397192425046016",
+			Name:     "auyellowdead",
+			Fallback: "💀",
 		},
 		game.Black: {
-			Name: "aublackdead",
-			ID:   "762397192291090462",
+			Name:     "aublackdead",
+			Fallback: "💀",
 		},
 		game.White: {
-			Name: "auwhitedead",
-			ID:   "762397192409186344",
+			Name:     "auwhitedead",
+			Fallback: "💀",
 		},
 		game.Purple: {
-			Name: "aupurpledead",
-			ID:   "762397192404860958",
+			Name:     "aupurpledead",
+			Fallback: "💀",
 		},
 		game.Brown: {
-			Name: "aubrowndead",
-			ID:   "762397192102739989",
+			Name:     "aubrowndead",
+			Fallback: "💀",
 		},
 		game.Cyan: {
-			Name: "aucyandead",
-			ID:   "762397192307867698",
+			Name:     "aucyandead",
+			Fallback: "💀",
 		},
 		game.Lime: {
-			Name: "aulimedead",
-			ID:   "762397192366325793",
+			Name:     "aulimedead",
+			Fallback: "💀",
 		},
 	},
 }
@@ -0,0 +1,122 @@
+package discord
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/game"
+)
+
+func TestEmojiAssets(t *testing.T) {
+	for _, emoji := range allGlobalEmojis() {
+		if _, err := emoji.ImageData(); err != nil {
+			t.Errorf("%s ist nicht eingebettet: %s", emoji.Name, err)
+		}
+		if emoji.Fallback == "" {
+			t.Errorf("%s hat keinen Ersatz", emoji.Name)
+		}
+	}
+}
+
+func TestSyncEmojisWithoutFreeSlots(t *testing.T) {
+	const guildID = "100"
+	fake := NewFakeDiscord("bot")
+	fake.AddGuild(guildID, "Volle Gilde", "1")
+	//only room for 5 more
+	for i := 0; i < EmojiSlots[discordgo.PremiumTierNone]-5; i++ {
+		fake.GuildEmojiCreate(guildID, fmt.Sprintf("fremd%d", i), "", nil)
+	}
+	guild := &GuildState{persistentGuildData: PGDDefault(guildID)}
+	guild.useEmojis(nil)
+
+	report, err := guild.syncEmojis(fake, guildID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Added != 5 || report.FreeSlots != 0 || len(report.Missing) != len(allGlobalEmojis())-5 {
+		t.Fatalf("%d hochgeladen, %d frei, %d fehlen", report.Added, report.FreeSlots, len(report.Missing))
+	}
+	//the ones for linking come first
+	red := guild.Emojis().Status[true][0]
+	if red.ID == "" || red.FormatForInline() != "<:aured:"+red.ID+">" {
+		t.Errorf("aured sollte hochgeladen sein: %+v", red)
+	}
+	yellow := guild.Emojis().Status[true][5]
+	if yellow.ID != "" || yellow.FormatForReaction() != "🟡" || !yellow.Matches(discordgo.Emoji{Name: "🟡"}) {
+		t.Errorf("auyellow sollte durch seinen Ersatz angezeigt werden: %+v", yellow)
+	}
+	if red.Matches(discordgo.Emoji{Name: "🟡"}) {
+		t.Error("der Ersatz von auyellow ist nicht aured")
+	}
+	//without all the emojis for linking, reactions don't work
+	if !guild.Emojis().TextStatus {
+		t.Error("die Gilde sollte die Textanzeige benutzen")
+	}
+	if text := guild.statusEmojis()[false][game.Red].FormatForInline(); text != "🟥 Rot (tot)" {
+		t.Errorf("ein toter roter Spieler wird als %q angezeigt", text)
+	}
+
+	removed, err := guild.removeEmojis(fake, guildID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 5 || guild.Emojis().Status[true][0].ID != "" || !guild.Emojis().TextStatus {
+		t.Errorf("%d entfernt, aured ist %+v", removed, guild.Emojis().Status[true][0])
+	}
+	left, _ := fake.GuildEmojis(guildID)
+	if len(left) != EmojiSlots[discordgo.PremiumTierNone]-5 {
+		t.Errorf("die fremden Emojis sollten bleiben, es sind noch %d", len(left))
+	}
+}
+
+func TestSyncEmojisSwitchesBackFromText(t *testing.T) {
+	const guildID = "100"
+	fake := NewFakeDiscord("bot")
+	fake.AddGuild(guildID, "Leere Gilde", "1")
+	guild := &GuildState{persistentGuildData: PGDDefault(guildID), emojis: &GuildEmojis{TextStatus: true}}
+
+	if _, err := guild.syncEmojis(fake, guildID); err != nil {
+		t.Fatal(err)
+	}
+	if guild.Emojis().TextStatus {
+		t.Error("mit allen Emojis sollte wieder mit Reaktionen verknüpft werden")
+	}
+	if _, err := guild.syncEmojis(fake, "gibt es nicht"); err == nil || !guild.Emojis().TextStatus {
+		t.Errorf("wenn die Emojis nicht abgerufen werden können, sollte die Textanzeige an sein (Fehler %v)", err)
+	}
+}
+
+//run with -race: the emojis are swapped while the status message is rendered
+func TestSyncEmojisWhileRendering(t *testing.T) {
+	bot, fake := fakeBot(t)
+	guild, _ := bot.AllGuilds.Get(flowGuildID)
+	sendCommand(bot, fake, ".au new ABCDEF eu")
+	bot.PushGuildPhaseUpdate(flowGuildID, game.LOBBY)
+
+	done := make(chan struct{})
+	rendered := make(chan struct{})
+	go func() {
+		defer close(rendered)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			gameStateResponse(guild)
+			guild.linkComponents()
+			guild.statusEmojis()
+		}
+	}()
+	for i := 0; i < 3; i++ {
+		sendCommand(bot, fake, ".au emojis remove")
+		sendCommand(bot, fake, ".au emojis sync")
+	}
+	close(done)
+	<-rendered
+
+	if guild.Emojis().TextStatus {
+		t.Error("nach dem Hochladen sollten alle Emojis da sein")
+	}
+}
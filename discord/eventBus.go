@@ -0,0 +1,275 @@
+package discord
+
+import (
+	"sync"
+
+	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/logging"
+)
+
+type GuildUpdateType int
+
+const (
+	PhaseUpdate GuildUpdateType = iota
+	PlayerUpdate
+	SocketUpdate
+	LobbyUpdate
+	BroadcastUpdate
+	ControlUpdate
+)
+
+var guildUpdateLabels = map[GuildUpdateType]string{
+	PhaseUpdate:     "phase",
+	PlayerUpdate:    "player",
+	SocketUpdate:    "socket",
+	LobbyUpdate:     "lobby",
+	BroadcastUpdate: "broadcast",
+	ControlUpdate:   "control",
+}
+
+type QueuePolicy int
+
+const (
+	// KeepAll queues every update of the type
+	KeepAll QueuePolicy = iota
+	// KeepLatest replaces an update of the same type at the tail of the queue; only the newest one matters.
+	// Anything that came in after it stays behind it
+	KeepLatest
+)
+
+// updatePolicies says how a new update is merged with the ones still waiting in a guild's queue.
+// Every player update (deaths especially) has to be applied. A phase is only merged with the same phase
+// again: games start and finish at LOBBY and MENU, and every DISCUSS is a meeting, so skipping any
+// phase in between would lose one of them
+var updatePolicies = map[GuildUpdateType]QueuePolicy{
+	PhaseUpdate:     KeepLatest,
+	PlayerUpdate:    KeepAll,
+	SocketUpdate:    KeepLatest,
+	LobbyUpdate:     KeepLatest,
+	BroadcastUpdate: KeepAll,
+	ControlUpdate:   KeepAll,
+}
+
+// GuildQueueSize is how many updates can wait for a guild's listener; once full the oldest one that isn't a phase
+// or a control command is dropped
+const GuildQueueSize = 128
+
+// GuildUpdate is one update for a guild's listener. Only the field matching the Type is set
+type GuildUpdate struct {
+	Type      GuildUpdateType
+	Phase     game.Phase
+	Player    game.Player
+	Socket    SocketStatus
+	Lobby     LobbyStatus
+	Broadcast BroadcastMessage
+	Control   ControlCommand
+}
+
+// ControlCommand is something an admin asked for outside of Discord, applied by the guild's listener like
+// everything else that changes the game
+type ControlCommand int
+
+const (
+	EndGameCommand ControlCommand = iota
+	PauseGameCommand
+	ResumeGameCommand
+)
+
+// GuildQueue holds the updates for one guild until its listener gets to them. Pushing never blocks,
+// so the socket.io handlers don't wait on a listener that's sleeping through a delay
+type GuildQueue struct {
+	guildID string
+	updates []GuildUpdate
+	size    int
+	closed  bool
+	//holds a value whenever there might be something new for Next
+	wake chan struct{}
+	lock sync.Mutex
+}
+
+func NewGuildQueue(guildID string, size int) *GuildQueue {
+	return &GuildQueue{
+		guildID: guildID,
+		updates: make([]GuildUpdate, 0),
+		size:    size,
+		closed:  false,
+		wake:    make(chan struct{}, 1),
+		lock:    sync.Mutex{},
+	}
+}
+
+// Push adds the update according to its type's policy. It returns false if the queue was closed
+func (q *GuildQueue) Push(update GuildUpdate) bool {
+	label := guildUpdateLabels[update.Type]
+
+	q.lock.Lock()
+	if q.closed {
+		q.lock.Unlock()
+		eventBusDropped.WithLabelValues(label, "closed").Inc()
+		return false
+	}
+	if last := len(q.updates) - 1; last >= 0 && canMerge(q.updates[last], update) {
+		q.updates[last] = update
+		eventBusMerged.WithLabelValues(label).Inc()
+		q.lock.Unlock()
+		q.signal()
+		return true
+	}
+	if len(q.updates) >= q.size {
+		dropped := q.oldestDroppable()
+		droppedLabel := guildUpdateLabels[q.updates[dropped].Type]
+		eventBusDropped.WithLabelValues(droppedLabel, "overflow").Inc()
+		logging.WithGuild(q.guildID).Warnf("Warteschlange ist voll, verwerfe das älteste Update (%s)", droppedLabel)
+		q.updates = append(q.updates[:dropped], q.updates[dropped+1:]...)
+	}
+	q.updates = append(q.updates, update)
+	q.lock.Unlock()
+
+	q.signal()
+	return true
+}
+
+// canMerge is true if the update can replace the queued one, which has to be the last in the queue
+func canMerge(queued, update GuildUpdate) bool {
+	if queued.Type != update.Type || updatePolicies[update.Type] != KeepLatest {
+		return false
+	}
+	if update.Type == PhaseUpdate {
+		return queued.Phase == update.Phase
+	}
+	return true
+}
+
+// oldestDroppable is the index of the update to drop when the queue is full: the oldest one that isn't a
+// phase or a control command, since a lost phase change leaves everyone muted wrong and an admin expects
+// what they asked for to happen. Only a queue full of those loses one
+func (q *GuildQueue) oldestDroppable() int {
+	for i, v := range q.updates {
+		if v.Type != PhaseUpdate && v.Type != ControlUpdate {
+			return i
+		}
+	}
+	return 0
+}
+
+// Next waits for the oldest update. It returns false once the queue is closed and empty
+func (q *GuildQueue) Next() (GuildUpdate, bool) {
+	for {
+		q.lock.Lock()
+		if len(q.updates) > 0 {
+			update := q.updates[0]
+			q.updates = q.updates[1:]
+			q.lock.Unlock()
+			return update, true
+		}
+		closed := q.closed
+		q.lock.Unlock()
+		if closed {
+			return GuildUpdate{}, false
+		}
+		<-q.wake
+	}
+}
+
+func (q *GuildQueue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.updates)
+}
+
+// Close stops the queue from taking updates; the listener still gets the ones already waiting
+func (q *GuildQueue) Close() {
+	q.lock.Lock()
+	q.closed = true
+	q.lock.Unlock()
+	q.signal()
+}
+
+func (q *GuildQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// EventBus routes updates to the queue of the guild they're for
+type EventBus struct {
+	queues map[string]*GuildQueue
+	lock   sync.RWMutex
+}
+
+func MakeEventBus() EventBus {
+	return EventBus{
+		queues: make(map[string]*GuildQueue),
+		lock:   sync.RWMutex{},
+	}
+}
+
+// Register gives the guild a new queue. A queue the guild already had is closed, so its listener stops
+func (eb *EventBus) Register(guildID string) *GuildQueue {
+	queue := NewGuildQueue(guildID, GuildQueueSize)
+	eb.lock.Lock()
+	old := eb.queues[guildID]
+	eb.queues[guildID] = queue
+	eb.lock.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	return queue
+}
+
+// Publish queues the update for the guild. It returns false if the guild has no queue (yet), in which
+// case the update is dropped
+func (eb *EventBus) Publish(guildID string, update GuildUpdate) bool {
+	eb.lock.RLock()
+	queue, ok := eb.queues[guildID]
+	eb.lock.RUnlock()
+
+	if !ok {
+		eventBusDropped.WithLabelValues(guildUpdateLabels[update.Type], "unknown_guild").Inc()
+		logging.WithGuild(guildID).Debugf("Update (%s) für eine Gilde ohne Warteschlange verworfen", guildUpdateLabels[update.Type])
+		return false
+	}
+	return queue.Push(update)
+}
+
+// Broadcast queues the update for every guild, and returns for how many it was queued
+func (eb *EventBus) Broadcast(update GuildUpdate) int {
+	eb.lock.RLock()
+	defer eb.lock.RUnlock()
+
+	count := 0
+	for _, queue := range eb.queues {
+		if queue.Push(update) {
+			count++
+		}
+	}
+	return count
+}
+
+// Depth is how many updates are waiting for the guild's listener
+func (eb *EventBus) Depth(guildID string) int {
+	eb.lock.RLock()
+	queue, ok := eb.queues[guildID]
+	eb.lock.RUnlock()
+	if !ok {
+		return 0
+	}
+	return queue.Len()
+}
+
+// Depths returns the total and the largest number of updates waiting across all guilds
+func (eb *EventBus) Depths() (total int, max int) {
+	eb.lock.RLock()
+	defer eb.lock.RUnlock()
+
+	for _, queue := range eb.queues {
+		depth := queue.Len()
+		total += depth
+		if depth > max {
+			max = depth
+		}
+	}
+	return total, max
+}
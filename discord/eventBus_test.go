@@ -0,0 +1,113 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/denverquane/amongusdiscord/game"
+)
+
+func TestGuildQueueKeepsPhaseOrder(t *testing.T) {
+	q := NewGuildQueue("1", 8)
+	q.Push(GuildUpdate{Type: PhaseUpdate, Phase: game.TASKS})
+	q.Push(GuildUpdate{Type: PlayerUpdate, Player: game.Player{Name: "rot", Action: game.EXILED}})
+	q.Push(GuildUpdate{Type: PhaseUpdate, Phase: game.DISCUSS})
+	q.Push(GuildUpdate{Type: PhaseUpdate, Phase: game.DISCUSS})
+	q.Push(GuildUpdate{Type: PhaseUpdate, Phase: game.LOBBY})
+	q.Push(GuildUpdate{Type: PhaseUpdate, Phase: game.TASKS})
+	q.Push(GuildUpdate{Type: PlayerUpdate, Player: game.Player{Name: "blau"}})
+
+	//only the repeated DISCUSS is merged
+	expected := []GuildUpdate{
+		{Type: PhaseUpdate, Phase: game.TASKS},
+		{Type: PlayerUpdate, Player: game.Player{Name: "rot"}},
+		{Type: PhaseUpdate, Phase: game.DISCUSS},
+		{Type: PhaseUpdate, Phase: game.LOBBY},
+		{Type: PhaseUpdate, Phase: game.TASKS},
+		{Type: PlayerUpdate, Player: game.Player{Name: "blau"}},
+	}
+	if q.Len() != len(expected) {
+		t.Fatalf("erwartet %d Updates, bekommen %d", len(expected), q.Len())
+	}
+	for i, want := range expected {
+		got, ok := q.Next()
+		if !ok || got.Type != want.Type || got.Phase != want.Phase || got.Player.Name != want.Player.Name {
+			t.Errorf("Update %d: erwartet %+v, bekommen %+v", i, want, got)
+		}
+	}
+}
+
+func TestGuildQueueMergesOnlyAtTheTail(t *testing.T) {
+	q := NewGuildQueue("1", 8)
+	q.Push(GuildUpdate{Type: SocketUpdate, Socket: SocketStatus{Connected: false}})
+	q.Push(GuildUpdate{Type: PlayerUpdate, Player: game.Player{Name: "rot"}})
+	q.Push(GuildUpdate{Type: SocketUpdate, Socket: SocketStatus{Connected: true}})
+	q.Push(GuildUpdate{Type: SocketUpdate, Socket: SocketStatus{Connected: false}})
+
+	if q.Len() != 3 {
+		t.Fatalf("erwartet 3 Updates, bekommen %d", q.Len())
+	}
+	for i, want := range []GuildUpdateType{SocketUpdate, PlayerUpdate, SocketUpdate} {
+		if got, _ := q.Next(); got.Type != want || (i == 2 && got.Socket.Connected) {
+			t.Errorf("Update %d: erwartet %s, bekommen %+v", i, guildUpdateLabels[want], got)
+		}
+	}
+}
+
+func TestGuildQueueOverflowKeepsPhases(t *testing.T) {
+	q := NewGuildQueue("1", 2)
+	q.Push(GuildUpdate{Type: PhaseUpdate, Phase: game.TASKS})
+	q.Push(GuildUpdate{Type: PlayerUpdate, Player: game.Player{Name: "rot"}})
+	q.Push(GuildUpdate{Type: PlayerUpdate, Player: game.Player{Name: "blau"}})
+
+	if got, _ := q.Next(); got.Type != PhaseUpdate {
+		t.Errorf("die Phase darf nicht verworfen werden, stattdessen kam %+v", got)
+	}
+	if got, _ := q.Next(); got.Player.Name != "blau" {
+		t.Errorf("rot sollte verworfen sein, stattdessen kam %+v", got)
+	}
+}
+
+func TestGuildQueueDropsOldest(t *testing.T) {
+	q := NewGuildQueue("1", 2)
+	for _, name := range []string{"rot", "blau", "grün"} {
+		q.Push(GuildUpdate{Type: PlayerUpdate, Player: game.Player{Name: name}})
+	}
+	q.Close()
+	if q.Push(GuildUpdate{Type: PlayerUpdate}) {
+		t.Error("eine geschlossene Warteschlange darf keine Updates annehmen")
+	}
+
+	names := make([]string, 0)
+	for {
+		update, ok := q.Next()
+		if !ok {
+			break
+		}
+		names = append(names, update.Player.Name)
+	}
+	if len(names) != 2 || names[0] != "blau" || names[1] != "grün" {
+		t.Errorf("erwartet [blau grün], bekommen %v", names)
+	}
+}
+
+func TestEventBusUnknownGuild(t *testing.T) {
+	eb := MakeEventBus()
+	if eb.Publish("1", GuildUpdate{Type: PhaseUpdate, Phase: game.LOBBY}) {
+		t.Error("ein Update für eine unbekannte Gilde darf nicht angenommen werden")
+	}
+
+	old := eb.Register("1")
+	if !eb.Publish("1", GuildUpdate{Type: PhaseUpdate, Phase: game.LOBBY}) {
+		t.Fatal("Update wurde nicht angenommen")
+	}
+	eb.Register("1")
+	if _, ok := old.Next(); !ok {
+		t.Error("das wartende Update muss trotz Schließen noch ankommen")
+	}
+	if _, ok := old.Next(); ok {
+		t.Error("die alte Warteschlange muss nach dem Neuregistrieren geschlossen sein")
+	}
+	if depth := eb.Depth("1"); depth != 0 {
+		t.Errorf("die neue Warteschlange sollte leer sein, hat aber %d", depth)
+	}
+}
@@ -0,0 +1,447 @@
+package discord
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var ErrFakeNotFound = errors.New("nicht gefunden")
+
+// FakeDiscord is a DiscordClient that keeps its guilds, members, roles, voice states and messages in memory.
+// Member patches are applied to the voice states right away, like Discord would eventually
+type FakeDiscord struct {
+	botUserID string
+	guilds    map[string]*discordgo.Guild
+	//by channel, in the order they were sent
+	messages  map[string][]*discordgo.Message
+	reactions map[string][]string
+	nextID    int
+	lock      sync.Mutex
+
+	//by message, and the answers by interaction
+	components map[string][]MessageComponent
+	responses  map[string]*InteractionResponse
+
+	// OnVoiceStateUpdate is called (outside the lock) after every patch, like the gateway event
+	// discordgo would deliver. Usually set to the bot's voiceStateChange handler
+	OnVoiceStateUpdate func(*discordgo.VoiceStateUpdate)
+}
+
+var _ DiscordClient = &FakeDiscord{}
+
+func NewFakeDiscord(botUserID string) *FakeDiscord {
+	return &FakeDiscord{
+		botUserID: botUserID,
+		guilds:    make(map[string]*discordgo.Guild),
+		messages:  make(map[string][]*discordgo.Message),
+		reactions: make(map[string][]string),
+		nextID:    1000,
+		lock:      sync.Mutex{},
+
+		components: make(map[string][]MessageComponent),
+		responses:  make(map[string]*InteractionResponse),
+	}
+}
+
+func (fd *FakeDiscord) newID() string {
+	fd.nextID++
+	return strconv.Itoa(fd.nextID)
+}
+
+func (fd *FakeDiscord) AddGuild(guildID, name, ownerID string) *discordgo.Guild {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	g := &discordgo.Guild{
+		ID:      guildID,
+		Name:    name,
+		OwnerID: ownerID,
+	}
+	fd.guilds[guildID] = g
+	return g
+}
+
+func (fd *FakeDiscord) AddChannel(guildID, channelID, name string, channelType discordgo.ChannelType) {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	if g, ok := fd.guilds[guildID]; ok {
+		g.Channels = append(g.Channels, &discordgo.Channel{ID: channelID, GuildID: guildID, Name: name, Type: channelType})
+	}
+}
+
+func (fd *FakeDiscord) AddRole(guildID, roleID, name string) {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	if g, ok := fd.guilds[guildID]; ok {
+		g.Roles = append(g.Roles, &discordgo.Role{ID: roleID, Name: name})
+	}
+}
+
+func (fd *FakeDiscord) AddMember(guildID, userID, username, nick string, roles ...string) {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	if g, ok := fd.guilds[guildID]; ok {
+		g.Members = append(g.Members, &discordgo.Member{
+			GuildID: guildID,
+			User:    &discordgo.User{ID: userID, Username: username, Discriminator: "0001"},
+			Nick:    nick,
+			Roles:   roles,
+		})
+	}
+}
+
+// JoinVoice puts the member in the voice channel, or takes them out of voice with an empty channelID
+func (fd *FakeDiscord) JoinVoice(guildID, userID, channelID string) {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	g, ok := fd.guilds[guildID]
+	if !ok {
+		return
+	}
+	for i, v := range g.VoiceStates {
+		if v.UserID == userID {
+			if channelID == "" {
+				g.VoiceStates = append(g.VoiceStates[:i], g.VoiceStates[i+1:]...)
+			} else {
+				v.ChannelID = channelID
+			}
+			return
+		}
+	}
+	if channelID != "" {
+		g.VoiceStates = append(g.VoiceStates, &discordgo.VoiceState{GuildID: guildID, UserID: userID, ChannelID: channelID})
+	}
+}
+
+// VoiceState returns a copy of the member's voice state, or nil if they're not in voice
+func (fd *FakeDiscord) VoiceState(guildID, userID string) *discordgo.VoiceState {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	if g, ok := fd.guilds[guildID]; ok {
+		for _, v := range g.VoiceStates {
+			if v.UserID == userID {
+				vs := *v
+				return &vs
+			}
+		}
+	}
+	return nil
+}
+
+// Messages returns copies of the messages that are currently in the channel
+func (fd *FakeDiscord) Messages(channelID string) []discordgo.Message {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	msgs := make([]discordgo.Message, 0, len(fd.messages[channelID]))
+	for _, m := range fd.messages[channelID] {
+		msgs = append(msgs, *m)
+	}
+	return msgs
+}
+
+// Reactions returns the emojis the bot reacted to the message with
+func (fd *FakeDiscord) Reactions(messageID string) []string {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	return append([]string{}, fd.reactions[messageID]...)
+}
+
+func (fd *FakeDiscord) Components(messageID string) []MessageComponent {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	return append([]MessageComponent{}, fd.components[messageID]...)
+}
+
+// InteractionResponse is how the bot answered the interaction, or nil if it didn't
+func (fd *FakeDiscord) InteractionResponse(interactionID string) *InteractionResponse {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	return fd.responses[interactionID]
+}
+
+func (fd *FakeDiscord) findMessage(channelID, messageID string) (*discordgo.Message, int) {
+	for i, m := range fd.messages[channelID] {
+		if m.ID == messageID {
+			return m, i
+		}
+	}
+	return nil, -1
+}
+
+func (fd *FakeDiscord) findMember(g *discordgo.Guild, userID string) *discordgo.Member {
+	for _, m := range g.Members {
+		if m.User.ID == userID {
+			return m
+		}
+	}
+	return nil
+}
+
+func (fd *FakeDiscord) send(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error) {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	msg := &discordgo.Message{
+		ID:        fd.newID(),
+		ChannelID: channelID,
+		Content:   data.Content,
+		Author:    &discordgo.User{ID: fd.botUserID, Bot: true},
+	}
+	if data.Embed != nil {
+		msg.Embeds = []*discordgo.MessageEmbed{data.Embed}
+	}
+	for _, file := range data.Files {
+		msg.Attachments = append(msg.Attachments, &discordgo.MessageAttachment{ID: fd.newID(), Filename: file.Name})
+	}
+	fd.messages[channelID] = append(fd.messages[channelID], msg)
+	copied := *msg
+	return &copied, nil
+}
+
+func (fd *FakeDiscord) edit(channelID, messageID string, apply func(*discordgo.Message)) (*discordgo.Message, error) {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	msg, _ := fd.findMessage(channelID, messageID)
+	if msg == nil {
+		return nil, ErrFakeNotFound
+	}
+	apply(msg)
+	copied := *msg
+	return &copied, nil
+}
+
+func (fd *FakeDiscord) ChannelMessageSend(channelID string, content string) (*discordgo.Message, error) {
+	return fd.send(channelID, &discordgo.MessageSend{Content: content})
+}
+
+func (fd *FakeDiscord) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
+	return fd.send(channelID, &discordgo.MessageSend{Embed: embed})
+}
+
+func (fd *FakeDiscord) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error) {
+	return fd.send(channelID, data)
+}
+
+func (fd *FakeDiscord) ChannelMessageEdit(channelID, messageID, content string) (*discordgo.Message, error) {
+	return fd.edit(channelID, messageID, func(m *discordgo.Message) {
+		m.Content = content
+	})
+}
+
+func (fd *FakeDiscord) ChannelMessageEditEmbed(channelID, messageID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
+	return fd.edit(channelID, messageID, func(m *discordgo.Message) {
+		m.Embeds = []*discordgo.MessageEmbed{embed}
+	})
+}
+
+func (fd *FakeDiscord) ChannelMessageEditComponents(channelID, messageID string, components []MessageComponent) error {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	if msg, _ := fd.findMessage(channelID, messageID); msg == nil {
+		return ErrFakeNotFound
+	}
+	fd.components[messageID] = append([]MessageComponent{}, components...)
+	return nil
+}
+
+func (fd *FakeDiscord) InteractionRespond(interaction *Interaction, response *InteractionResponse) error {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	if _, ok := fd.responses[interaction.ID]; ok {
+		return errors.New("die Interaktion wurde schon beantwortet")
+	}
+	fd.responses[interaction.ID] = response
+	return nil
+}
+
+func (fd *FakeDiscord) ChannelMessageDelete(channelID, messageID string) error {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	_, i := fd.findMessage(channelID, messageID)
+	if i < 0 {
+		return ErrFakeNotFound
+	}
+	fd.messages[channelID] = append(fd.messages[channelID][:i], fd.messages[channelID][i+1:]...)
+	delete(fd.reactions, messageID)
+	delete(fd.components, messageID)
+	return nil
+}
+
+func (fd *FakeDiscord) MessageReactionAdd(channelID, messageID, emojiID string) error {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	fd.reactions[messageID] = append(fd.reactions[messageID], emojiID)
+	return nil
+}
+
+func (fd *FakeDiscord) MessageReactionRemove(channelID, messageID, emojiID, userID string) error {
+	//only the bot's own reactions are kept track of
+	return nil
+}
+
+func (fd *FakeDiscord) MessageReactionsRemoveAll(channelID, messageID string) error {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	delete(fd.reactions, messageID)
+	return nil
+}
+
+func (fd *FakeDiscord) UserChannelCreate(recipientID string) (*discordgo.Channel, error) {
+	return &discordgo.Channel{ID: "dm-" + recipientID, Type: discordgo.ChannelTypeDM}, nil
+}
+
+func (fd *FakeDiscord) Guild(guildID string) (*discordgo.Guild, error) {
+	return fd.CachedGuild(guildID)
+}
+
+func (fd *FakeDiscord) GuildChannels(guildID string) ([]*discordgo.Channel, error) {
+	g, err := fd.CachedGuild(guildID)
+	if err != nil {
+		return nil, err
+	}
+	return g.Channels, nil
+}
+
+func (fd *FakeDiscord) GuildMember(guildID, userID string) (*discordgo.Member, error) {
+	return fd.CachedMember(guildID, userID)
+}
+
+func (fd *FakeDiscord) GuildMembers(guildID string, after string, limit int) ([]*discordgo.Member, error) {
+	g, err := fd.CachedGuild(guildID)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.Members) > limit {
+		return g.Members[:limit], nil
+	}
+	return g.Members, nil
+}
+
+func (fd *FakeDiscord) GuildRoles(guildID string) ([]*discordgo.Role, error) {
+	g, err := fd.CachedGuild(guildID)
+	if err != nil {
+		return nil, err
+	}
+	return g.Roles, nil
+}
+
+func (fd *FakeDiscord) GuildEmojis(guildID string) ([]*discordgo.Emoji, error) {
+	g, err := fd.CachedGuild(guildID)
+	if err != nil {
+		return nil, err
+	}
+	return g.Emojis, nil
+}
+
+func (fd *FakeDiscord) GuildEmojiCreate(guildID, name, image string, roles []string) (*discordgo.Emoji, error) {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	g, ok := fd.guilds[guildID]
+	if !ok {
+		return nil, ErrFakeNotFound
+	}
+	emoji := &discordgo.Emoji{ID: fd.newID(), Name: name, Roles: roles}
+	g.Emojis = append(g.Emojis, emoji)
+	copied := *emoji
+	return &copied, nil
+}
+
+func (fd *FakeDiscord) GuildEmojiDelete(guildID, emojiID string) error {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	g, ok := fd.guilds[guildID]
+	if !ok {
+		return ErrFakeNotFound
+	}
+	for i, v := range g.Emojis {
+		if v.ID == emojiID {
+			g.Emojis = append(g.Emojis[:i], g.Emojis[i+1:]...)
+			return nil
+		}
+	}
+	return ErrFakeNotFound
+}
+
+func (fd *FakeDiscord) PatchMember(guildID, userID string, patch MemberPatch) error {
+	fd.lock.Lock()
+	g, ok := fd.guilds[guildID]
+	if !ok {
+		fd.lock.Unlock()
+		return ErrFakeNotFound
+	}
+	member := fd.findMember(g, userID)
+	if member == nil {
+		fd.lock.Unlock()
+		return ErrFakeNotFound
+	}
+	member.Mute = patch.Mute
+	member.Deaf = patch.Deaf
+	if patch.Nick != nil {
+		member.Nick = *patch.Nick
+	}
+	var update *discordgo.VoiceStateUpdate
+	for _, v := range g.VoiceStates {
+		if v.UserID == userID {
+			v.Mute = patch.Mute
+			v.Deaf = patch.Deaf
+			vs := *v
+			update = &discordgo.VoiceStateUpdate{VoiceState: &vs}
+		}
+	}
+	handler := fd.OnVoiceStateUpdate
+	fd.lock.Unlock()
+
+	if update != nil && handler != nil {
+		handler(update)
+	}
+	return nil
+}
+
+// CachedGuild returns a copy of the guild, so callers can read it while the fake changes
+func (fd *FakeDiscord) CachedGuild(guildID string) (*discordgo.Guild, error) {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	g, ok := fd.guilds[guildID]
+	if !ok {
+		return nil, ErrFakeNotFound
+	}
+	copied := *g
+	copied.Channels = append([]*discordgo.Channel{}, g.Channels...)
+	copied.Roles = append([]*discordgo.Role{}, g.Roles...)
+	copied.Emojis = append([]*discordgo.Emoji{}, g.Emojis...)
+	copied.Members = make([]*discordgo.Member, 0, len(g.Members))
+	for _, m := range g.Members {
+		member := *m
+		copied.Members = append(copied.Members, &member)
+	}
+	copied.VoiceStates = make([]*discordgo.VoiceState, 0, len(g.VoiceStates))
+	for _, v := range g.VoiceStates {
+		vs := *v
+		copied.VoiceStates = append(copied.VoiceStates, &vs)
+	}
+	return &copied, nil
+}
+
+func (fd *FakeDiscord) CachedMember(guildID, userID string) (*discordgo.Member, error) {
+	fd.lock.Lock()
+	defer fd.lock.Unlock()
+	g, ok := fd.guilds[guildID]
+	if !ok {
+		return nil, ErrFakeNotFound
+	}
+	member := fd.findMember(g, userID)
+	if member == nil {
+		return nil, ErrFakeNotFound
+	}
+	copied := *member
+	return &copied, nil
+}
+
+func (fd *FakeDiscord) BotUserID() string {
+	return fd.botUserID
+}
+
+func (fd *FakeDiscord) Close() error {
+	return nil
+}
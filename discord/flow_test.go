@@ -0,0 +1,148 @@
+package discord
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/coordination"
+	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/storage"
+)
+
+const (
+	flowGuildID  = "1"
+	flowOwnerID  = "2"
+	flowTextID   = "3"
+	flowVoiceID  = "4"
+	flowAliceID  = "10"
+	flowBobID    = "11"
+	flowWaitTime = 5 * time.Second
+)
+
+// fakeBot is a bot that talks to an in-memory Discord with one guild: the owner, alice and bob are
+// all in the Among Us voice channel
+func fakeBot(t *testing.T) (*Bot, *FakeDiscord) {
+	fake := NewFakeDiscord("bot")
+	fake.AddGuild(flowGuildID, "Testgilde", flowOwnerID)
+	fake.AddChannel(flowGuildID, flowTextID, "allgemein", discordgo.ChannelTypeGuildText)
+	fake.AddChannel(flowGuildID, flowVoiceID, "Among Us", discordgo.ChannelTypeGuildVoice)
+	for id, name := range map[string]string{flowOwnerID: "chef", flowAliceID: "alice", flowBobID: "bob"} {
+		fake.AddMember(flowGuildID, id, name, "")
+		fake.JoinVoice(flowGuildID, id, flowVoiceID)
+	}
+	//the emojis are already there, so nothing is uploaded
+	for _, alive := range []bool{true, false} {
+		for _, emoji := range GlobalAlivenessEmojis[alive] {
+			fake.GuildEmojiCreate(flowGuildID, emoji.Name, "", nil)
+		}
+	}
+	for _, emoji := range GlobalSpecialEmojis {
+		fake.GuildEmojiCreate(flowGuildID, emoji.Name, "", nil)
+	}
+
+	storageClient := &storage.FilesystemDriver{}
+	if err := storageClient.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := &Bot{
+		url:              "http://localhost",
+		socketPort:       "8123",
+		AllConns:         MakeConnRegistry(),
+		AllGuilds:        MakeGuildRegistry(),
+		Coordinator:      coordination.NewMemoryCoordinator(),
+		EventBus:         MakeEventBus(),
+		SessionManager:   NewSessionManager(fake, nil),
+		StorageInterface: storageClient,
+	}
+	fake.OnVoiceStateUpdate = func(m *discordgo.VoiceStateUpdate) {
+		bot.voiceStateChange()(nil, m)
+	}
+	bot.newGuild()(nil, &discordgo.GuildCreate{Guild: &discordgo.Guild{ID: flowGuildID, Name: "Testgilde"}})
+	return bot, fake
+}
+
+// sendCommand has the owner post the message, and hands it to the bot like the gateway would
+func sendCommand(bot *Bot, fake *FakeDiscord, content string) {
+	posted, _ := fake.ChannelMessageSend(flowTextID, content)
+	bot.messageCreate()(nil, &discordgo.MessageCreate{Message: &discordgo.Message{
+		ID:        posted.ID,
+		ChannelID: flowTextID,
+		GuildID:   flowGuildID,
+		Content:   content,
+		Author:    &discordgo.User{ID: flowOwnerID},
+	}})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(flowWaitTime)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Zeitüberschreitung beim Warten auf: %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func voiceIs(fake *FakeDiscord, userID string, mute, deaf bool) func() bool {
+	return func() bool {
+		vs := fake.VoiceState(flowGuildID, userID)
+		return vs != nil && vs.Mute == mute && vs.Deaf == deaf
+	}
+}
+
+func TestGameFlowOffline(t *testing.T) {
+	bot, fake := fakeBot(t)
+	guild, ok := bot.AllGuilds.Get(flowGuildID)
+	if !ok {
+		t.Fatal("Gilde wurde nicht angelegt")
+	}
+	//no reason to wait in a test
+	guild.PGD().Delays = GameDelays{}
+
+	sendCommand(bot, fake, ".au new ABCDEF eu")
+	if !guild.IsGameRunning() || !guild.GameStateMsg.Exists() {
+		t.Fatal("nach .au new sollte ein Spiel mit Statusmeldung laufen")
+	}
+	if !guild.Tracking.IsTracked(flowVoiceID) {
+		t.Fatal("der Sprachkanal des Spielleiters sollte verfolgt werden")
+	}
+
+	bot.PushGuildPhaseUpdate(flowGuildID, game.LOBBY)
+	bot.PushGuildPlayerUpdate(flowGuildID, game.Player{Action: game.JOINED, Name: "alice", Color: 0})
+	bot.PushGuildPlayerUpdate(flowGuildID, game.Player{Action: game.JOINED, Name: "bob", Color: 1})
+	waitFor(t, "beide Spieler verknüpft", func() bool {
+		return guild.UserData.GetCountLinked() == 2
+	})
+
+	bot.PushGuildPhaseUpdate(flowGuildID, game.TASKS)
+	waitFor(t, "alice in den Aufgaben stumm und taub", voiceIs(fake, flowAliceID, true, true))
+	waitFor(t, "bob in den Aufgaben stumm und taub", voiceIs(fake, flowBobID, true, true))
+	if vs := fake.VoiceState(flowGuildID, flowOwnerID); vs.Mute || vs.Deaf {
+		t.Error("der nicht verknüpfte Spielleiter darf nicht stummgeschaltet werden")
+	}
+
+	bot.PushGuildPlayerUpdate(flowGuildID, game.Player{Action: game.DIED, Name: "bob", Color: 1, IsDead: true})
+	bot.PushGuildPhaseUpdate(flowGuildID, game.DISCUSS)
+	waitFor(t, "alice in der Diskussion hörbar", voiceIs(fake, flowAliceID, false, false))
+	waitFor(t, "der tote bob in der Diskussion stumm", voiceIs(fake, flowBobID, true, false))
+
+	sendCommand(bot, fake, ".au end")
+	waitFor(t, "alice nach dem Spiel hörbar", voiceIs(fake, flowAliceID, false, false))
+	waitFor(t, "bob nach dem Spiel hörbar", voiceIs(fake, flowBobID, false, false))
+	if guild.IsGameRunning() || guild.GameStateMsg.Exists() {
+		t.Error("nach .au end sollte kein Spiel mehr laufen")
+	}
+
+	summaries := 0
+	for _, msg := range fake.Messages(flowTextID) {
+		if len(msg.Embeds) == 1 && msg.Embeds[0].Title == "Spielzusammenfassung" {
+			summaries++
+		}
+	}
+	if summaries != 1 {
+		t.Errorf("erwartet eine Spielzusammenfassung, bekommen %d", summaries)
+	}
+}
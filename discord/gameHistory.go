@@ -0,0 +1,301 @@
+package discord
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/locale"
+	"github.com/denverquane/amongusdiscord/logging"
+)
+
+// GamePlayerRecord is how a single player fared in a finished game
+type GamePlayerRecord struct {
+	//empty if the player was never linked to a Discord user
+	UserID string `json:"userID"`
+	Name   string `json:"name"`
+	Color  int    `json:"color"`
+
+	Died   bool `json:"died"`
+	Exiled bool `json:"exiled"`
+	Left   bool `json:"left"`
+	//unix timestamp of the death or exile, 0 if they survived
+	DeathTime int64 `json:"deathTime"`
+	//how many meetings had been called when the player died or was exiled
+	DeathMeeting int `json:"deathMeeting"`
+}
+
+func (pr *GamePlayerRecord) IsDead() bool {
+	return pr.Died || pr.Exiled
+}
+
+// meetingsSurvived is how many of the game's meetings the player lived through. The meeting that exiled them
+// doesn't count; an exile recorded before its meeting was counted (e.g. out of order events) isn't negative
+func (pr *GamePlayerRecord) meetingsSurvived(meetings int) int {
+	survived := meetings
+	if pr.IsDead() {
+		survived = pr.DeathMeeting
+		if pr.Exiled {
+			survived--
+		}
+	}
+	if survived < 0 {
+		return 0
+	}
+	return survived
+}
+
+// lifetime is how many seconds of the game the player was alive
+func (pr *GamePlayerRecord) lifetime(record *GameRecord) int64 {
+	end := record.EndTime
+	if pr.IsDead() {
+		end = pr.DeathTime
+	}
+	if end < record.StartTime {
+		return 0
+	}
+	return end - record.StartTime
+}
+
+// GameRecord is one game, from the first tasks phase until everyone is back in the lobby
+type GameRecord struct {
+	StartTime int64              `json:"startTime"`
+	EndTime   int64              `json:"endTime"`
+	Meetings  int                `json:"meetings"`
+	Players   []GamePlayerRecord `json:"players"`
+}
+
+// BuildGameRecord turns the events of a finished game into its record. userIDs says which Discord user played
+// as which in-game name
+func BuildGameRecord(events []GameEvent, userIDs map[string]string) *GameRecord {
+	record := &GameRecord{Players: make([]GamePlayerRecord, 0)}
+	players := map[string]int{}
+	getOrAddPlayer := func(event GameEvent) *GamePlayerRecord {
+		if i, ok := players[event.Name]; ok {
+			return &record.Players[i]
+		}
+		players[event.Name] = len(record.Players)
+		record.Players = append(record.Players, GamePlayerRecord{
+			UserID: userIDs[event.Name],
+			Name:   event.Name,
+			Color:  event.Color,
+		})
+		return &record.Players[len(record.Players)-1]
+	}
+
+	for _, event := range events {
+		switch event.Type {
+		case GameStartEvent:
+			record.StartTime = event.Time.Unix()
+		case GameEndEvent:
+			record.EndTime = event.Time.Unix()
+		case MeetingEvent:
+			record.Meetings = event.Meeting
+		case PlayerJoinEvent:
+			getOrAddPlayer(event)
+		case DeathEvent, ExileEvent:
+			pr := getOrAddPlayer(event)
+			if pr.IsDead() {
+				break
+			}
+			pr.Died = event.Type == DeathEvent
+			pr.Exiled = event.Type == ExileEvent
+			pr.DeathTime = event.Time.Unix()
+			pr.DeathMeeting = event.Meeting
+		case LeaveEvent:
+			getOrAddPlayer(event).Left = true
+		}
+	}
+	return record
+}
+
+func (record *GameRecord) ToData() (map[string]interface{}, error) {
+	var data map[string]interface{}
+
+	jsonBytes, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(jsonBytes, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func GameRecordsFromData(data []map[string]interface{}) []GameRecord {
+	records := make([]GameRecord, 0, len(data))
+	for _, v := range data {
+		jsonBytes, err := json.Marshal(v)
+		if err != nil {
+			logging.Error(err)
+			continue
+		}
+		record := GameRecord{}
+		err = json.Unmarshal(jsonBytes, &record)
+		if err != nil {
+			logging.Error(err)
+			continue
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// storeGameRecord stores the record of a finished game
+func (bot *Bot) storeGameRecord(guild *GuildState, events []GameEvent, userIDs map[string]string) {
+	record := BuildGameRecord(events, userIDs)
+	if len(record.Players) == 0 {
+		return
+	}
+
+	data, err := record.ToData()
+	if err != nil {
+		guild.logger().Error(err)
+		return
+	}
+	err = bot.StorageInterface.AddGameRecord(guild.PGD().GuildID, data)
+	if err != nil {
+		guild.logger().Errorf("Fehler beim Speichern des Spiels: %s", err)
+	} else {
+		guild.logger().Infof("Spiel mit %d Spielern gespeichert", len(record.Players))
+	}
+}
+
+// PlayerStats is everything we know about one Discord user across the recorded games
+type PlayerStats struct {
+	UserID           string
+	GamesPlayed      int
+	GamesSurvived    int
+	TimesDied        int
+	TimesExiled      int
+	MeetingsSurvived int
+	//total seconds spent alive, summed over all games
+	TotalLifetime int64
+}
+
+func (ps *PlayerStats) SurvivalRate() float64 {
+	if ps.GamesPlayed == 0 {
+		return 0
+	}
+	return float64(ps.GamesSurvived) / float64(ps.GamesPlayed)
+}
+
+func (ps *PlayerStats) AverageLifetime() time.Duration {
+	if ps.GamesPlayed == 0 {
+		return 0
+	}
+	return time.Duration(ps.TotalLifetime/int64(ps.GamesPlayed)) * time.Second
+}
+
+// ComputePlayerStats aggregates the records into stats for every linked Discord user, by userID
+func ComputePlayerStats(records []GameRecord) map[string]*PlayerStats {
+	stats := map[string]*PlayerStats{}
+	for _, record := range records {
+		for _, player := range record.Players {
+			if player.UserID == "" {
+				continue
+			}
+			ps, ok := stats[player.UserID]
+			if !ok {
+				ps = &PlayerStats{UserID: player.UserID}
+				stats[player.UserID] = ps
+			}
+			ps.GamesPlayed++
+
+			ps.MeetingsSurvived += player.meetingsSurvived(record.Meetings)
+			ps.TotalLifetime += player.lifetime(&record)
+			switch {
+			case player.Exiled:
+				ps.TimesExiled++
+			case player.Died:
+				ps.TimesDied++
+			default:
+				ps.GamesSurvived++
+			}
+		}
+	}
+	return stats
+}
+
+func (bot *Bot) loadGameRecords(guildID string) ([]GameRecord, error) {
+	data, err := bot.StorageInterface.GetGameRecords(guildID)
+	if err != nil {
+		return nil, err
+	}
+	return GameRecordsFromData(data), nil
+}
+
+func (bot *Bot) handleStatsCommand(guild *GuildState, s DiscordClient, m *discordgo.MessageCreate, args []string) {
+	userID := m.Author.ID
+	if len(args) > 1 {
+		id, err := extractUserIDFromMention(args[1])
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("stats.unknownUser", args[1], guild.PGD().CommandPrefix))
+			return
+		}
+		userID = id
+	}
+
+	records, err := bot.loadGameRecords(guild.PGD().GuildID)
+	if err != nil {
+		guild.logger().Error(err)
+		s.ChannelMessageSend(m.ChannelID, guild.tr("history.loadFailed"))
+		return
+	}
+	stats, ok := ComputePlayerStats(records)[userID]
+	if !ok {
+		s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+			Content:         guild.tr("stats.noGames", userID),
+			AllowedMentions: &discordgo.MessageAllowedMentions{Users: nil},
+		})
+		return
+	}
+	sendMessageEmbed(s, m.ChannelID, statsResponse(stats, guild.PGD().Language))
+}
+
+func statsResponse(stats *PlayerStats, lang string) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{
+		Title:       locale.T(lang, "stats.title"),
+		Description: locale.T(lang, "stats.description", stats.UserID),
+		Color:       3447003, //BLUE
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:   locale.T(lang, "stats.games"),
+				Value:  fmt.Sprintf("%d", stats.GamesPlayed),
+				Inline: true,
+			},
+			{
+				Name:   locale.T(lang, "stats.survivalRate"),
+				Value:  fmt.Sprintf("%.0f%%", stats.SurvivalRate()*100),
+				Inline: true,
+			},
+			{
+				Name:   locale.T(lang, "stats.averageLifetime"),
+				Value:  formatLifetime(stats.AverageLifetime()),
+				Inline: true,
+			},
+			{
+				Name:   locale.T(lang, "stats.died"),
+				Value:  fmt.Sprintf("%d", stats.TimesDied),
+				Inline: true,
+			},
+			{
+				Name:   locale.T(lang, "stats.exiled"),
+				Value:  fmt.Sprintf("%d", stats.TimesExiled),
+				Inline: true,
+			},
+			{
+				Name:   locale.T(lang, "stats.meetingsSurvived"),
+				Value:  fmt.Sprintf("%d", stats.MeetingsSurvived),
+				Inline: true,
+			},
+		},
+	}
+}
+
+func formatLifetime(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d min", int(d.Minutes()), int(d.Seconds())%60)
+}
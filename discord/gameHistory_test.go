@@ -0,0 +1,100 @@
+package discord
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGamePlayerRecordMath(t *testing.T) {
+	record := GameRecord{StartTime: 1000, EndTime: 1600, Meetings: 3}
+	tests := []struct {
+		name     string
+		player   GamePlayerRecord
+		meetings int
+		lifetime int64
+	}{
+		{"überlebt", GamePlayerRecord{}, 3, 600},
+		{"vor dem ersten Meeting getötet", GamePlayerRecord{Died: true, DeathTime: 1100}, 0, 100},
+		{"nach dem zweiten Meeting getötet", GamePlayerRecord{Died: true, DeathTime: 1400, DeathMeeting: 2}, 2, 400},
+		{"im zweiten Meeting rausgewählt", GamePlayerRecord{Exiled: true, DeathTime: 1300, DeathMeeting: 2}, 1, 300},
+		{"rausgewählt, bevor das Meeting gezählt wurde", GamePlayerRecord{Exiled: true, DeathTime: 1300}, 0, 300},
+		{"Tod vor dem Start", GamePlayerRecord{Died: true, DeathTime: 900}, 0, 0},
+	}
+	for _, test := range tests {
+		if meetings := test.player.meetingsSurvived(record.Meetings); meetings != test.meetings {
+			t.Errorf("%s: %d Meetings überlebt, erwartet %d", test.name, meetings, test.meetings)
+		}
+		if lifetime := test.player.lifetime(&record); lifetime != test.lifetime {
+			t.Errorf("%s: %d Sekunden gelebt, erwartet %d", test.name, lifetime, test.lifetime)
+		}
+	}
+}
+
+func TestComputePlayerStats(t *testing.T) {
+	records := []GameRecord{
+		{StartTime: 0, EndTime: 600, Meetings: 2, Players: []GamePlayerRecord{
+			{UserID: "1", Name: "alice"},
+			{UserID: "2", Name: "bob", Exiled: true, DeathTime: 300, DeathMeeting: 1},
+			{Name: "nicht verknüpft", Died: true, DeathTime: 100},
+		}},
+		{StartTime: 1000, EndTime: 1300, Meetings: 1, Players: []GamePlayerRecord{
+			{UserID: "1", Name: "alice", Died: true, DeathTime: 1100},
+			//the exile came in before its meeting
+			{UserID: "2", Name: "bob", Exiled: true, DeathTime: 1200},
+		}},
+	}
+	stats := ComputePlayerStats(records)
+	if len(stats) != 2 {
+		t.Fatalf("erwartet Statistiken für 2 Benutzer, bekommen %d", len(stats))
+	}
+	tests := []PlayerStats{
+		{UserID: "1", GamesPlayed: 2, GamesSurvived: 1, TimesDied: 1, MeetingsSurvived: 2, TotalLifetime: 700},
+		{UserID: "2", GamesPlayed: 2, TimesExiled: 2, MeetingsSurvived: 0, TotalLifetime: 500},
+	}
+	for _, want := range tests {
+		got, ok := stats[want.UserID]
+		if !ok {
+			t.Errorf("keine Statistik für %s", want.UserID)
+			continue
+		}
+		if *got != want {
+			t.Errorf("Statistik für %s ist %+v, erwartet %+v", want.UserID, *got, want)
+		}
+	}
+	if rate := stats["1"].SurvivalRate(); rate != 0.5 {
+		t.Errorf("Überlebensrate %f, erwartet 0.5", rate)
+	}
+}
+
+func TestBuildGameRecord(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	events := []GameEvent{
+		{Type: GameStartEvent, Time: start},
+		{Type: PlayerJoinEvent, Time: start, Name: "Rot", Color: 0},
+		{Type: PlayerJoinEvent, Time: start, Name: "Blau", Color: 1},
+		{Type: DeathEvent, Time: start.Add(time.Minute), Name: "Rot", Color: 0},
+		{Type: MeetingEvent, Time: start.Add(2 * time.Minute), Meeting: 1},
+		{Type: ExileEvent, Time: start.Add(3 * time.Minute), Meeting: 1, Name: "Blau", Color: 1},
+		//dead is dead, even if the capture says it twice
+		{Type: DeathEvent, Time: start.Add(4 * time.Minute), Meeting: 1, Name: "Blau", Color: 1},
+		{Type: LeaveEvent, Time: start.Add(4 * time.Minute), Meeting: 1, Name: "Blau", Color: 1},
+		{Type: GameEndEvent, Time: start.Add(5 * time.Minute), Meeting: 1},
+	}
+	record := BuildGameRecord(events, map[string]string{"Blau": "11"})
+
+	if record.StartTime != start.Unix() || record.EndTime != start.Add(5*time.Minute).Unix() || record.Meetings != 1 {
+		t.Errorf("unerwartetes Spiel: %+v", record)
+	}
+	expected := []GamePlayerRecord{
+		{Name: "Rot", Color: 0, Died: true, DeathTime: start.Add(time.Minute).Unix()},
+		{UserID: "11", Name: "Blau", Color: 1, Exiled: true, Left: true, DeathTime: start.Add(3 * time.Minute).Unix(), DeathMeeting: 1},
+	}
+	if len(record.Players) != len(expected) {
+		t.Fatalf("%d Spieler, erwartet %d", len(record.Players), len(expected))
+	}
+	for i, want := range expected {
+		if record.Players[i] != want {
+			t.Errorf("Spieler %d ist %+v, erwartet %+v", i, record.Players[i], want)
+		}
+	}
+}
@@ -2,20 +2,48 @@ package discord
 
 import (
 	"github.com/bwmarrin/discordgo"
-	"log"
+	"github.com/denverquane/amongusdiscord/logging"
+	"reflect"
 	"sync"
 	"time"
 )
 
-//TODO up this for a full public rollout
-const EditDelaySeconds = 1
+// DefaultEditWindow is how long changes to a status message are collected into one edit, unless the guild chose
+// its own window
+var DefaultEditWindow = time.Second
+
+// MinEditWindow and MaxEditWindow limit the window a guild can choose
+const (
+	MinEditWindow = 250 * time.Millisecond
+	MaxEditWindow = 10 * time.Second
+)
+
+//rate limits widen the window up to this
+const maxEditBackoff = 30 * time.Second
 
 type GameStateMessage struct {
 	message  *discordgo.Message
 	leaderID string //who started the game
 	lock     sync.RWMutex
 
+	//copies of the message in other channels, by channel ID. They're edited along with it, but don't take reactions
+	mirrors map[string]*discordgo.Message
+	//set if Discord refused the message's buttons and select menu; players then link by reactions or commands
+	noComponents bool
+
+	//how many messages were posted below the message, and when it was posted
+	messagesBelow int
+	created       time.Time
+
 	deferredEdit *discordgo.MessageEmbed
+	//what the message shows, so edits that wouldn't change anything are skipped
+	lastSent *discordgo.MessageEmbed
+
+	//the guild's window (0 for the default), and how far rate limits widened it
+	window  time.Duration
+	backoff time.Duration
+	//wakes the waiting worker for an edit that shouldn't wait for the window
+	wake chan struct{}
 }
 
 func MakeGameStateMessage() GameStateMessage {
@@ -23,6 +51,8 @@ func MakeGameStateMessage() GameStateMessage {
 		message:  nil,
 		leaderID: "",
 		lock:     sync.RWMutex{},
+		mirrors:  map[string]*discordgo.Message{},
+		wake:     make(chan struct{}, 1),
 	}
 }
 
@@ -32,7 +62,7 @@ func (gsm *GameStateMessage) Exists() bool {
 	return gsm.message != nil
 }
 
-func (gsm *GameStateMessage) AddReaction(s *discordgo.Session, emoji string) {
+func (gsm *GameStateMessage) AddReaction(s DiscordClient, emoji string) {
 	gsm.lock.Lock()
 	if gsm.message != nil {
 		addReaction(s, gsm.message.ChannelID, gsm.message.ID, emoji)
@@ -40,7 +70,7 @@ func (gsm *GameStateMessage) AddReaction(s *discordgo.Session, emoji string) {
 	gsm.lock.Unlock()
 }
 
-func (gsm *GameStateMessage) RemoveAllReactions(s *discordgo.Session) {
+func (gsm *GameStateMessage) RemoveAllReactions(s DiscordClient) {
 	gsm.lock.Lock()
 	if gsm.message != nil {
 		removeAllReactions(s, gsm.message.ChannelID, gsm.message.ID)
@@ -48,54 +78,272 @@ func (gsm *GameStateMessage) RemoveAllReactions(s *discordgo.Session) {
 	gsm.lock.Unlock()
 }
 
-func (gsm *GameStateMessage) AddAllReactions(s *discordgo.Session, emojis []Emoji) {
+// SetComponents replaces the buttons and select menus of the message. Mirrors don't take them, like reactions
+func (gsm *GameStateMessage) SetComponents(s DiscordClient, components []MessageComponent) error {
+	gsm.lock.Lock()
+	defer gsm.lock.Unlock()
+	if gsm.message == nil {
+		return nil
+	}
+	return s.ChannelMessageEditComponents(gsm.message.ChannelID, gsm.message.ID, components)
+}
+
+// AddComponents gives the message the components players link with, and notes whether Discord took them. It
+// returns whether that changed, so the footer can tell players how to link
+func (gsm *GameStateMessage) AddComponents(s DiscordClient, components []MessageComponent) (bool, error) {
+	gsm.lock.Lock()
+	defer gsm.lock.Unlock()
+	if gsm.message == nil {
+		return false, nil
+	}
+	err := s.ChannelMessageEditComponents(gsm.message.ChannelID, gsm.message.ID, components)
+	changed := (err != nil) != gsm.noComponents
+	gsm.noComponents = err != nil
+	return changed, err
+}
+
+// NoComponents is true if Discord refused the components
+func (gsm *GameStateMessage) NoComponents() bool {
+	gsm.lock.RLock()
+	defer gsm.lock.RUnlock()
+	return gsm.noComponents
+}
+
+func (gsm *GameStateMessage) AddAllReactions(s DiscordClient, emojis []Emoji) {
 	for _, e := range emojis {
 		gsm.AddReaction(s, e.FormatForReaction())
 	}
 	gsm.AddReaction(s, "❌")
 }
 
-func (gsm *GameStateMessage) Delete(s *discordgo.Session) {
+func (gsm *GameStateMessage) Delete(s DiscordClient) {
 	gsm.lock.Lock()
 	if gsm.message != nil {
 		go deleteMessage(s, gsm.message.ChannelID, gsm.message.ID)
 		gsm.message = nil
 	}
+	for channelID, mirror := range gsm.mirrors {
+		go deleteMessage(s, mirror.ChannelID, mirror.ID)
+		delete(gsm.mirrors, channelID)
+	}
 	gsm.lock.Unlock()
 }
 
-func (gsm *GameStateMessage) Edit(s *discordgo.Session, me *discordgo.MessageEmbed) {
+// DeleteMessage deletes only the message itself, and keeps its mirrors where they are
+func (gsm *GameStateMessage) DeleteMessage(s DiscordClient) {
 	gsm.lock.Lock()
+	if gsm.message != nil {
+		go deleteMessage(s, gsm.message.ChannelID, gsm.message.ID)
+		gsm.message = nil
+	}
+	gsm.lock.Unlock()
+}
+
+// Edit changes the message once the window is over, together with all other changes made until then
+func (gsm *GameStateMessage) Edit(s DiscordClient, me *discordgo.MessageEmbed) {
+	gsm.edit(s, me, false)
+}
+
+// EditNow changes the message right away, taking along changes that were waiting for the window. It's meant for
+// phase changes, which players have to see before anything cosmetic
+func (gsm *GameStateMessage) EditNow(s DiscordClient, me *discordgo.MessageEmbed) {
+	gsm.edit(s, me, true)
+}
+
+func (gsm *GameStateMessage) edit(s DiscordClient, me *discordgo.MessageEmbed, now bool) {
+	gsm.lock.Lock()
+	defer gsm.lock.Unlock()
+	if gsm.wake == nil {
+		gsm.wake = make(chan struct{}, 1)
+	}
 	//the worker is already waiting to update the message, so just swap the message in-place
 	if gsm.deferredEdit != nil {
 		gsm.deferredEdit = me //swap with the newer message
+		statusMessageEdits.WithLabelValues("coalesced").Inc()
 	} else {
 		gsm.deferredEdit = me
 		//the edit is empty, so there isn't a worker waiting to update it
-		go gsm.EditWorker(s, EditDelaySeconds)
+		go gsm.editWorker(s, gsm.currentWindow(), gsm.wake)
+	}
+	if now {
+		select {
+		case gsm.wake <- struct{}{}:
+		default:
+			//the worker is already woken up
+		}
 	}
-	gsm.lock.Unlock()
 }
 
-func (gsm *GameStateMessage) EditWorker(s *discordgo.Session, delay int) {
-	log.Printf("Warte %d Sekunden, um die Statusmeldung so zu aktualisieren, dass sie nicht auf die Rate beschränkt ist", delay)
-	time.Sleep(time.Duration(delay) * time.Second)
+func (gsm *GameStateMessage) editWorker(s DiscordClient, window time.Duration, wake chan struct{}) {
+	logging.Debugf("Warte %s, um Änderungen an der Statusmeldung zu einer Bearbeitung zusammenzufassen", window)
+	timer := time.NewTimer(window)
+	select {
+	case <-timer.C:
+	case <-wake:
+		timer.Stop()
+	}
 
 	gsm.lock.Lock()
-	if gsm.message != nil {
-		editMessageEmbed(s, gsm.message.ChannelID, gsm.message.ID, gsm.deferredEdit)
+	defer gsm.lock.Unlock()
+	//a wake-up that came in after the window was over is meant for this edit, not the next one
+	select {
+	case <-wake:
+	default:
 	}
+	me := gsm.deferredEdit
 	gsm.deferredEdit = nil
-	gsm.lock.Unlock()
+	if me == nil || reflect.DeepEqual(me, gsm.lastSent) {
+		statusMessageEdits.WithLabelValues("skipped").Inc()
+		return
+	}
+	sent := true
+	if gsm.message != nil {
+		sent = editMessageEmbed(s, gsm.message.ChannelID, gsm.message.ID, me) != nil
+	}
+	for _, mirror := range gsm.mirrors {
+		sent = editMessageEmbed(s, mirror.ChannelID, mirror.ID, me) != nil && sent
+	}
+	//after a failed edit, nobody knows what the message shows, so the next edit goes through even if it's the same
+	if !sent {
+		statusMessageEdits.WithLabelValues("failed").Inc()
+		gsm.lastSent = nil
+		return
+	}
+	statusMessageEdits.WithLabelValues("sent").Inc()
+	gsm.lastSent = me
+	//every edit that gets through without a rate limit narrows the window again
+	gsm.backoff /= 2
+	if gsm.backoff <= gsm.baseWindow() {
+		gsm.backoff = 0
+	}
+}
+
+func (gsm *GameStateMessage) baseWindow() time.Duration {
+	if gsm.window > 0 {
+		return gsm.window
+	}
+	return DefaultEditWindow
+}
+
+func (gsm *GameStateMessage) currentWindow() time.Duration {
+	if window := gsm.baseWindow(); window > gsm.backoff {
+		return window
+	}
+	return gsm.backoff
+}
+
+// SetEditWindow sets the guild's window; 0 is the default one
+func (gsm *GameStateMessage) SetEditWindow(window time.Duration) {
+	gsm.lock.Lock()
+	defer gsm.lock.Unlock()
+	gsm.window = window
+}
+
+// EditWindow is how long the next edit waits for more changes, including the widening from rate limits
+func (gsm *GameStateMessage) EditWindow() time.Duration {
+	gsm.lock.RLock()
+	defer gsm.lock.RUnlock()
+	return gsm.currentWindow()
+}
+
+// Widened is true while rate limits keep the window wider than the guild's
+func (gsm *GameStateMessage) Widened() bool {
+	gsm.lock.RLock()
+	defer gsm.lock.RUnlock()
+	return gsm.backoff > gsm.baseWindow()
 }
 
-func (gsm *GameStateMessage) CreateMessage(s *discordgo.Session, me *discordgo.MessageEmbed, channelID string, authorID string) {
+// RateLimited doubles the window if Discord rate limited one of the message's channels, but at least to the time
+// Discord asked to wait. It's false if the channel has nothing to do with the message
+func (gsm *GameStateMessage) RateLimited(channelID string, retryAfter time.Duration) bool {
+	gsm.lock.Lock()
+	defer gsm.lock.Unlock()
+	if gsm.message == nil {
+		return false
+	}
+	if _, ok := gsm.mirrors[channelID]; !ok && gsm.message.ChannelID != channelID {
+		return false
+	}
+	widened := 2 * gsm.currentWindow()
+	if widened < retryAfter {
+		widened = retryAfter
+	}
+	if widened > maxEditBackoff {
+		widened = maxEditBackoff
+	}
+	gsm.backoff = widened
+	return true
+}
+
+func (gsm *GameStateMessage) CreateMessage(s DiscordClient, me *discordgo.MessageEmbed, channelID string, authorID string) {
 	gsm.lock.Lock()
 	gsm.leaderID = authorID
 	gsm.message = sendMessageEmbed(s, channelID, me)
+	gsm.lastSent = me
+	gsm.messagesBelow = 0
+	gsm.created = time.Now()
+	//an edit that's still waiting would put older contents into the new message
+	if gsm.deferredEdit != nil {
+		gsm.deferredEdit = me
+	}
 	gsm.lock.Unlock()
 }
 
+// AddMirror posts a copy of the message to the channel. There's nothing to mirror without a message, and the
+// message's own channel doesn't need a copy
+func (gsm *GameStateMessage) AddMirror(s DiscordClient, me *discordgo.MessageEmbed, channelID string) {
+	gsm.lock.Lock()
+	defer gsm.lock.Unlock()
+	if gsm.message == nil || gsm.message.ChannelID == channelID {
+		return
+	}
+	if _, ok := gsm.mirrors[channelID]; ok {
+		return
+	}
+	mirror := sendMessageEmbed(s, channelID, me)
+	if mirror != nil {
+		if gsm.mirrors == nil {
+			gsm.mirrors = map[string]*discordgo.Message{}
+		}
+		gsm.mirrors[channelID] = mirror
+	}
+}
+
+// RemoveMirror deletes the copy of the message in the channel, if there is one
+func (gsm *GameStateMessage) RemoveMirror(s DiscordClient, channelID string) {
+	gsm.lock.Lock()
+	defer gsm.lock.Unlock()
+	if mirror, ok := gsm.mirrors[channelID]; ok {
+		go deleteMessage(s, mirror.ChannelID, mirror.ID)
+		delete(gsm.mirrors, channelID)
+	}
+}
+
+// CountMessage notes a message posted in the channel, which pushes the status message further up
+func (gsm *GameStateMessage) CountMessage(channelID string) {
+	gsm.lock.Lock()
+	defer gsm.lock.Unlock()
+	if gsm.message != nil && gsm.message.ChannelID == channelID {
+		gsm.messagesBelow++
+	}
+}
+
+// ClaimBump is true if the message is buried under at least threshold messages (or any at all, with force), and
+// wasn't posted in the last interval. Only one caller gets to bump it; it has to post the message again
+func (gsm *GameStateMessage) ClaimBump(threshold int, force bool, interval time.Duration) bool {
+	gsm.lock.Lock()
+	defer gsm.lock.Unlock()
+	if gsm.message == nil || gsm.messagesBelow == 0 || (!force && gsm.messagesBelow < threshold) {
+		return false
+	}
+	if time.Since(gsm.created) < interval {
+		return false
+	}
+	gsm.messagesBelow = 0
+	gsm.created = time.Now()
+	return true
+}
+
 func (gsm *GameStateMessage) SameChannel(channelID string) bool {
 	gsm.lock.RLock()
 	defer gsm.lock.RUnlock()
@@ -105,6 +353,23 @@ func (gsm *GameStateMessage) SameChannel(channelID string) bool {
 	return false
 }
 
+// ChannelID returns the channel the status message is in, or an empty string if there isn't one
+func (gsm *GameStateMessage) ChannelID() string {
+	gsm.lock.RLock()
+	defer gsm.lock.RUnlock()
+	if gsm.message != nil {
+		return gsm.message.ChannelID
+	}
+	return ""
+}
+
+// IsMessage is true if the message is the status message, and not one of its mirrors
+func (gsm *GameStateMessage) IsMessage(messageID string) bool {
+	gsm.lock.RLock()
+	defer gsm.lock.RUnlock()
+	return gsm.message != nil && gsm.message.ID == messageID
+}
+
 func (gsm *GameStateMessage) IsReactionTo(m *discordgo.MessageReactionAdd) bool {
 	gsm.lock.RLock()
 	defer gsm.lock.RUnlock()
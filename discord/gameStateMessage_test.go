@@ -0,0 +1,122 @@
+package discord
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// editCounter counts the edits that actually reach Discord, and fails them while failing is set
+type editCounter struct {
+	*FakeDiscord
+	edits   int32
+	failing int32
+}
+
+func (ec *editCounter) ChannelMessageEditEmbed(channelID, messageID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
+	atomic.AddInt32(&ec.edits, 1)
+	if atomic.LoadInt32(&ec.failing) != 0 {
+		return nil, errors.New("Discord ist nicht erreichbar")
+	}
+	return ec.FakeDiscord.ChannelMessageEditEmbed(channelID, messageID, embed)
+}
+
+func (ec *editCounter) count() int {
+	return int(atomic.LoadInt32(&ec.edits))
+}
+
+func TestEditSkipsUnchangedEmbeds(t *testing.T) {
+	s := &editCounter{FakeDiscord: NewFakeDiscord("bot")}
+	gsm := MakeGameStateMessage()
+	gsm.SetEditWindow(MinEditWindow)
+	gsm.CreateMessage(s, &discordgo.MessageEmbed{Title: "Lobby"}, flowTextID, flowOwnerID)
+
+	gsm.Edit(s, &discordgo.MessageEmbed{Title: "Lobby"})
+	time.Sleep(3 * MinEditWindow)
+	if s.count() != 0 {
+		t.Fatalf("die Meldung zeigt das schon, trotzdem %d Bearbeitungen", s.count())
+	}
+
+	gsm.Edit(s, &discordgo.MessageEmbed{Title: "Aufgaben"})
+	gsm.Edit(s, &discordgo.MessageEmbed{Title: "Diskussion"})
+	waitFor(t, "die Bearbeitung", func() bool { return s.count() > 0 })
+	time.Sleep(2 * MinEditWindow)
+	if s.count() != 1 {
+		t.Errorf("beide Änderungen sollten eine Bearbeitung sein, nicht %d", s.count())
+	}
+	if embeds := statusEmbeds(s.FakeDiscord, flowTextID); len(embeds) != 1 || embeds[0].Title != "Diskussion" {
+		t.Errorf("die Meldung sollte die neuere Änderung zeigen: %+v", embeds)
+	}
+}
+
+func TestEditNowSkipsTheWindow(t *testing.T) {
+	s := &editCounter{FakeDiscord: NewFakeDiscord("bot")}
+	gsm := MakeGameStateMessage()
+	gsm.SetEditWindow(MaxEditWindow)
+	gsm.CreateMessage(s, &discordgo.MessageEmbed{Title: "Lobby"}, flowTextID, flowOwnerID)
+
+	gsm.Edit(s, &discordgo.MessageEmbed{Title: "Lobby", Description: "alice ist verknüpft"})
+	gsm.EditNow(s, &discordgo.MessageEmbed{Title: "Aufgaben"})
+	waitFor(t, "den Phasenwechsel", func() bool { return s.count() == 1 })
+	if embeds := statusEmbeds(s.FakeDiscord, flowTextID); embeds[0].Title != "Aufgaben" {
+		t.Errorf("die Meldung sollte die Aufgaben zeigen, nicht %q", embeds[0].Title)
+	}
+}
+
+func TestFailedEditIsSentAgain(t *testing.T) {
+	s := &editCounter{FakeDiscord: NewFakeDiscord("bot")}
+	gsm := MakeGameStateMessage()
+	gsm.SetEditWindow(MinEditWindow)
+	gsm.CreateMessage(s, &discordgo.MessageEmbed{Title: "Lobby"}, flowTextID, flowOwnerID)
+
+	atomic.StoreInt32(&s.failing, 1)
+	gsm.EditNow(s, &discordgo.MessageEmbed{Title: "Aufgaben"})
+	waitFor(t, "die fehlgeschlagene Bearbeitung", func() bool { return s.count() == 1 })
+
+	//the message still shows the lobby, so the same edit has to go out again
+	atomic.StoreInt32(&s.failing, 0)
+	gsm.EditNow(s, &discordgo.MessageEmbed{Title: "Aufgaben"})
+	waitFor(t, "die wiederholte Bearbeitung", func() bool { return s.count() == 2 })
+	if embeds := statusEmbeds(s.FakeDiscord, flowTextID); embeds[0].Title != "Aufgaben" {
+		t.Errorf("die Meldung sollte die Aufgaben zeigen, nicht %q", embeds[0].Title)
+	}
+}
+
+func TestRateLimitWidensTheWindow(t *testing.T) {
+	s := NewFakeDiscord("bot")
+	gsm := MakeGameStateMessage()
+	gsm.SetEditWindow(time.Second)
+	gsm.CreateMessage(s, &discordgo.MessageEmbed{Title: "Lobby"}, flowTextID, flowOwnerID)
+
+	if gsm.RateLimited(flowVoiceID, 0) {
+		t.Error("ein anderer Kanal betrifft die Meldung nicht")
+	}
+	if channelID := channelFromURL("https://discord.com/api/v8/channels/" + flowTextID + "/messages/1001"); !gsm.RateLimited(channelID, 0) {
+		t.Fatalf("der Kanal %q sollte die Meldung betreffen", channelID)
+	}
+	if gsm.EditWindow() != 2*time.Second || !gsm.Widened() {
+		t.Errorf("das Fenster sollte sich verdoppeln, nicht %s sein", gsm.EditWindow())
+	}
+	gsm.RateLimited(flowTextID, 5*time.Second)
+	if gsm.EditWindow() != 5*time.Second {
+		t.Errorf("das Fenster sollte so lang sein, wie Discord es verlangt, nicht %s", gsm.EditWindow())
+	}
+	for i := 0; i < 5; i++ {
+		gsm.RateLimited(flowTextID, 0)
+	}
+	if gsm.EditWindow() != maxEditBackoff {
+		t.Errorf("das Fenster sollte höchstens %s sein, nicht %s", maxEditBackoff, gsm.EditWindow())
+	}
+
+	//an edit that goes through narrows it again
+	wake := make(chan struct{}, 1)
+	wake <- struct{}{}
+	gsm.deferredEdit = &discordgo.MessageEmbed{Title: "Aufgaben"}
+	gsm.editWorker(s, time.Hour, wake)
+	if gsm.EditWindow() != maxEditBackoff/2 {
+		t.Errorf("das Fenster sollte sich halbieren, nicht %s sein", gsm.EditWindow())
+	}
+}
@@ -0,0 +1,316 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/locale"
+)
+
+type GameEventType int
+
+const (
+	GameStartEvent GameEventType = iota
+	PlayerJoinEvent
+	MeetingEvent
+	DeathEvent
+	ExileEvent
+	LeaveEvent
+	GameEndEvent
+)
+
+// GameEvent is one thing that happened during a game, in the order the capture reported it
+type GameEvent struct {
+	Type GameEventType
+	Time time.Time
+	//how many meetings had been called when the event happened
+	Meeting int
+	//only set for player events
+	Name  string
+	Color int
+}
+
+// GameEventLog collects the events of the game that's currently being played, for the summary and the
+// stored record afterwards
+type GameEventLog struct {
+	events   []GameEvent
+	meetings int
+	//which Discord user played as which in-game name
+	userIDs map[string]string
+	lock    sync.Mutex
+}
+
+func MakeGameEventLog() GameEventLog {
+	return GameEventLog{
+		events:   nil,
+		meetings: 0,
+		userIDs:  nil,
+		lock:     sync.Mutex{},
+	}
+}
+
+func (gel *GameEventLog) IsActive() bool {
+	gel.lock.Lock()
+	defer gel.lock.Unlock()
+	return gel.events != nil
+}
+
+// Start begins a new log with the players that are currently known
+func (gel *GameEventLog) Start(nameColors map[string]int) {
+	gel.lock.Lock()
+	defer gel.lock.Unlock()
+
+	now := time.Now()
+	gel.meetings = 0
+	gel.userIDs = map[string]string{}
+	gel.events = []GameEvent{{Type: GameStartEvent, Time: now}}
+	for name, color := range nameColors {
+		gel.events = append(gel.events, GameEvent{Type: PlayerJoinEvent, Time: now, Name: name, Color: color})
+	}
+}
+
+func (gel *GameEventLog) AddMeeting() {
+	gel.lock.Lock()
+	defer gel.lock.Unlock()
+	if gel.events == nil {
+		return
+	}
+	gel.meetings++
+	gel.events = append(gel.events, GameEvent{Type: MeetingEvent, Time: time.Now(), Meeting: gel.meetings})
+}
+
+// AddPlayerEvent logs the player update, if it's something that belongs in the summary
+func (gel *GameEventLog) AddPlayerEvent(player game.Player) {
+	gel.lock.Lock()
+	defer gel.lock.Unlock()
+	if gel.events == nil {
+		return
+	}
+
+	eventType := GameEventType(-1)
+	switch player.Action {
+	case game.JOINED:
+		eventType = PlayerJoinEvent
+	case game.DIED:
+		eventType = DeathEvent
+	case game.EXILED:
+		eventType = ExileEvent
+	case game.LEFT:
+		fallthrough
+	case game.DISCONNECTED:
+		eventType = LeaveEvent
+	}
+	if player.Disconnected {
+		eventType = LeaveEvent
+	}
+	if eventType < 0 {
+		return
+	}
+	gel.events = append(gel.events, GameEvent{
+		Type:    eventType,
+		Time:    time.Now(),
+		Meeting: gel.meetings,
+		Name:    player.Name,
+		Color:   player.Color,
+	})
+}
+
+// LinkUsers remembers which Discord user plays as which in-game name; a later link for the name wins
+func (gel *GameEventLog) LinkUsers(users []game.UserData) {
+	gel.lock.Lock()
+	defer gel.lock.Unlock()
+	if gel.events == nil {
+		return
+	}
+	for _, user := range users {
+		gel.userIDs[user.GetPlayerName()] = user.GetID()
+	}
+}
+
+// Finish closes the log, and returns its events (or nil if no game was being logged) and who played as who
+func (gel *GameEventLog) Finish() ([]GameEvent, map[string]string) {
+	gel.lock.Lock()
+	defer gel.lock.Unlock()
+
+	events := gel.events
+	gel.events = nil
+	if events != nil {
+		events = append(events, GameEvent{Type: GameEndEvent, Time: time.Now(), Meeting: gel.meetings})
+	}
+	return events, gel.userIDs
+}
+
+// Discard closes the log without returning the events
+func (gel *GameEventLog) Discard() {
+	gel.lock.Lock()
+	gel.events = nil
+	gel.lock.Unlock()
+}
+
+type SummaryPlayerState int
+
+const (
+	SummaryAlive SummaryPlayerState = iota
+	SummaryDied
+	SummaryExiled
+	SummaryLeft
+)
+
+// SummaryPlayer is how a player ended the game
+type SummaryPlayer struct {
+	Name  string
+	Color int
+	State SummaryPlayerState
+}
+
+func (sp *SummaryPlayer) IsAlive() bool {
+	return sp.State == SummaryAlive
+}
+
+// GameSummary is what's left of a game's event log once it's over
+type GameSummary struct {
+	Duration time.Duration
+	Meetings int
+	//sorted by color, like the status message
+	Players []SummaryPlayer
+	//the death and exile events, in order
+	Deaths []GameEvent
+}
+
+// SummarizeGame builds the summary from the events of a finished game
+func SummarizeGame(events []GameEvent) GameSummary {
+	summary := GameSummary{}
+	players := map[string]*SummaryPlayer{}
+	var start time.Time
+
+	for _, event := range events {
+		switch event.Type {
+		case GameStartEvent:
+			start = event.Time
+		case GameEndEvent:
+			summary.Duration = event.Time.Sub(start)
+		case MeetingEvent:
+			summary.Meetings = event.Meeting
+		case PlayerJoinEvent:
+			if _, ok := players[event.Name]; !ok {
+				players[event.Name] = &SummaryPlayer{Name: event.Name, Color: event.Color, State: SummaryAlive}
+			}
+		case DeathEvent, ExileEvent, LeaveEvent:
+			player, ok := players[event.Name]
+			if !ok {
+				player = &SummaryPlayer{Name: event.Name, Color: event.Color, State: SummaryAlive}
+				players[event.Name] = player
+			}
+			//dead players can still leave, but they stay dead as far as the summary is concerned
+			if player.State != SummaryAlive {
+				break
+			}
+			switch event.Type {
+			case DeathEvent:
+				player.State = SummaryDied
+				summary.Deaths = append(summary.Deaths, event)
+			case ExileEvent:
+				player.State = SummaryExiled
+				summary.Deaths = append(summary.Deaths, event)
+			default:
+				player.State = SummaryLeft
+			}
+		}
+	}
+
+	summary.Players = make([]SummaryPlayer, 0, len(players))
+	for _, v := range players {
+		summary.Players = append(summary.Players, *v)
+	}
+	sort.Slice(summary.Players, func(i, j int) bool {
+		if summary.Players[i].Color == summary.Players[j].Color {
+			return summary.Players[i].Name < summary.Players[j].Name
+		}
+		return summary.Players[i].Color < summary.Players[j].Color
+	})
+	return summary
+}
+
+// finishGame closes the event log, stores the game's record and posts its summary, if a game was being logged
+func (bot *Bot) finishGame(guild *GuildState, s DiscordClient) {
+	//anyone who linked mid-game should still get credit for it
+	guild.GameEventLog.LinkUsers(guild.UserData.GetLinkedUsers())
+	events, userIDs := guild.GameEventLog.Finish()
+	if events == nil {
+		return
+	}
+	bot.storeGameRecord(guild, events, userIDs)
+	bot.postGameSummary(guild, s, events, userIDs)
+}
+
+// postGameSummary posts the summary of the finished game to the game channel
+func (bot *Bot) postGameSummary(guild *GuildState, s DiscordClient, events []GameEvent, userIDs map[string]string) {
+	channelID := guild.GameStateMsg.ChannelID()
+	if channelID == "" {
+		return
+	}
+	summary := SummarizeGame(events)
+	if len(summary.Players) == 0 {
+		return
+	}
+
+	sendMessageEmbed(s, channelID, gameSummaryResponse(&summary, userIDs, guild.statusEmojis(), guild.PGD().Language))
+}
+
+func gameSummaryResponse(summary *GameSummary, userIDs map[string]string, emojis AlivenessEmojis, lang string) *discordgo.MessageEmbed {
+	fields := make([]*discordgo.MessageEmbedField, 0, len(summary.Players)+1)
+	for _, player := range summary.Players {
+		emoji := emojis[player.IsAlive()][player.Color]
+		value := emoji.FormatForInline() + " "
+		switch player.State {
+		case SummaryDied:
+			value += locale.T(lang, "summary.died")
+		case SummaryExiled:
+			value += locale.T(lang, "summary.exiled")
+		case SummaryLeft:
+			value += locale.T(lang, "summary.left")
+		default:
+			value += locale.T(lang, "summary.survived")
+		}
+		if userID, ok := userIDs[player.Name]; ok {
+			value += fmt.Sprintf(" <@!%s>", userID)
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   player.Name,
+			Value:  value,
+			Inline: true,
+		})
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	if len(summary.Deaths) == 0 {
+		buf.WriteString(locale.T(lang, "summary.nobodyDied"))
+	}
+	for i, event := range summary.Deaths {
+		emoji := emojis[false][event.Color]
+		if event.Type == ExileEvent {
+			buf.WriteString(locale.T(lang, "summary.exiledIn", i+1, emoji.FormatForInline(), event.Name, event.Meeting) + "\n")
+		} else if event.Meeting == 0 {
+			buf.WriteString(locale.T(lang, "summary.diedBeforeFirst", i+1, emoji.FormatForInline(), event.Name) + "\n")
+		} else {
+			buf.WriteString(locale.T(lang, "summary.diedAfter", i+1, emoji.FormatForInline(), event.Name, event.Meeting) + "\n")
+		}
+	}
+	fields = append(fields, &discordgo.MessageEmbedField{
+		Name:   locale.T(lang, "summary.deaths"),
+		Value:  buf.String(),
+		Inline: false,
+	})
+
+	return &discordgo.MessageEmbed{
+		Title:       locale.T(lang, "summary.title"),
+		Description: locale.T(lang, "summary.description", formatLifetime(summary.Duration), summary.Meetings),
+		Color:       10181046, //PURPLE
+		Fields:      fields,
+	}
+}
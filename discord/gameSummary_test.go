@@ -0,0 +1,45 @@
+package discord
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizeGame(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	events := []GameEvent{
+		{Type: GameStartEvent, Time: start},
+		{Type: PlayerJoinEvent, Time: start, Name: "Blau", Color: 1},
+		{Type: PlayerJoinEvent, Time: start, Name: "Rot", Color: 0},
+		{Type: PlayerJoinEvent, Time: start, Name: "Grün", Color: 2},
+		{Type: DeathEvent, Time: start.Add(time.Minute), Meeting: 0, Name: "Grün", Color: 2},
+		{Type: MeetingEvent, Time: start.Add(2 * time.Minute), Meeting: 1},
+		{Type: ExileEvent, Time: start.Add(3 * time.Minute), Meeting: 1, Name: "Blau", Color: 1},
+		{Type: LeaveEvent, Time: start.Add(4 * time.Minute), Meeting: 1, Name: "Blau", Color: 1},
+		{Type: GameEndEvent, Time: start.Add(5 * time.Minute), Meeting: 1},
+	}
+	summary := SummarizeGame(events)
+
+	if summary.Duration != 5*time.Minute {
+		t.Errorf("Dauer ist %s, erwartet 5m", summary.Duration)
+	}
+	if summary.Meetings != 1 {
+		t.Errorf("%d Meetings, erwartet 1", summary.Meetings)
+	}
+	expected := []SummaryPlayer{
+		{Name: "Rot", Color: 0, State: SummaryAlive},
+		{Name: "Blau", Color: 1, State: SummaryExiled},
+		{Name: "Grün", Color: 2, State: SummaryDied},
+	}
+	if len(summary.Players) != len(expected) {
+		t.Fatalf("%d Spieler, erwartet %d", len(summary.Players), len(expected))
+	}
+	for i, v := range expected {
+		if summary.Players[i] != v {
+			t.Errorf("Spieler %d ist %+v, erwartet %+v", i, summary.Players[i], v)
+		}
+	}
+	if len(summary.Deaths) != 2 || summary.Deaths[0].Name != "Grün" || summary.Deaths[1].Name != "Blau" {
+		t.Errorf("falsche Reihenfolge der Tode: %+v", summary.Deaths)
+	}
+}
@@ -3,8 +3,8 @@ package discord
 import (
 	"container/heap"
 	"fmt"
-	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -45,20 +45,81 @@ func (gd *GameDelays) GetDelay(origin, dest game.Phase) int {
 
 // GuildState struct
 type GuildState struct {
-	PersistentGuildData *PersistentGuildData
+	//swapped as a whole when the settings are reloaded or imported; use PGD and SetPGD
+	persistentGuildData *PersistentGuildData
+	pgdLock             sync.RWMutex
 
-	Linked bool
+	//whether a capture is linked; accessed atomically
+	linked int32
 
-	UserData UserDataSet
-	Tracking Tracking
+	UserData    UserDataSet
+	Tracking    Tracking
+	LinkHistory LinkHistory
 
 	GameStateMsg GameStateMessage
 
-	StatusEmojis  AlivenessEmojis
-	SpecialEmojis map[string]Emoji
+	//swapped as a whole when the emojis are synced or removed; use Emojis and setEmojis
+	emojis    *GuildEmojis
+	emojiLock sync.RWMutex
 
 	AmongUsData game.AmongUsData
-	GameRunning bool
+	//whether capture events are applied, i.e. the game isn't ended or paused; accessed atomically
+	gameRunning  int32
+	GameEventLog GameEventLog
+	Leaderboards LeaderboardMessages
+
+	//use setPendingImport and takePendingImport
+	pendingSettingsImport *SettingsImport
+	pendingImportLock     sync.Mutex
+	//only used to tag log entries
+	shardID int
+}
+
+// PGD is the guild's current settings
+func (guild *GuildState) PGD() *PersistentGuildData {
+	guild.pgdLock.RLock()
+	defer guild.pgdLock.RUnlock()
+	return guild.persistentGuildData
+}
+
+// SetPGD replaces the guild's settings, e.g. with ones reloaded from storage
+func (guild *GuildState) SetPGD(pgd *PersistentGuildData) {
+	guild.pgdLock.Lock()
+	guild.persistentGuildData = pgd
+	guild.pgdLock.Unlock()
+}
+
+func (guild *GuildState) IsLinked() bool {
+	return atomic.LoadInt32(&guild.linked) == 1
+}
+
+func (guild *GuildState) SetLinked(linked bool) {
+	atomic.StoreInt32(&guild.linked, boolToInt32(linked))
+}
+
+func (guild *GuildState) IsGameRunning() bool {
+	return atomic.LoadInt32(&guild.gameRunning) == 1
+}
+
+func (guild *GuildState) SetGameRunning(running bool) {
+	atomic.StoreInt32(&guild.gameRunning, boolToInt32(running))
+}
+
+// ToggleGameRunning pauses or resumes the game, and returns whether it's running now
+func (guild *GuildState) ToggleGameRunning() bool {
+	for {
+		old := atomic.LoadInt32(&guild.gameRunning)
+		if atomic.CompareAndSwapInt32(&guild.gameRunning, old, 1-old) {
+			return old == 0
+		}
+	}
+}
+
+func boolToInt32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
 }
 
 type EmojiCollection struct {
@@ -75,7 +136,7 @@ type TrackedMemberAction struct {
 	targetChannel Tracking
 }
 
-func (guild *GuildState) checkCacheAndAddUser(g *discordgo.Guild, s *discordgo.Session, userID string) (game.UserData, bool) {
+func (guild *GuildState) checkCacheAndAddUser(g *discordgo.Guild, s DiscordClient, userID string) (game.UserData, bool) {
 	if g == nil {
 		return game.UserData{}, false
 	}
@@ -87,9 +148,9 @@ func (guild *GuildState) checkCacheAndAddUser(g *discordgo.Guild, s *discordgo.S
 			return user, true
 		}
 	}
-	mem, err := s.GuildMember(guild.PersistentGuildData.GuildID, userID)
+	mem, err := s.GuildMember(guild.PGD().GuildID, userID)
 	if err != nil {
-		log.Println(err)
+		guild.logger().Error(err)
 		return game.UserData{}, false
 	}
 	user := game.MakeUserDataFromDiscordUser(mem.User, mem.Nick)
@@ -133,6 +194,12 @@ func (h *PatchPriority) Pop() interface{} {
 
 //handleTrackedMembers moves/mutes players according to the current game state
 func (guild *GuildState) handleTrackedMembers(sm *SessionManager, delay int, handlePriority HandlePriority) {
+	guild.handleTrackedMembersForPhase(sm, delay, handlePriority, time.Time{})
+}
+
+// handleTrackedMembersForPhase is handleTrackedMembers in response to a phase event received at phaseTime,
+// so the time until each update completes can be observed
+func (guild *GuildState) handleTrackedMembersForPhase(sm *SessionManager, delay int, handlePriority HandlePriority, phaseTime time.Time) {
 
 	g := guild.verifyVoiceStateChanges(sm.GetPrimarySession())
 
@@ -158,10 +225,10 @@ func (guild *GuildState) handleTrackedMembers(sm *SessionManager, delay int, han
 		tracked := guild.Tracking.IsTracked(voiceState.ChannelID)
 		//only actually tracked if we're in a tracked channel AND linked to a player
 		tracked = tracked && userData.IsLinked()
-		shouldMute, shouldDeaf := guild.PersistentGuildData.VoiceRules.GetVoiceState(userData.IsAlive(), tracked, guild.AmongUsData.GetPhase())
+		shouldMute, shouldDeaf := guild.PGD().VoiceRules.GetVoiceState(userData.IsAlive(), tracked, guild.AmongUsData.GetPhase())
 
 		nick := userData.GetPlayerName()
-		if !guild.PersistentGuildData.ApplyNicknames {
+		if !guild.PGD().ApplyNicknames {
 			nick = ""
 		}
 
@@ -182,7 +249,7 @@ func (guild *GuildState) handleTrackedMembers(sm *SessionManager, delay int, han
 					}
 				}
 
-				params := UserPatchParameters{guild.PersistentGuildData.GuildID, userData, shouldDeaf, shouldMute, nick}
+				params := UserPatchParameters{guild.PGD().GuildID, userData, shouldDeaf, shouldMute, nick}
 
 				heap.Push(priorityQueue, PrioritizedPatchParams{
 					priority:    priority,
@@ -192,9 +259,9 @@ func (guild *GuildState) handleTrackedMembers(sm *SessionManager, delay int, han
 
 		} else if userData.IsLinked() {
 			if shouldMute {
-				log.Printf("%s wird nicht stummgeschaltet, da er/sie bereits stummgeschaltet ist\n", userData.GetUserName())
+				guild.logger().Debugf("%s wird nicht stummgeschaltet, da er/sie bereits stummgeschaltet ist", userData.GetUserName())
 			} else {
-				log.Printf("%s nicht stummschalten, da er/sie bereits nicht stummgeschaltet ist\n", userData.GetUserName())
+				guild.logger().Debugf("%s nicht stummschalten, da er/sie bereits nicht stummgeschaltet ist", userData.GetUserName())
 			}
 		}
 	}
@@ -202,7 +269,7 @@ func (guild *GuildState) handleTrackedMembers(sm *SessionManager, delay int, han
 	waitForHigherPriority := false
 
 	if delay > 0 {
-		log.Printf("%d Sekunden schlafen, bevor Änderungen an Benutzern vorgenommen werden\n", delay)
+		guild.logger().Debugf("%d Sekunden schlafen, bevor Änderungen an Benutzern vorgenommen werden", delay)
 		time.Sleep(time.Second * time.Duration(delay))
 	}
 
@@ -211,7 +278,7 @@ func (guild *GuildState) handleTrackedMembers(sm *SessionManager, delay int, han
 
 		if p.priority > 0 {
 			waitForHigherPriority = true
-			log.Printf("Benutzer/in %s hat eine höhere Priorität: %d\n", p.patchParams.Userdata.GetID(), p.priority)
+			guild.logger().Debugf("Benutzer/in %s hat eine höhere Priorität: %d", p.patchParams.Userdata.GetID(), p.priority)
 		} else if waitForHigherPriority {
 			//wait for all the other users to get muted/unmuted completely, first
 			//log.Println("Waiting for high priority user changes first")
@@ -227,22 +294,30 @@ func (guild *GuildState) handleTrackedMembers(sm *SessionManager, delay int, han
 		guild.UserData.UpdateUserData(p.patchParams.Userdata.GetID(), p.patchParams.Userdata)
 
 		//we can issue mutes/deafens from ANY session, not just the primary
-		go muteWorker(sm.GetSessionForRequest(), &wg, p.patchParams)
+		s := sm.GetSessionForRequest()
+		go muteWorker(s, sm.sessionLabel(s), &wg, &guild.UserData, p.patchParams, phaseTime)
 	}
 	wg.Wait()
 
 	return
 }
 
-func muteWorker(s *discordgo.Session, wg *sync.WaitGroup, parameters UserPatchParameters) {
-	guildMemberUpdate(s, parameters)
+func muteWorker(s DiscordClient, session string, wg *sync.WaitGroup, uds *UserDataSet, parameters UserPatchParameters, phaseTime time.Time) {
+	outcome := guildMemberUpdate(s, parameters)
+	if outcome != MemberUpdateFailed {
+		uds.SetApplied(parameters.Userdata.GetID(), parameters.Mute, parameters.Deaf)
+	}
+	memberUpdates.WithLabelValues(outcome, session).Inc()
+	if !phaseTime.IsZero() {
+		muteLatency.Observe(time.Since(phaseTime).Seconds())
+	}
 	wg.Done()
 }
 
-func (guild *GuildState) verifyVoiceStateChanges(s *discordgo.Session) *discordgo.Guild {
-	g, err := s.State.Guild(guild.PersistentGuildData.GuildID)
+func (guild *GuildState) verifyVoiceStateChanges(s DiscordClient) *discordgo.Guild {
+	g, err := s.CachedGuild(guild.PGD().GuildID)
 	if err != nil {
-		log.Println(err)
+		guild.logger().Error(err)
 		return nil
 	}
 
@@ -261,7 +336,7 @@ func (guild *GuildState) verifyVoiceStateChanges(s *discordgo.Session) *discordg
 		tracked := guild.Tracking.IsTracked(voiceState.ChannelID)
 		//only actually tracked if we're in a tracked channel AND linked to a player
 		tracked = tracked && userData.IsLinked()
-		mute, deaf := guild.PersistentGuildData.VoiceRules.GetVoiceState(userData.IsAlive(), tracked, guild.AmongUsData.GetPhase())
+		mute, deaf := guild.PGD().VoiceRules.GetVoiceState(userData.IsAlive(), tracked, guild.AmongUsData.GetPhase())
 		if userData.IsPendingVoiceUpdate() && voiceState.Mute == mute && voiceState.Deaf == deaf {
 			userData.SetPendingVoiceUpdate(false)
 
@@ -278,7 +353,7 @@ func (guild *GuildState) verifyVoiceStateChanges(s *discordgo.Session) *discordg
 //voiceStateChange handles more edge-case behavior for users moving between voice channels, and catches when
 //relevant discord api requests are fully applied successfully. Otherwise, we can issue multiple requests for
 //the same mute/unmute, erroneously
-func (guild *GuildState) voiceStateChange(s *discordgo.Session, m *discordgo.VoiceStateUpdate) {
+func (guild *GuildState) voiceStateChange(s DiscordClient, m *discordgo.VoiceStateUpdate) {
 	g := guild.verifyVoiceStateChanges(s)
 
 	if g == nil {
@@ -296,7 +371,7 @@ func (guild *GuildState) voiceStateChange(s *discordgo.Session, m *discordgo.Voi
 	tracked := guild.Tracking.IsTracked(m.ChannelID)
 	//only actually tracked if we're in a tracked channel AND linked to a player
 	tracked = tracked && userData.IsLinked()
-	mute, deaf := guild.PersistentGuildData.VoiceRules.GetVoiceState(userData.IsAlive(), tracked, guild.AmongUsData.GetPhase())
+	mute, deaf := guild.PGD().VoiceRules.GetVoiceState(userData.IsAlive(), tracked, guild.AmongUsData.GetPhase())
 	//check the userdata is linked here to not accidentally undeafen music bots, for example
 	if userData.IsLinked() && !userData.IsPendingVoiceUpdate() && (mute != m.Mute || deaf != m.Deaf) {
 		userData.SetPendingVoiceUpdate(true)
@@ -304,11 +379,17 @@ func (guild *GuildState) voiceStateChange(s *discordgo.Session, m *discordgo.Voi
 		guild.UserData.UpdateUserData(m.UserID, userData)
 
 		nick := userData.GetPlayerName()
-		if !guild.PersistentGuildData.ApplyNicknames {
+		if !guild.PGD().ApplyNicknames {
 			nick = ""
 		}
 
-		go guildMemberUpdate(s, UserPatchParameters{m.GuildID, userData, deaf, mute, nick})
+		go func(params UserPatchParameters) {
+			outcome := guildMemberUpdate(s, params)
+			if outcome != MemberUpdateFailed {
+				guild.UserData.SetApplied(params.Userdata.GetID(), params.Mute, params.Deaf)
+			}
+			memberUpdates.WithLabelValues(outcome, primarySessionLabel).Inc()
+		}(UserPatchParameters{m.GuildID, userData, deaf, mute, nick})
 
 		//log.Println("Applied deaf/undeaf mute/unmute via voiceStateChange")
 
@@ -321,10 +402,10 @@ func (guild *GuildState) voiceStateChange(s *discordgo.Session, m *discordgo.Voi
 	}
 }
 
-func (bot *Bot) handleReactionGameStartAdd(guild *GuildState, s *discordgo.Session, m *discordgo.MessageReactionAdd) {
-	g, err := s.State.Guild(guild.PersistentGuildData.GuildID)
+func (bot *Bot) handleReactionGameStartAdd(guild *GuildState, s DiscordClient, m *discordgo.MessageReactionAdd) {
+	g, err := s.CachedGuild(guild.PGD().GuildID)
 	if err != nil {
-		log.Println(err)
+		guild.logger().Error(err)
 		return
 	}
 
@@ -333,28 +414,28 @@ func (bot *Bot) handleReactionGameStartAdd(guild *GuildState, s *discordgo.Sessi
 		//verify that the user is reacting to the state/status message
 		if guild.GameStateMsg.IsReactionTo(m) {
 			idMatched := false
-			for color, e := range guild.StatusEmojis[true] {
-				if e.ID == m.Emoji.ID {
+			for color, e := range guild.Emojis().Status[true] {
+				if e.Matches(m.Emoji) {
 					idMatched = true
-					log.Printf("Spieler/in %s reagierte mit Farbe %s", m.UserID, game.GetColorStringForInt(color))
+					guild.logger().Infof("Spieler/in %s reagierte mit Farbe %s", m.UserID, game.GetColorStringForInt(color))
 					//the user doesn't exist in our userdata cache; add them
 
 					_, added := guild.checkCacheAndAddUser(g, s, m.UserID)
 					if !added {
-						log.Println("Keine Benutzer in Discord gefunden mit der userID " + m.UserID)
+						guild.logger().Info("Keine Benutzer in Discord gefunden mit der userID " + m.UserID)
 					}
 
 					playerData := guild.AmongUsData.GetByColor(game.GetColorStringForInt(color))
 					if playerData != nil {
 						guild.UserData.UpdatePlayerData(m.UserID, playerData)
 					} else {
-						log.Println("Ich konnte keine Spielerdaten für diese Farbe finden. Ist die Erfassung verknüpft?")
+						guild.logger().Info("Ich konnte keine Spielerdaten für diese Farbe finden. Ist die Erfassung verknüpft?")
 					}
 
 					//then remove the player's reaction if we matched, or if we didn't
 					err := s.MessageReactionRemove(m.ChannelID, m.MessageID, e.FormatForReaction(), m.UserID)
 					if err != nil {
-						log.Println(err)
+						guild.logger().Error(err)
 					}
 					break
 				}
@@ -362,11 +443,11 @@ func (bot *Bot) handleReactionGameStartAdd(guild *GuildState, s *discordgo.Sessi
 			if !idMatched {
 				//log.Println(m.Emoji.Name)
 				if m.Emoji.Name == "❌" {
-					log.Printf("Spieler entfernen %s", m.UserID)
+					guild.logger().Infof("Spieler entfernen %s", m.UserID)
 					guild.UserData.ClearPlayerData(m.UserID)
 					err := s.MessageReactionRemove(m.ChannelID, m.MessageID, "❌", m.UserID)
 					if err != nil {
-						log.Println(err)
+						guild.logger().Error(err)
 					}
 					idMatched = true
 				}
@@ -381,11 +462,11 @@ func (bot *Bot) handleReactionGameStartAdd(guild *GuildState, s *discordgo.Sessi
 }
 
 func (guild *GuildState) HasAdminPermissions(userID string) bool {
-	if len(guild.PersistentGuildData.AdminUserIDs) == 0 {
+	if len(guild.PGD().AdminUserIDs) == 0 {
 		return false
 	}
 
-	for _, v := range guild.PersistentGuildData.AdminUserIDs {
+	for _, v := range guild.PGD().AdminUserIDs {
 		if v == userID {
 			return true
 		}
@@ -393,17 +474,17 @@ func (guild *GuildState) HasAdminPermissions(userID string) bool {
 	return false
 }
 
-func (guild *GuildState) HasRolePermissions(s *discordgo.Session, userID string) bool {
-	if len(guild.PersistentGuildData.PermissionedRoleIDs) == 0 {
+func (guild *GuildState) HasRolePermissions(s DiscordClient, userID string) bool {
+	if len(guild.PGD().PermissionedRoleIDs) == 0 {
 		return false
 	}
 
-	mem, err := s.GuildMember(guild.PersistentGuildData.GuildID, userID)
+	mem, err := s.GuildMember(guild.PGD().GuildID, userID)
 	if err != nil {
-		log.Println(err)
+		guild.logger().Error(err)
 	}
 	for _, role := range mem.Roles {
-		for _, testRole := range guild.PersistentGuildData.PermissionedRoleIDs {
+		for _, testRole := range guild.PGD().PermissionedRoleIDs {
 			if testRole == role {
 				return true
 			}
@@ -417,7 +498,7 @@ func (guild *GuildState) ToString() string {
 	return fmt.Sprintf("%v", guild)
 }
 
-func (guild *GuildState) clearGameTracking(s *discordgo.Session) {
+func (guild *GuildState) clearGameTracking(s DiscordClient) {
 	//clear the discord user links to underlying player data
 	guild.UserData.ClearAllPlayerData()
 
@@ -427,5 +508,8 @@ func (guild *GuildState) clearGameTracking(s *discordgo.Session) {
 	//reset all the tracking channels
 	guild.Tracking.Reset()
 
+	//whatever game was being recorded is incomplete now
+	guild.GameEventLog.Discard()
+
 	guild.GameStateMsg.Delete(s)
 }
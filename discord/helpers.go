@@ -3,12 +3,11 @@ package discord
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"log"
 	"strings"
 	"time"
 
-	"github.com/bwmarrin/discordgo"
 	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/logging"
 )
 
 // when querying for the member list we need to specify a size
@@ -25,109 +24,80 @@ type UserPatchParameters struct {
 	Nick     string
 }
 
-func guildMemberUpdate(s *discordgo.Session, params UserPatchParameters) {
+// guildMemberUpdate applies the mute/deafen/nickname, and returns how that went (for the metrics)
+func guildMemberUpdate(s DiscordClient, params UserPatchParameters) string {
 	g, err := s.Guild(params.GuildID)
 	if err != nil {
-		log.Println(err)
+		logging.WithGuild(params.GuildID).Error(err)
 	}
 
 	//we can't nickname the owner, and we shouldn't nickname with an empty string...
 	if params.Nick == "" || g.OwnerID == params.Userdata.GetID() {
-		guildMemberUpdateNoNick(s, params)
+		if guildMemberUpdateNoNick(s, params) != nil {
+			return MemberUpdateFailed
+		}
+		return MemberUpdateSuccess
 	} else {
-		newParams := struct {
-			Deaf bool   `json:"deaf"`
-			Mute bool   `json:"mute"`
-			Nick string `json:"nick"`
-		}{params.Deaf, params.Mute, params.Nick}
-		log.Printf("Issuing update request to discord for userID %s with mute=%v deaf=%v nick=%s\n", params.Userdata.GetID(), params.Mute, params.Deaf, params.Nick)
-
-		_, err := s.RequestWithBucketID("PATCH", discordgo.EndpointGuildMember(params.GuildID, params.Userdata.GetID()), newParams, discordgo.EndpointGuildMember(params.GuildID, ""))
+		nick := params.Nick
+		logging.WithGuild(params.GuildID).Debugf("Sende Änderung an Discord für userID %s mit mute=%v deaf=%v nick=%s", params.Userdata.GetID(), params.Mute, params.Deaf, params.Nick)
+
+		err := s.PatchMember(params.GuildID, params.Userdata.GetID(), MemberPatch{Deaf: params.Deaf, Mute: params.Mute, Nick: &nick})
 		if err != nil {
-			log.Println("Fehler beim Ändern des Spitznamens für den Benutzer: Verschiebe den Bot in den Rollen nach oben")
-			log.Println(err)
-			guildMemberUpdateNoNick(s, params)
+			logging.WithGuild(params.GuildID).WithError(err).Warn("Fehler beim Ändern des Spitznamens für den Benutzer: Verschiebe den Bot in den Rollen nach oben")
+			if guildMemberUpdateNoNick(s, params) != nil {
+				return MemberUpdateFailed
+			}
+			return MemberUpdateNoNick
 		}
+		return MemberUpdateSuccess
 	}
 }
 
-func guildMemberUpdateNoNick(s *discordgo.Session, params UserPatchParameters) {
-	log.Printf("Issuing update request to discord for userID %s with mute=%v deaf=%v\n", params.Userdata.GetID(), params.Mute, params.Deaf)
-	newParams := struct {
-		Deaf bool `json:"deaf"`
-		Mute bool `json:"mute"`
-	}{params.Deaf, params.Mute}
-	_, err := s.RequestWithBucketID("PATCH", discordgo.EndpointGuildMember(params.GuildID, params.Userdata.GetID()), newParams, discordgo.EndpointGuildMember(params.GuildID, ""))
+func guildMemberUpdateNoNick(s DiscordClient, params UserPatchParameters) error {
+	logging.WithGuild(params.GuildID).Debugf("Sende Änderung an Discord für userID %s mit mute=%v deaf=%v", params.Userdata.GetID(), params.Mute, params.Deaf)
+	err := s.PatchMember(params.GuildID, params.Userdata.GetID(), MemberPatch{Deaf: params.Deaf, Mute: params.Mute})
 	if err != nil {
-		log.Println(err)
+		logging.WithGuild(params.GuildID).Error(err)
 	}
+	return err
 }
 
-func getPhaseFromString(input string) game.Phase {
-	if len(input) == 0 {
-		return game.UNINITIALIZED
-	}
+// restoreMember sets the server mute and deafen (false lifts them), and sets the nickname back if nick isn't nil
+// (an empty nickname removes it)
+func restoreMember(s DiscordClient, guildID, userID string, mute, deaf bool, nick *string) error {
+	logging.WithGuild(guildID).Debugf("Stelle Benutzer %s wieder her", userID)
+	return s.PatchMember(guildID, userID, MemberPatch{Deaf: deaf, Mute: mute, Nick: nick})
+}
 
-	switch strings.ToLower(input) {
-	case "lobby":
-		fallthrough
-	case "l":
-		return game.LOBBY
-	case "task":
-		fallthrough
-	case "t":
-		fallthrough
-	case "tasks":
-		fallthrough
-	case "game":
-		fallthrough
-	case "g":
-		return game.TASKS
-	case "discuss":
-		fallthrough
-	case "disc":
-		fallthrough
-	case "d":
-		fallthrough
-	case "discussion":
-		return game.DISCUSS
-	default:
-		return game.UNINITIALIZED
-	}
+// inputAliases resolves the colors, phases and regions players type, including the guild's own aliases
+func (guild *GuildState) inputAliases() *game.Aliases {
+	return game.NewAliases(guild.PGD().InputAliases)
 }
 
-// GetRoomAndRegionFromArgs does what it sounds like
-func getRoomAndRegionFromArgs(args []string) (string, string) {
+// GetRoomAndRegionFromArgs does what it sounds like; what isn't given is empty
+func getRoomAndRegionFromArgs(aliases *game.Aliases, args []string) (string, string) {
 	if len(args) == 0 {
-		return "Nicht vorgesehen", "Nicht vorgesehen"
+		return "", ""
 	}
 	room := strings.ToUpper(args[0])
 	if len(args) == 1 {
-		return room, "Nicht vorgesehen"
+		return room, ""
 	}
-	region := strings.ToLower(args[1])
-	switch region {
-	case "na":
-		fallthrough
-	case "us":
-		fallthrough
-	case "usa":
-		fallthrough
-	case "north":
-		region = "North America"
-	case "eu":
-		fallthrough
-	case "europe":
-		region = "Europe"
-	case "as":
-		fallthrough
-	case "asia":
-		region = "Asia"
+	return room, aliases.Region(strings.Join(args[1:], " "))
+}
+
+// meWords let players refer to themselves, e.g. `.au link me red`
+var meWords = map[string]bool{"me": true, "ich": true, "mich": true}
+
+// meToMention turns a me into the author's mention, and leaves everything else as it is
+func meToMention(arg, authorID string) string {
+	if meWords[strings.ToLower(arg)] {
+		return "<@" + authorID + ">"
 	}
-	return room, region
+	return arg
 }
 
-func getMemberFromString(s *discordgo.Session, GuildID string, input string) string {
+func getMemberFromString(s DiscordClient, GuildID string, input string) string {
 	// find which member the user was referencing in their message
 	// TODO increase performance by caching member list for when function called more than once
 	// first check if is mentionned
@@ -145,7 +115,7 @@ func getMemberFromString(s *discordgo.Session, GuildID string, input string) str
 	return ""
 }
 
-func getRoleFromString(s *discordgo.Session, GuildID string, input string) string {
+func getRoleFromString(s DiscordClient, GuildID string, input string) string {
 	// find which role the user was referencing in their message
 	// first check if is mentionned
 	ID, err := extractRoleIDFromMention(input)
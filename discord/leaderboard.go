@@ -0,0 +1,351 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/locale"
+)
+
+// LeaderboardPageSize is how many ranks are shown on one page of the leaderboard
+const LeaderboardPageSize = 10
+
+// LeaderboardMinGames is how many games a player needs before they're ranked by survival rate,
+// otherwise a single lucky game puts someone at 100%
+const LeaderboardMinGames = 3
+
+// LeaderboardTimeout is how long a leaderboard message keeps reacting to page turns
+const LeaderboardTimeout = 10 * time.Minute
+
+const leaderboardPrevEmoji = "⬅️"
+const leaderboardNextEmoji = "➡️"
+
+// SeasonDateFormat is how season dates are entered and stored
+const SeasonDateFormat = "2006-01-02"
+
+// Season is a named range of days that leaderboards can be restricted to
+type Season struct {
+	Name  string `json:"name"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Bounds returns the first and last moment of the season (the end date is inclusive)
+func (season *Season) Bounds() (time.Time, time.Time, error) {
+	start, err := time.Parse(SeasonDateFormat, season.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err := time.Parse(SeasonDateFormat, season.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return start, end.Add(24*time.Hour - time.Second), nil
+}
+
+// Overlaps is true if the seasons share a day, or either one has invalid dates
+func (season *Season) Overlaps(other *Season) bool {
+	start, end, err := season.Bounds()
+	if err != nil {
+		return true
+	}
+	otherStart, otherEnd, err := other.Bounds()
+	if err != nil {
+		return true
+	}
+	return !end.Before(otherStart) && !otherEnd.Before(start)
+}
+
+func (season *Season) Contains(t time.Time) bool {
+	start, end, err := season.Bounds()
+	if err != nil {
+		return false
+	}
+	return !t.Before(start) && !t.After(end)
+}
+
+type LeaderboardMetric int
+
+const (
+	GamesPlayedMetric LeaderboardMetric = iota
+	SurvivalRateMetric
+	TimesExiledMetric
+	MeetingsSurvivedMetric
+)
+
+var LeaderboardMetricStringMapping = map[string]LeaderboardMetric{
+	"games":    GamesPlayedMetric,
+	"played":   GamesPlayedMetric,
+	"g":        GamesPlayedMetric,
+	"survival": SurvivalRateMetric,
+	"survived": SurvivalRateMetric,
+	"s":        SurvivalRateMetric,
+	"exiled":   TimesExiledMetric,
+	"exiles":   TimesExiledMetric,
+	"e":        TimesExiledMetric,
+	"meetings": MeetingsSurvivedMetric,
+	"m":        MeetingsSurvivedMetric,
+}
+
+// LeaderboardMetricKeys are the catalog keys of the metrics' names
+var LeaderboardMetricKeys = map[LeaderboardMetric]string{
+	GamesPlayedMetric:      "leaderboard.metric.games",
+	SurvivalRateMetric:     "leaderboard.metric.survival",
+	TimesExiledMetric:      "leaderboard.metric.exiled",
+	MeetingsSurvivedMetric: "leaderboard.metric.meetings",
+}
+
+func (metric LeaderboardMetric) value(ps *PlayerStats) float64 {
+	switch metric {
+	case SurvivalRateMetric:
+		return ps.SurvivalRate()
+	case TimesExiledMetric:
+		return float64(ps.TimesExiled)
+	case MeetingsSurvivedMetric:
+		return float64(ps.MeetingsSurvived)
+	default:
+		return float64(ps.GamesPlayed)
+	}
+}
+
+func (metric LeaderboardMetric) format(ps *PlayerStats, lang string) string {
+	if metric == SurvivalRateMetric {
+		return locale.T(lang, "leaderboard.survivalValue", ps.SurvivalRate()*100, ps.GamesPlayed)
+	}
+	return fmt.Sprintf("%.0f", metric.value(ps))
+}
+
+// RankPlayers sorts the stats by the metric, best first
+func RankPlayers(stats map[string]*PlayerStats, metric LeaderboardMetric) []*PlayerStats {
+	ranked := make([]*PlayerStats, 0, len(stats))
+	for _, ps := range stats {
+		if metric == SurvivalRateMetric && ps.GamesPlayed < LeaderboardMinGames {
+			continue
+		}
+		ranked = append(ranked, ps)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		vi, vj := metric.value(ranked[i]), metric.value(ranked[j])
+		if vi == vj {
+			if ranked[i].GamesPlayed == ranked[j].GamesPlayed {
+				return ranked[i].UserID < ranked[j].UserID
+			}
+			return ranked[i].GamesPlayed > ranked[j].GamesPlayed
+		}
+		return vi > vj
+	})
+	return ranked
+}
+
+// LeaderboardMessage is a posted leaderboard message that can be paged through with reactions
+type LeaderboardMessage struct {
+	channelID string
+	messageID string
+	title     string
+	lang      string
+	metric    LeaderboardMetric
+	ranked    []*PlayerStats
+	page      int
+	created   time.Time
+}
+
+func (lb *LeaderboardMessage) NumPages() int {
+	pages := (len(lb.ranked) + LeaderboardPageSize - 1) / LeaderboardPageSize
+	if pages == 0 {
+		return 1
+	}
+	return pages
+}
+
+func (lb *LeaderboardMessage) ToEmbed() *discordgo.MessageEmbed {
+	buf := bytes.NewBuffer([]byte{})
+	if len(lb.ranked) == 0 {
+		buf.WriteString(locale.T(lb.lang, "leaderboard.noGames"))
+		if lb.metric == SurvivalRateMetric {
+			buf.WriteString(locale.T(lb.lang, "leaderboard.minGames", LeaderboardMinGames))
+		}
+	}
+	start := lb.page * LeaderboardPageSize
+	for i := start; i < len(lb.ranked) && i < start+LeaderboardPageSize; i++ {
+		buf.WriteString(fmt.Sprintf("**%d.** <@%s> — %s\n", i+1, lb.ranked[i].UserID, lb.metric.format(lb.ranked[i], lb.lang)))
+	}
+	return &discordgo.MessageEmbed{
+		Title:       lb.title,
+		Description: buf.String(),
+		Color:       15844367, //GOLD
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: locale.T(lb.lang, "leaderboard.page", lb.page+1, lb.NumPages()),
+		},
+	}
+}
+
+// LeaderboardMessages are the leaderboard messages of a guild that still respond to page turns, by messageID
+type LeaderboardMessages struct {
+	boards map[string]*LeaderboardMessage
+	lock   sync.Mutex
+}
+
+func MakeLeaderboardMessages() LeaderboardMessages {
+	return LeaderboardMessages{
+		boards: map[string]*LeaderboardMessage{},
+		lock:   sync.Mutex{},
+	}
+}
+
+func (lbs *LeaderboardMessages) Add(lb *LeaderboardMessage) {
+	lbs.lock.Lock()
+	defer lbs.lock.Unlock()
+	for id, v := range lbs.boards {
+		if time.Since(v.created) > LeaderboardTimeout {
+			delete(lbs.boards, id)
+		}
+	}
+	lbs.boards[lb.messageID] = lb
+}
+
+// Turn moves the leaderboard on the message by delta pages, and returns the new embed if the page changed
+func (lbs *LeaderboardMessages) Turn(messageID string, delta int) (*LeaderboardMessage, *discordgo.MessageEmbed) {
+	lbs.lock.Lock()
+	defer lbs.lock.Unlock()
+	lb, ok := lbs.boards[messageID]
+	if !ok {
+		return nil, nil
+	}
+	if time.Since(lb.created) > LeaderboardTimeout {
+		delete(lbs.boards, messageID)
+		return nil, nil
+	}
+	page := lb.page + delta
+	if page < 0 || page >= lb.NumPages() {
+		return lb, nil
+	}
+	lb.page = page
+	return lb, lb.ToEmbed()
+}
+
+// currentSeason is the season running today; seasons can't overlap, so there's at most one
+func (guild *GuildState) currentSeason() *Season {
+	now := time.Now()
+	for i, v := range guild.PGD().Seasons {
+		if v.Contains(now) {
+			return &guild.PGD().Seasons[i]
+		}
+	}
+	return nil
+}
+
+func (guild *GuildState) findSeason(name string) *Season {
+	for i, v := range guild.PGD().Seasons {
+		if strings.ToLower(v.Name) == strings.ToLower(name) {
+			return &guild.PGD().Seasons[i]
+		}
+	}
+	return nil
+}
+
+func filterRecordsBySeason(records []GameRecord, season *Season) []GameRecord {
+	if season == nil {
+		return records
+	}
+	filtered := make([]GameRecord, 0)
+	for _, v := range records {
+		if season.Contains(time.Unix(v.StartTime, 0).UTC()) {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
+
+func (bot *Bot) handleLeaderboardCommand(guild *GuildState, s DiscordClient, m *discordgo.MessageCreate, args []string) {
+	metric := GamesPlayedMetric
+	rest := args[1:]
+	if len(rest) > 0 {
+		if v, ok := LeaderboardMetricStringMapping[rest[0]]; ok {
+			metric = v
+			rest = rest[1:]
+		}
+	}
+
+	//no season means the current one, if there is any; "all" means every game ever recorded
+	season := guild.currentSeason()
+	if len(rest) > 0 {
+		name := strings.Join(rest, " ")
+		if name == "all" || name == "alle" {
+			season = nil
+		} else {
+			season = guild.findSeason(name)
+			if season == nil {
+				s.ChannelMessageSend(m.ChannelID, guild.tr("leaderboard.unknown", name, guild.PGD().CommandPrefix))
+				return
+			}
+		}
+	}
+
+	records, err := bot.loadGameRecords(guild.PGD().GuildID)
+	if err != nil {
+		guild.logger().Error(err)
+		s.ChannelMessageSend(m.ChannelID, guild.tr("history.loadFailed"))
+		return
+	}
+	records = filterRecordsBySeason(records, season)
+
+	title := guild.tr("leaderboard.title", guild.tr(LeaderboardMetricKeys[metric]))
+	if season != nil {
+		title = guild.tr("leaderboard.season", title, season.Name)
+	} else {
+		title = guild.tr("leaderboard.overall", title)
+	}
+	lb := &LeaderboardMessage{
+		channelID: m.ChannelID,
+		title:     title,
+		lang:      guild.PGD().Language,
+		metric:    metric,
+		ranked:    RankPlayers(ComputePlayerStats(records), metric),
+		page:      0,
+		created:   time.Now(),
+	}
+	msg := sendMessageEmbed(s, m.ChannelID, lb.ToEmbed())
+	if msg == nil {
+		return
+	}
+	if lb.NumPages() > 1 {
+		lb.messageID = msg.ID
+		guild.Leaderboards.Add(lb)
+		addReaction(s, msg.ChannelID, msg.ID, leaderboardPrevEmoji)
+		addReaction(s, msg.ChannelID, msg.ID, leaderboardNextEmoji)
+	}
+}
+
+// handleLeaderboardReaction turns the page of a leaderboard, and returns true if the reaction was meant for one
+func (bot *Bot) handleLeaderboardReaction(guild *GuildState, s DiscordClient, m *discordgo.MessageReactionAdd) bool {
+	delta := 0
+	switch m.Emoji.Name {
+	case leaderboardPrevEmoji:
+		delta = -1
+	case leaderboardNextEmoji:
+		delta = 1
+	default:
+		return false
+	}
+	if m.UserID == s.BotUserID() {
+		return false
+	}
+
+	lb, embed := guild.Leaderboards.Turn(m.MessageID, delta)
+	if lb == nil {
+		return false
+	}
+	if embed != nil {
+		editMessageEmbed(s, lb.channelID, lb.messageID, embed)
+	}
+	err := s.MessageReactionRemove(m.ChannelID, m.MessageID, m.Emoji.Name, m.UserID)
+	if err != nil {
+		guild.logger().Error(err)
+	}
+	return true
+}
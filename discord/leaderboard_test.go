@@ -0,0 +1,99 @@
+package discord
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeasonBounds(t *testing.T) {
+	season := Season{Name: "herbst", Start: "2020-09-01", End: "2020-11-30"}
+	start, end, err := season.Bounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !start.Equal(time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Start ist %s", start)
+	}
+	//the end date is inclusive
+	if !end.Equal(time.Date(2020, 11, 30, 23, 59, 59, 0, time.UTC)) {
+		t.Errorf("Ende ist %s", end)
+	}
+	if !season.Contains(time.Date(2020, 11, 30, 22, 0, 0, 0, time.UTC)) || season.Contains(time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("der letzte Tag gehört zur Saison, der nächste nicht")
+	}
+
+	if _, _, err := (&Season{Start: "1.9.2020", End: "2020-11-30"}).Bounds(); err == nil {
+		t.Error("ein Datum in anderem Format muss abgelehnt werden")
+	}
+}
+
+func TestSeasonOverlaps(t *testing.T) {
+	fall := Season{Name: "herbst", Start: "2020-09-01", End: "2020-11-30"}
+	tests := []struct {
+		season   Season
+		overlaps bool
+	}{
+		{Season{Start: "2020-12-01", End: "2021-02-28"}, false},
+		{Season{Start: "2020-11-30", End: "2021-02-28"}, true},
+		{Season{Start: "2020-06-01", End: "2020-08-31"}, false},
+		{Season{Start: "2020-10-01", End: "2020-10-31"}, true},
+		{Season{Start: "2020-01-01", End: "2021-01-01"}, true},
+	}
+	for _, test := range tests {
+		if fall.Overlaps(&test.season) != test.overlaps || test.season.Overlaps(&fall) != test.overlaps {
+			t.Errorf("%s bis %s: Überschneidung %v erwartet", test.season.Start, test.season.End, test.overlaps)
+		}
+	}
+}
+
+func TestFilterRecordsBySeason(t *testing.T) {
+	records := []GameRecord{
+		{StartTime: time.Date(2020, 8, 31, 23, 0, 0, 0, time.UTC).Unix()},
+		{StartTime: time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC).Unix()},
+		{StartTime: time.Date(2020, 11, 30, 23, 0, 0, 0, time.UTC).Unix()},
+		{StartTime: time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC).Unix()},
+	}
+	if all := filterRecordsBySeason(records, nil); len(all) != len(records) {
+		t.Errorf("ohne Saison zählen alle Spiele, bekommen %d", len(all))
+	}
+	filtered := filterRecordsBySeason(records, &Season{Name: "herbst", Start: "2020-09-01", End: "2020-11-30"})
+	if len(filtered) != 2 || filtered[0].StartTime != records[1].StartTime || filtered[1].StartTime != records[2].StartTime {
+		t.Errorf("erwartet die Spiele vom 1.9. und 30.11., bekommen %+v", filtered)
+	}
+}
+
+func TestRankPlayers(t *testing.T) {
+	stats := map[string]*PlayerStats{
+		"1": {UserID: "1", GamesPlayed: 4, GamesSurvived: 2, MeetingsSurvived: 5},
+		"2": {UserID: "2", GamesPlayed: 2, GamesSurvived: 2, MeetingsSurvived: 5},
+		"3": {UserID: "3", GamesPlayed: 4, GamesSurvived: 3, MeetingsSurvived: 1},
+		"4": {UserID: "4", GamesPlayed: 3, GamesSurvived: 2, MeetingsSurvived: 5},
+	}
+	tests := []struct {
+		metric LeaderboardMetric
+		order  []string
+	}{
+		//ties go to whoever played more, then by ID
+		{GamesPlayedMetric, []string{"1", "3", "4", "2"}},
+		//2 played too few games to be ranked
+		{SurvivalRateMetric, []string{"3", "4", "1"}},
+		{MeetingsSurvivedMetric, []string{"1", "4", "2", "3"}},
+	}
+	for _, test := range tests {
+		ranked := RankPlayers(stats, test.metric)
+		order := make([]string, len(ranked))
+		for i, v := range ranked {
+			order[i] = v.UserID
+		}
+		if len(order) != len(test.order) {
+			t.Errorf("Metrik %d: Reihenfolge %v, erwartet %v", test.metric, order, test.order)
+			continue
+		}
+		for i := range order {
+			if order[i] != test.order[i] {
+				t.Errorf("Metrik %d: Reihenfolge %v, erwartet %v", test.metric, order, test.order)
+				break
+			}
+		}
+	}
+}
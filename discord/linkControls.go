@@ -0,0 +1,186 @@
+package discord
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/logging"
+)
+
+// the custom IDs of the status message's components
+const (
+	componentColor    = "link:color"
+	componentUnlink   = "link:unlink"
+	componentSpectate = "link:spectate"
+)
+
+// linkComponents are a select menu with every color, and buttons to unlink and to spectate
+func (guild *GuildState) linkComponents() []MessageComponent {
+	status := guild.Emojis().Status[true]
+	options := make([]SelectOption, len(game.ColorStrings))
+	for color := range options {
+		//a color without an emoji still gets its square
+		componentEmoji := &ComponentEmoji{Name: ColorSquares[color]}
+		if color < len(status) {
+			if emoji := status[color]; emoji.ID != "" {
+				componentEmoji = &ComponentEmoji{ID: emoji.ID, Name: emoji.Name}
+			} else if emoji.Fallback != "" {
+				componentEmoji = &ComponentEmoji{Name: emoji.Fallback}
+			}
+		}
+		options[color] = SelectOption{
+			Label: guild.tr("color." + game.GetColorStringForInt(color)),
+			Value: strconv.Itoa(color),
+			Emoji: componentEmoji,
+		}
+	}
+	return []MessageComponent{
+		{Type: ComponentActionRow, Components: []MessageComponent{
+			{Type: ComponentSelectMenu, CustomID: componentColor, Placeholder: guild.tr("controls.colorPlaceholder"), Options: options},
+		}},
+		{Type: ComponentActionRow, Components: []MessageComponent{
+			{Type: ComponentButton, CustomID: componentUnlink, Label: guild.tr("controls.unlink"), Style: ButtonDanger},
+			{Type: ComponentButton, CustomID: componentSpectate, Label: guild.tr("controls.spectate"), Style: ButtonSecondary},
+		}},
+	}
+}
+
+// addLinkControls gives the status message the components players link with. If Discord refuses them, players
+// link by reactions like before
+func (guild *GuildState) addLinkControls(s DiscordClient) {
+	changed, err := guild.GameStateMsg.AddComponents(s, guild.linkComponents())
+	if err != nil {
+		guild.logger().Warnf("Komponenten konnten nicht an die Statusmeldung gehängt werden, es wird mit Reaktionen verknüpft: %s", err)
+		guild.addLinkReactions(s)
+	}
+	if changed {
+		//the footer tells players how to link
+		guild.GameStateMsg.Edit(s, gameStateResponse(guild))
+	}
+}
+
+// removeLinkControls takes the components and reactions off the status message, when there's nothing to link
+func (guild *GuildState) removeLinkControls(s DiscordClient) {
+	if !guild.GameStateMsg.NoComponents() {
+		if err := guild.GameStateMsg.SetComponents(s, nil); err != nil {
+			guild.logger().Error(err)
+		}
+	}
+	guild.GameStateMsg.RemoveAllReactions(s)
+}
+
+// interactionCreate decodes interactions, which discordgo only passes along as raw events
+func (bot *Bot) interactionCreate() func(s *discordgo.Session, e *discordgo.Event) {
+	return func(_ *discordgo.Session, e *discordgo.Event) {
+		if e.Type != "INTERACTION_CREATE" {
+			return
+		}
+		var interaction Interaction
+		if err := json.Unmarshal(e.RawData, &interaction); err != nil {
+			logging.Errorf("Interaktion konnte nicht gelesen werden: %s", err)
+			return
+		}
+		s := bot.SessionManager.GetPrimarySession()
+		if guild, ok := bot.AllGuilds.Get(interaction.GuildID); ok {
+			bot.handleInteraction(guild, s, &interaction)
+		}
+	}
+}
+
+// handleInteraction links, unlinks or sets the user spectating from the status message's components, and answers
+// only them
+func (bot *Bot) handleInteraction(guild *GuildState, s DiscordClient, interaction *Interaction) {
+	userID := interaction.UserID()
+	if interaction.Type != InteractionMessageComponent || userID == "" || interaction.Message == nil {
+		return
+	}
+	answer, changed := "", false
+	if !guild.GameStateMsg.IsMessage(interaction.Message.ID) {
+		answer = guild.tr("controls.noGame")
+	} else {
+		switch interaction.Data.CustomID {
+		case componentColor:
+			answer, changed = guild.linkFromComponent(s, userID, interaction.Data.Values)
+		case componentUnlink:
+			answer, changed = guild.unlinkFromComponent(userID)
+		case componentSpectate:
+			answer, changed = guild.spectateFromComponent(s, userID)
+		default:
+			return
+		}
+	}
+	//Discord waits only 3 seconds for the answer, so the voice changes come after it
+	if err := s.InteractionRespond(interaction, ephemeralResponse(answer)); err != nil {
+		guild.logger().Error(err)
+	}
+	if changed {
+		guild.handleTrackedMembers(&bot.SessionManager, 0, NoPriority)
+		guild.GameStateMsg.Edit(s, gameStateResponse(guild))
+	}
+}
+
+// addUserIfMissing caches the user, but doesn't replace them if they are, which would forget their link
+func (guild *GuildState) addUserIfMissing(s DiscordClient, userID string) bool {
+	if _, err := guild.UserData.GetUser(userID); err == nil {
+		return true
+	}
+	g, err := s.CachedGuild(guild.PGD().GuildID)
+	if err != nil {
+		guild.logger().Error(err)
+		return false
+	}
+	_, added := guild.checkCacheAndAddUser(g, s, userID)
+	return added
+}
+
+func (guild *GuildState) linkFromComponent(s DiscordClient, userID string, values []string) (string, bool) {
+	if len(values) != 1 {
+		return guild.tr("controls.noColor"), false
+	}
+	color, err := strconv.Atoi(values[0])
+	if err != nil || color < 0 || color >= len(game.ColorStrings) {
+		return guild.tr("controls.noColor"), false
+	}
+	colorName := game.GetColorStringForInt(color)
+	label := guild.tr("color." + colorName)
+	playerData := guild.AmongUsData.GetByColor(colorName)
+	if playerData == nil {
+		return guild.tr("controls.noPlayer", label), false
+	}
+	if !guild.addUserIfMissing(s, userID) {
+		guild.logger().Info("Keine Benutzer in Discord gefunden mit der userID " + userID)
+		return guild.tr("controls.unknownUser"), false
+	}
+	otherID, linked := guild.UserData.LinkUnlessTaken(userID, playerData)
+	if otherID != "" {
+		return guild.tr("controls.taken", label, otherID), false
+	}
+	if !linked {
+		guild.logger().Info("Keine Benutzer in Discord gefunden mit der userID " + userID)
+		return guild.tr("controls.unknownUser"), false
+	}
+	guild.logger().Infof("Spieler/in %s hat über das Menü die Farbe %s gewählt", userID, colorName)
+	return guild.tr("controls.linked", playerData.Name, label), true
+}
+
+func (guild *GuildState) unlinkFromComponent(userID string) (string, bool) {
+	user, err := guild.UserData.GetUser(userID)
+	if err != nil || !user.IsLinked() {
+		return guild.tr("controls.notLinked"), false
+	}
+	guild.logger().Infof("Spieler entfernen %s", userID)
+	guild.UserData.ClearPlayerData(userID)
+	return guild.tr("controls.unlinked"), true
+}
+
+func (guild *GuildState) spectateFromComponent(s DiscordClient, userID string) (string, bool) {
+	if !guild.addUserIfMissing(s, userID) {
+		return guild.tr("controls.unknownUser"), false
+	}
+	user, _ := guild.UserData.GetUser(userID)
+	guild.UserData.SetSpectating(userID)
+	guild.logger().Infof("%s schaut zu", userID)
+	return guild.tr("controls.spectating"), user.IsLinked()
+}
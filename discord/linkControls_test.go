@@ -0,0 +1,176 @@
+package discord
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/game"
+)
+
+// clickComponent hands the bot an interaction like the gateway would, and returns what the user was told
+func clickComponent(t *testing.T, bot *Bot, fake *FakeDiscord, userID, messageID, customID string, values ...string) string {
+	t.Helper()
+	fake.lock.Lock()
+	id := fake.newID()
+	fake.lock.Unlock()
+	raw, _ := json.Marshal(map[string]interface{}{
+		"id":         id,
+		"type":       InteractionMessageComponent,
+		"token":      "token" + id,
+		"guild_id":   flowGuildID,
+		"channel_id": flowTextID,
+		"member":     map[string]interface{}{"user": map[string]string{"id": userID}},
+		"message":    map[string]string{"id": messageID},
+		"data":       map[string]interface{}{"custom_id": customID, "component_type": ComponentSelectMenu, "values": values},
+	})
+	bot.interactionCreate()(nil, &discordgo.Event{Type: "INTERACTION_CREATE", RawData: raw})
+	response := fake.InteractionResponse(id)
+	if response == nil || response.Data == nil || response.Data.Flags != messageFlagEphemeral {
+		t.Fatalf("%s wurde nicht nur dem Nutzer beantwortet: %+v", customID, response)
+	}
+	return response.Data.Content
+}
+
+func TestLinkWithComponents(t *testing.T) {
+	bot, fake := fakeBot(t)
+	guild, _ := bot.AllGuilds.Get(flowGuildID)
+	guild.PGD().Delays = GameDelays{}
+
+	sendCommand(bot, fake, ".au new ABCDEF eu")
+	bot.PushGuildPhaseUpdate(flowGuildID, game.LOBBY)
+	//names that don't match anyone, so nobody is linked automatically
+	bot.PushGuildPlayerUpdate(flowGuildID, game.Player{Action: game.JOINED, Name: "Rotkäppchen", Color: game.Red})
+	bot.PushGuildPlayerUpdate(flowGuildID, game.Player{Action: game.JOINED, Name: "Blaubart", Color: game.Blue})
+	messageID := ""
+	waitFor(t, "das Menü an der Statusmeldung", func() bool {
+		for _, msg := range fake.Messages(flowTextID) {
+			if len(fake.Components(msg.ID)) == 2 {
+				messageID = msg.ID
+				return guild.AmongUsData.GetByName("Blaubart") != nil
+			}
+		}
+		return false
+	})
+	if reactions := fake.Reactions(messageID); len(reactions) > 0 {
+		t.Errorf("mit Menü braucht es keine Reaktionen: %v", reactions)
+	}
+
+	red := guild.tr("color.red")
+	if text := clickComponent(t, bot, fake, flowAliceID, messageID, componentColor, strconv.Itoa(game.Red)); text != guild.tr("controls.linked", "Rotkäppchen", red) {
+		t.Errorf("alice sollte verknüpft sein: %q", text)
+	}
+	if text := clickComponent(t, bot, fake, flowBobID, messageID, componentColor, strconv.Itoa(game.Red)); text != guild.tr("controls.taken", red, flowAliceID) {
+		t.Errorf("rot ist schon vergeben: %q", text)
+	}
+	if user, _ := guild.UserData.GetUser(flowAliceID); !user.IsLinked() || user.GetPlayerName() != "Rotkäppchen" {
+		t.Error("alice sollte mit Rotkäppchen verknüpft bleiben")
+	}
+
+	if text := clickComponent(t, bot, fake, flowBobID, messageID, componentSpectate); text != guild.tr("controls.spectating") {
+		t.Errorf("bob sollte zuschauen: %q", text)
+	}
+	if guild.UserData.AttemptPairingByMatchingNames("bob", guild.AmongUsData.GetByName("Blaubart")) {
+		t.Error("wer zuschaut, wird nicht automatisch verknüpft")
+	}
+
+	if text := clickComponent(t, bot, fake, flowAliceID, messageID, componentUnlink); text != guild.tr("controls.unlinked") {
+		t.Errorf("alice sollte nicht mehr verknüpft sein: %q", text)
+	}
+	if text := clickComponent(t, bot, fake, flowAliceID, messageID, componentUnlink); text != guild.tr("controls.notLinked") {
+		t.Errorf("alice war schon nicht mehr verknüpft: %q", text)
+	}
+	if text := clickComponent(t, bot, fake, flowAliceID, "gibt es nicht", componentUnlink); text != guild.tr("controls.noGame") {
+		t.Errorf("eine alte Meldung gehört zu keinem Spiel: %q", text)
+	}
+
+	bot.PushGuildPhaseUpdate(flowGuildID, game.MENU)
+	waitFor(t, "das Menü ist weg", func() bool { return len(fake.Components(messageID)) == 0 })
+}
+
+func TestLinkUnlessTakenOnlyLinksOne(t *testing.T) {
+	uds := MakeUserDataSet()
+	for i := 0; i < 10; i++ {
+		uds.AddFullUser(game.MakeUserDataFromDiscordUser(&discordgo.User{ID: strconv.Itoa(i)}, ""))
+	}
+	red := &game.PlayerData{Name: "Rot", Color: game.Red, IsAlive: true}
+
+	linked := make(chan string, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(userID string) {
+			defer wg.Done()
+			if _, ok := uds.LinkUnlessTaken(userID, red); ok {
+				linked <- userID
+			}
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+	close(linked)
+	if len(linked) != 1 {
+		t.Fatalf("genau eine/r darf Rot bekommen, es waren %d", len(linked))
+	}
+	winner := <-linked
+	//linking again to one's own color is fine, for anybody else it's taken
+	if _, ok := uds.LinkUnlessTaken(winner, red); !ok {
+		t.Error("die eigene Farbe darf noch einmal gewählt werden")
+	}
+	other := "0"
+	if winner == other {
+		other = "1"
+	}
+	if takenBy, ok := uds.LinkUnlessTaken(other, red); ok || takenBy != winner {
+		t.Errorf("Rot gehört %s, bekommen %q", winner, takenBy)
+	}
+	if takenBy, ok := uds.LinkUnlessTaken("99", &game.PlayerData{Color: game.Blue}); ok || takenBy != "" {
+		t.Error("unbekannte Benutzer können nicht verknüpft werden")
+	}
+}
+
+//run with -race: the capture listener and commands add the controls while edits render the footer
+func TestAddLinkControlsWhileRendering(t *testing.T) {
+	bot, fake := fakeBot(t)
+	guild, _ := bot.AllGuilds.Get(flowGuildID)
+	sendCommand(bot, fake, ".au new ABCDEF eu")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			guild.addLinkControls(fake)
+		}()
+		go func() {
+			defer wg.Done()
+			gameStateResponse(guild)
+		}()
+	}
+	wg.Wait()
+	if guild.GameStateMsg.NoComponents() || len(fake.Components(guild.GameStateMsg.message.ID)) == 0 {
+		t.Error("die Statusmeldung sollte die Komponenten haben")
+	}
+}
+
+func TestLinkComponentsWithoutEmojis(t *testing.T) {
+	bot, fake := fakeBot(t)
+	guild, _ := bot.AllGuilds.Get(flowGuildID)
+	guild.setEmojis(&GuildEmojis{TextStatus: true})
+
+	options := guild.linkComponents()[0].Components[0].Options
+	if len(options) != len(game.ColorStrings) {
+		t.Fatalf("das Menü sollte alle %d Farben haben, nicht %d", len(game.ColorStrings), len(options))
+	}
+	if options[game.Red].Emoji.Name != ColorSquares[game.Red] {
+		t.Errorf("ohne Emoji sollte rot sein Quadrat zeigen: %+v", options[game.Red].Emoji)
+	}
+	lime := guild.tr("color." + game.GetColorStringForInt(game.Lime))
+	if text, _ := guild.linkFromComponent(fake, flowAliceID, []string{strconv.Itoa(game.Lime)}); text != guild.tr("controls.noPlayer", lime) {
+		t.Errorf("limette ist eine Farbe, es spielt nur niemand damit: %q", text)
+	}
+	if text, _ := guild.linkFromComponent(fake, flowAliceID, []string{strconv.Itoa(len(game.ColorStrings))}); text != guild.tr("controls.noColor") {
+		t.Errorf("die Farbe gibt es nicht: %q", text)
+	}
+}
@@ -0,0 +1,295 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/locale"
+)
+
+// AutoLinkMinConfidence is the score a remembered alias needs before a player is linked automatically.
+// Every game played under a name counts 1, and a matching color adds AutoLinkColorBonus on top
+const AutoLinkMinConfidence = 2
+const AutoLinkColorBonus = 1
+
+// MaxAliasesPerUser caps how many in-game identities we remember for a single Discord user
+const MaxAliasesPerUser = 10
+
+// LinkAlias is an in-game name/color that a Discord user has been linked to in a previous game
+type LinkAlias struct {
+	Name     string `json:"name"`
+	Color    int    `json:"color"`
+	Count    int    `json:"count"`
+	LastSeen int64  `json:"lastSeen"`
+}
+
+// LinkHistory remembers which Discord users played under which in-game names and colors
+type LinkHistory struct {
+	//indexed by discord userID
+	Aliases map[string][]LinkAlias `json:"aliases"`
+
+	lock sync.RWMutex
+}
+
+func MakeLinkHistory() LinkHistory {
+	return LinkHistory{
+		Aliases: map[string][]LinkAlias{},
+		lock:    sync.RWMutex{},
+	}
+}
+
+func normalizeAliasName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), " ", "")
+}
+
+// LoadData replaces the contents of the history with the map fetched from storage
+func (lh *LinkHistory) LoadData(data map[string]interface{}) error {
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	temp := struct {
+		Aliases map[string][]LinkAlias `json:"aliases"`
+	}{}
+	err = json.Unmarshal(jsonBytes, &temp)
+	if err != nil {
+		return err
+	}
+	if temp.Aliases == nil {
+		temp.Aliases = map[string][]LinkAlias{}
+	}
+
+	lh.lock.Lock()
+	lh.Aliases = temp.Aliases
+	lh.lock.Unlock()
+	return nil
+}
+
+func (lh *LinkHistory) ToData() (map[string]interface{}, error) {
+	var data map[string]interface{}
+
+	lh.lock.RLock()
+	jsonBytes, err := json.Marshal(lh)
+	lh.lock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(jsonBytes, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Record notes that userID played under the provided name and color
+func (lh *LinkHistory) Record(userID, name string, color int) {
+	lh.lock.Lock()
+	defer lh.lock.Unlock()
+
+	now := time.Now().Unix()
+	aliases := lh.Aliases[userID]
+	for i, v := range aliases {
+		if normalizeAliasName(v.Name) == normalizeAliasName(name) {
+			aliases[i].Name = name
+			aliases[i].Color = color
+			aliases[i].Count++
+			aliases[i].LastSeen = now
+			lh.Aliases[userID] = aliases
+			return
+		}
+	}
+	aliases = append(aliases, LinkAlias{
+		Name:     name,
+		Color:    color,
+		Count:    1,
+		LastSeen: now,
+	})
+	if len(aliases) > MaxAliasesPerUser {
+		//forget whichever alias was used the longest time ago
+		sort.Slice(aliases, func(i, j int) bool {
+			return aliases[i].LastSeen > aliases[j].LastSeen
+		})
+		aliases = aliases[:MaxAliasesPerUser]
+	}
+	lh.Aliases[userID] = aliases
+}
+
+// Remove forgets a single alias for a user, and returns if it existed
+func (lh *LinkHistory) Remove(userID, name string) bool {
+	lh.lock.Lock()
+	defer lh.lock.Unlock()
+
+	aliases := lh.Aliases[userID]
+	for i, v := range aliases {
+		if normalizeAliasName(v.Name) == normalizeAliasName(name) {
+			lh.Aliases[userID] = append(aliases[:i], aliases[i+1:]...)
+			if len(lh.Aliases[userID]) == 0 {
+				delete(lh.Aliases, userID)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Clear forgets all aliases for a user, and returns how many were removed
+func (lh *LinkHistory) Clear(userID string) int {
+	lh.lock.Lock()
+	defer lh.lock.Unlock()
+
+	num := len(lh.Aliases[userID])
+	delete(lh.Aliases, userID)
+	return num
+}
+
+// GetAliases returns a copy of the user's aliases, most used first
+func (lh *LinkHistory) GetAliases(userID string) []LinkAlias {
+	lh.lock.RLock()
+	aliases := make([]LinkAlias, len(lh.Aliases[userID]))
+	copy(aliases, lh.Aliases[userID])
+	lh.lock.RUnlock()
+
+	sort.Slice(aliases, func(i, j int) bool {
+		if aliases[i].Count == aliases[j].Count {
+			return aliases[i].LastSeen > aliases[j].LastSeen
+		}
+		return aliases[i].Count > aliases[j].Count
+	})
+	return aliases
+}
+
+// FindCandidate returns the one user out of candidateIDs that has most confidently played as this name/color before.
+// A candidate is only returned if they reach AutoLinkMinConfidence AND nobody else scores the same; if we're unsure,
+// we'd rather have the player react than link the wrong person
+func (lh *LinkHistory) FindCandidate(name string, color int, candidateIDs []string) (string, bool) {
+	lh.lock.RLock()
+	defer lh.lock.RUnlock()
+
+	name = normalizeAliasName(name)
+	bestID := ""
+	bestScore := 0
+	tied := false
+
+	for _, userID := range candidateIDs {
+		for _, alias := range lh.Aliases[userID] {
+			if normalizeAliasName(alias.Name) != name {
+				continue
+			}
+			score := alias.Count
+			if alias.Color == color {
+				score += AutoLinkColorBonus
+			}
+			if score > bestScore {
+				bestID = userID
+				bestScore = score
+				tied = false
+			} else if score == bestScore {
+				tied = true
+			}
+			break
+		}
+	}
+	if bestID == "" || tied || bestScore < AutoLinkMinConfidence {
+		return "", false
+	}
+	return bestID, true
+}
+
+// attemptAutoLinkFromHistory links the player to an unlinked user who has played under the same name before
+func (guild *GuildState) attemptAutoLinkFromHistory(name string, data *game.PlayerData) bool {
+	if data == nil || guild.UserData.IsPlayerNameLinked(name) {
+		return false
+	}
+	//spectators aren't candidates, and AutoLink skips them in case they started spectating since
+	userID, ok := guild.LinkHistory.FindCandidate(name, data.Color, guild.UserData.GetUnlinkedUserIDs())
+	if !ok {
+		return false
+	}
+	return guild.UserData.AutoLink(userID, data)
+}
+
+// recordLinkHistory remembers everyone who is currently linked, and writes the history to storage
+func (bot *Bot) recordLinkHistory(guild *GuildState) {
+	linked := guild.UserData.GetLinkedUsers()
+	if len(linked) == 0 {
+		return
+	}
+	for _, user := range linked {
+		guild.LinkHistory.Record(user.GetID(), user.GetPlayerName(), user.GetColor())
+	}
+	bot.writeLinkHistory(guild)
+}
+
+func (bot *Bot) writeLinkHistory(guild *GuildState) {
+	data, err := guild.LinkHistory.ToData()
+	if err != nil {
+		guild.logger().Error(err)
+		return
+	}
+	err = bot.StorageInterface.WriteLinkHistory(guild.PGD().GuildID, data)
+	if err != nil {
+		guild.logger().Errorf("Fehler beim Schreiben des Verknüpfungsverlaufs: %s", err)
+	}
+}
+
+func (bot *Bot) handleAliasesCommand(guild *GuildState, s DiscordClient, m *discordgo.MessageCreate, args []string) {
+	userID := m.Author.ID
+	action := ""
+	rest := args[1:]
+
+	if len(rest) > 0 && (rest[0] == "remove" || rest[0] == "clear") {
+		action = rest[0]
+		rest = rest[1:]
+	}
+	//an optional mention at the end means the command is about somebody else
+	if len(rest) > 0 {
+		if id, err := extractUserIDFromMention(rest[len(rest)-1]); err == nil {
+			userID = id
+			rest = rest[:len(rest)-1]
+		}
+	}
+
+	switch action {
+	case "remove":
+		if len(rest) == 0 {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("aliases.removeWhich", guild.PGD().CommandPrefix))
+			return
+		}
+		name := strings.Join(rest, " ")
+		if guild.LinkHistory.Remove(userID, name) {
+			bot.writeLinkHistory(guild)
+			s.ChannelMessageSend(m.ChannelID, guild.tr("aliases.removed", name))
+		} else {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("aliases.unknown", name))
+		}
+	case "clear":
+		num := guild.LinkHistory.Clear(userID)
+		bot.writeLinkHistory(guild)
+		s.ChannelMessageSend(m.ChannelID, guild.tr("aliases.cleared", num))
+	default:
+		s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+			Content:         aliasesResponse(userID, guild.LinkHistory.GetAliases(userID), guild.PGD().CommandPrefix, guild.PGD().Language),
+			AllowedMentions: &discordgo.MessageAllowedMentions{Users: nil},
+		})
+	}
+}
+
+func aliasesResponse(userID string, aliases []LinkAlias, commandPrefix, lang string) string {
+	if len(aliases) == 0 {
+		return locale.T(lang, "aliases.none", userID)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	buf.WriteString(locale.T(lang, "aliases.title", userID) + "\n")
+	for _, v := range aliases {
+		lastSeen := time.Unix(v.LastSeen, 0).Format(locale.T(lang, "aliases.dateFormat"))
+		buf.WriteString(locale.T(lang, "aliases.item", v.Name, game.GetColorStringForInt(v.Color), v.Count, lastSeen) + "\n")
+	}
+	buf.WriteString(locale.T(lang, "aliases.hint", commandPrefix))
+	return buf.String()
+}
@@ -0,0 +1,114 @@
+package discord
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/game"
+)
+
+func TestLinkHistoryRecord(t *testing.T) {
+	lh := MakeLinkHistory()
+	lh.Record("1", "Rot Käppchen", game.Red)
+	lh.Record("1", "rotkäppchen", game.Blue)
+	lh.Record("1", "Blaubart", game.Blue)
+
+	aliases := lh.GetAliases("1")
+	if len(aliases) != 2 {
+		t.Fatalf("erwartet 2 Aliase, bekommen %+v", aliases)
+	}
+	//the same name in other spelling counts for the alias, which keeps the latest spelling and color
+	if aliases[0].Name != "rotkäppchen" || aliases[0].Color != game.Blue || aliases[0].Count != 2 {
+		t.Errorf("unerwarteter Alias: %+v", aliases[0])
+	}
+
+	for i := 0; i < MaxAliasesPerUser; i++ {
+		lh.Record("2", fmt.Sprintf("Spieler%d", i), game.Red)
+		//LastSeen is in seconds, so make the order visible
+		lh.Aliases["2"][i].LastSeen -= int64(MaxAliasesPerUser - i)
+	}
+	lh.Record("2", "Neu", game.Red)
+	aliases = lh.GetAliases("2")
+	if len(aliases) != MaxAliasesPerUser {
+		t.Fatalf("erwartet %d Aliase, bekommen %d", MaxAliasesPerUser, len(aliases))
+	}
+	for _, v := range aliases {
+		if v.Name == "Spieler0" {
+			t.Error("der am längsten nicht benutzte Alias sollte vergessen sein")
+		}
+	}
+}
+
+func TestLinkHistoryFindCandidate(t *testing.T) {
+	lh := MakeLinkHistory()
+	//once as Rot in red: 1 game + the color bonus
+	lh.Record("1", "Rot", game.Red)
+	//once as Blau, but now in another color: 1 game only
+	lh.Record("2", "Blau", game.Blue)
+	//twice as Grün in any color
+	lh.Record("3", "Grün", game.Red)
+	lh.Record("3", "Grün", game.Red)
+	lh.Record("4", "Grün", game.Red)
+	lh.Record("4", "Grün", game.Red)
+
+	tests := []struct {
+		name       string
+		color      int
+		candidates []string
+		userID     string
+	}{
+		{"ROT", game.Red, []string{"1", "2"}, "1"},
+		{"Blau", game.Green, []string{"1", "2"}, ""},
+		{"Blau", game.Blue, []string{"1", "2"}, "2"},
+		{"Rot", game.Red, []string{"2"}, ""},
+		{"Grün", game.Green, []string{"3"}, "3"},
+		{"Grün", game.Green, []string{"3", "4"}, ""},
+	}
+	for _, test := range tests {
+		userID, ok := lh.FindCandidate(test.name, test.color, test.candidates)
+		if userID != test.userID || ok != (test.userID != "") {
+			t.Errorf("%s in %d von %v: bekommen %q, erwartet %q", test.name, test.color, test.candidates, userID, test.userID)
+		}
+	}
+}
+
+func TestIsPlayerNameLinkedNormalizes(t *testing.T) {
+	uds := MakeUserDataSet()
+	uds.AddFullUser(game.MakeUserDataFromDiscordUser(&discordgo.User{ID: "1", Username: "alice"}, ""))
+	uds.UpdatePlayerData("1", &game.PlayerData{Name: "Rot Käppchen", Color: game.Red, IsAlive: true})
+
+	for _, name := range []string{"Rot Käppchen", "rotkäppchen", "ROT KÄPPCHEN"} {
+		if !uds.IsPlayerNameLinked(name) {
+			t.Errorf("%q ist schon verknüpft", name)
+		}
+	}
+	if uds.IsPlayerNameLinked("Rot") {
+		t.Error("Rot ist nicht verknüpft")
+	}
+}
+
+func TestAutoLinkFromHistorySkipsSpectators(t *testing.T) {
+	guild := &GuildState{persistentGuildData: PGDDefault("1"), UserData: MakeUserDataSet(), LinkHistory: MakeLinkHistory()}
+	guild.UserData.AddFullUser(game.MakeUserDataFromDiscordUser(&discordgo.User{ID: "1", Username: "alice"}, ""))
+	guild.LinkHistory.Record("1", "Rot", game.Red)
+	red := &game.PlayerData{Name: "Rot", Color: game.Red, IsAlive: true}
+
+	guild.UserData.SetSpectating("1")
+	if guild.attemptAutoLinkFromHistory("Rot", red) {
+		t.Error("wer zuschaut, wird nicht automatisch verknüpft")
+	}
+	if guild.UserData.AutoLink("1", red) {
+		t.Error("AutoLink darf Zuschauer nicht verknüpfen")
+	}
+	if user, _ := guild.UserData.GetUser("1"); user.IsLinked() || !user.IsSpectating() {
+		t.Error("alice sollte weiter zuschauen")
+	}
+
+	//once they link themselves again, the history may link them
+	guild.UserData.UpdatePlayerData("1", &game.PlayerData{Name: "Blau", Color: game.Blue})
+	guild.UserData.ClearPlayerData("1")
+	if !guild.attemptAutoLinkFromHistory("Rot", red) {
+		t.Error("alice schaut nicht mehr zu und sollte verknüpft werden")
+	}
+}
@@ -0,0 +1,65 @@
+package discord
+
+import (
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/logging"
+)
+
+func (bot *Bot) logger() *logging.Logger {
+	return logging.WithFields(logging.Fields{logging.FieldShard: bot.shardID})
+}
+
+func (bot *Bot) guildLogger(guildID string) *logging.Logger {
+	return logging.WithFields(logging.Fields{
+		logging.FieldShard: bot.shardID,
+		logging.FieldGuild: guildID,
+	})
+}
+
+// socketLogger tags the entries with the socket, and the guild it's linked to if there is one
+func (bot *Bot) socketLogger(connID string) *logging.Logger {
+	fields := logging.Fields{
+		logging.FieldShard:  bot.shardID,
+		logging.FieldSocket: connID,
+	}
+	if guildID, ok := bot.AllConns.GuildID(connID); ok {
+		fields[logging.FieldGuild] = guildID
+	}
+	return logging.WithFields(fields)
+}
+
+func (guild *GuildState) logger() *logging.Logger {
+	return logging.WithFields(logging.Fields{
+		logging.FieldShard: guild.shardID,
+		logging.FieldGuild: guild.PGD().GuildID,
+		logging.FieldPhase: phaseLabel(guild.AmongUsData.GetPhase()),
+	})
+}
+
+// handleDebugCommand switches the guild's debug logging on or off; without an argument it just flips it
+func (guild *GuildState) handleDebugCommand(s DiscordClient, m *discordgo.MessageCreate, args []string) {
+	guildID := guild.PGD().GuildID
+	enabled := !logging.IsGuildDebug(guildID)
+	if len(args) > 1 {
+		switch strings.ToLower(args[1]) {
+		case "on", "an", "true":
+			enabled = true
+		case "off", "aus", "false":
+			enabled = false
+		default:
+			s.ChannelMessageSend(m.ChannelID, guild.tr("debug.unknown", args[1], guild.PGD().CommandPrefix))
+			return
+		}
+	}
+
+	logging.SetGuildDebug(guildID, enabled)
+	if enabled {
+		guild.logger().Infof("Debug-Logs eingeschaltet von %s", m.Author.ID)
+		s.ChannelMessageSend(m.ChannelID, guild.tr("debug.enabled"))
+	} else {
+		guild.logger().Infof("Debug-Logs ausgeschaltet von %s", m.Author.ID)
+		s.ChannelMessageSend(m.ChannelID, guild.tr("debug.disabled"))
+	}
+}
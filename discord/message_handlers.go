@@ -2,11 +2,11 @@ package discord
 
 import (
 	"fmt"
-	"log"
 	"regexp"
 	"strings"
 
 	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/logging"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -16,7 +16,13 @@ const download64URL = "https://github.com/denverquane/amonguscapture/releases/la
 const dotNet32Url = "https://dotnet.microsoft.com/download/dotnet-core/thank-you/sdk-3.1.402-windows-x86-installer"
 const dotNet64Url = "https://dotnet.microsoft.com/download/dotnet-core/thank-you/sdk-3.1.402-windows-x64-installer"
 
-func (bot *Bot) handleGameEndMessage(guild *GuildState, s *discordgo.Session) {
+func (bot *Bot) handleGameEndMessage(guild *GuildState, s DiscordClient) {
+	if phase := guild.AmongUsData.GetPhase(); phase == game.TASKS || phase == game.DISCUSS {
+		bot.recordLinkHistory(guild)
+		//has to happen before the tracking is cleared, while we still know who was who
+		bot.finishGame(guild, s)
+	}
+
 	guild.AmongUsData.SetAllAlive()
 	guild.AmongUsData.SetPhase(game.LOBBY)
 
@@ -26,13 +32,13 @@ func (bot *Bot) handleGameEndMessage(guild *GuildState, s *discordgo.Session) {
 	//clear the tracking and make sure all users are unlinked
 	guild.clearGameTracking(s)
 
-	guild.GameRunning = false
+	guild.SetGameRunning(false)
 
 	// clear any existing game state message
 	guild.AmongUsData.SetRoomRegion("", "")
 }
 
-func (bot *Bot) handleNewGameMessage(guild *GuildState, s *discordgo.Session, m *discordgo.MessageCreate, g *discordgo.Guild, room, region string) {
+func (bot *Bot) handleNewGameMessage(guild *GuildState, s DiscordClient, m *discordgo.MessageCreate, g *discordgo.Guild, room, region string) {
 	initialTracking := make([]TrackingChannel, 0)
 
 	//TODO need to send a message to the capture re-questing all the player/game states. Otherwise,
@@ -41,15 +47,12 @@ func (bot *Bot) handleNewGameMessage(guild *GuildState, s *discordgo.Session, m
 
 	//TODO don't always recreate if we're already connected...
 
-	connectCode := generateConnectCode(guild.PersistentGuildData.GuildID)
-	log.Println(connectCode)
-	bot.LinkCodeLock.Lock()
-	bot.LinkCodes[GameOrLobbyCode{
-		gameCode:    room,
-		connectCode: connectCode,
-	}] = guild.PersistentGuildData.GuildID
-
-	bot.LinkCodeLock.Unlock()
+	connectCode := generateConnectCode(guild.PGD().GuildID)
+	guild.logger().Debugf("Verbindungscode %s", connectCode)
+	err := bot.Coordinator.SetLinkCodes(guild.PGD().GuildID, connectCode, room)
+	if err != nil {
+		guild.logger().Errorf("Verbindungscode konnte nicht gespeichert werden: %s", err)
+	}
 
 	var hyperlink string
 	var minimalUrl string
@@ -82,23 +85,22 @@ func (bot *Bot) handleNewGameMessage(guild *GuildState, s *discordgo.Session, m
 		hyperlink = fmt.Sprintf("aucapture://%s%s/%s%s", host, port, connectCode, insecure)
 		minimalUrl = fmt.Sprintf("%s%s%s", protocol, host, port)
 	} else {
-		hyperlink = "Invalid Server URL (missing `http://`? Or do you have a trailing `/`?)"
-		minimalUrl = "Invalid Server URL"
+		hyperlink = guild.tr("newGame.invalidURL")
+		minimalUrl = guild.tr("newGame.invalidURLShort")
 	}
 
 	var embed = discordgo.MessageEmbed{
-		URL:   "",
-		Type:  "",
-		Title: "Du hast gerade ein Spiel gestartet!",
-		Description: fmt.Sprintf("Klicke auf den folgenden Link, um die Aufnahme zu verknüpfen: \n <%s>\n\n"+
-			"Du hast die Aufnahme nicht installiert? [Lade es hier herunter](%s)\nWenn du .NET Core nicht installiert hast, kannst du dies [hier](%s) erhalten.\n\nAufnahme manuell verknüpfen:", hyperlink, download32URL, download64URL, dotNet32Url, dotNet64Url),
-		Timestamp: "",
-		Color:     3066993, //GREEN
-		Image:     nil,
-		Thumbnail: nil,
-		Video:     nil,
-		Provider:  nil,
-		Author:    nil,
+		URL:         "",
+		Type:        "",
+		Title:       guild.tr("newGame.title"),
+		Description: guild.tr("newGame.description", hyperlink, download32URL, download64URL, dotNet32Url, dotNet64Url),
+		Timestamp:   "",
+		Color:       3066993, //GREEN
+		Image:       nil,
+		Thumbnail:   nil,
+		Video:       nil,
+		Provider:    nil,
+		Author:      nil,
 		Fields: []*discordgo.MessageEmbedField{
 			&discordgo.MessageEmbedField{
 				Name:   "URL",
@@ -117,18 +119,18 @@ func (bot *Bot) handleNewGameMessage(guild *GuildState, s *discordgo.Session, m
 
 	channels, err := s.GuildChannels(m.GuildID)
 	if err != nil {
-		log.Println(err)
+		guild.logger().Error(err)
 	}
 
 	for _, channel := range channels {
 		if channel.Type == discordgo.ChannelTypeGuildVoice {
-			if channel.ID == guild.PersistentGuildData.DefaultTrackedChannel || strings.ToLower(channel.Name) == strings.ToLower(guild.PersistentGuildData.DefaultTrackedChannel) {
+			if channel.ID == guild.PGD().DefaultTrackedChannel || strings.ToLower(channel.Name) == strings.ToLower(guild.PGD().DefaultTrackedChannel) {
 				initialTracking = append(initialTracking, TrackingChannel{
 					channelID:   channel.ID,
 					channelName: channel.Name,
 					forGhosts:   false,
 				})
-				log.Printf("Der in der Konfiguration angegebene anfängliche Standardkanal wurde gefunden: ID %s, Name %s\n", channel.ID, channel.Name)
+				guild.logger().Infof("Der in der Konfiguration angegebene anfängliche Standardkanal wurde gefunden: ID %s, Name %s", channel.ID, channel.Name)
 			}
 		}
 		for _, v := range g.VoiceStates {
@@ -143,7 +145,7 @@ func (bot *Bot) handleNewGameMessage(guild *GuildState, s *discordgo.Session, m
 							channelName: channel.Name,
 							forGhosts:   false,
 						})
-						log.Printf("Benutzer, der neu eingegeben hat, befindet sich im Sprachkanal \"%s\". Verwende diesen für die Erfassung", channel.Name)
+						guild.logger().Infof("Benutzer, der neu eingegeben hat, befindet sich im Sprachkanal \"%s\". Verwende diesen für die Erfassung", channel.Name)
 					}
 				}
 
@@ -155,12 +157,12 @@ func (bot *Bot) handleNewGameMessage(guild *GuildState, s *discordgo.Session, m
 	guild.handleGameStartMessage(s, m, room, region, initialTracking, g)
 }
 
-func (guild *GuildState) handleGameStartMessage(s *discordgo.Session, m *discordgo.MessageCreate, room string, region string, channels []TrackingChannel, g *discordgo.Guild) {
+func (guild *GuildState) handleGameStartMessage(s DiscordClient, m *discordgo.MessageCreate, room string, region string, channels []TrackingChannel, g *discordgo.Guild) {
 	guild.AmongUsData.SetRoomRegion(room, region)
 
 	guild.clearGameTracking(s)
 
-	guild.GameRunning = true
+	guild.SetGameRunning(true)
 
 	for _, channel := range channels {
 		if channel.channelName != "" {
@@ -173,81 +175,78 @@ func (guild *GuildState) handleGameStartMessage(s *discordgo.Session, m *discord
 		}
 	}
 
-	guild.GameStateMsg.CreateMessage(s, gameStateResponse(guild), m.ChannelID, m.Author.ID)
+	guild.createStatusMessage(s, m.ChannelID, m.Author.ID)
 
-	log.Println("Selbstspielstatusmeldung hinzugefügt")
+	guild.logger().Info("Selbstspielstatusmeldung hinzugefügt")
 
 	if guild.AmongUsData.GetPhase() != game.MENU {
-		for _, e := range guild.StatusEmojis[true] {
-			guild.GameStateMsg.AddReaction(s, e.FormatForReaction())
-		}
-		guild.GameStateMsg.AddReaction(s, "❌")
+		guild.addLinkControls(s)
 	}
 }
 
 // sendMessage provides a single interface to send a message to a channel via discord
-func sendMessage(s *discordgo.Session, channelID string, message string) *discordgo.Message {
+func sendMessage(s DiscordClient, channelID string, message string) *discordgo.Message {
 	msg, err := s.ChannelMessageSend(channelID, message)
 	if err != nil {
-		log.Println(err)
+		logging.Error(err)
 	}
 	return msg
 }
 
-func sendMessageDM(s *discordgo.Session, userID string, message *discordgo.MessageEmbed) *discordgo.Message {
+func sendMessageDM(s DiscordClient, userID string, message *discordgo.MessageEmbed) *discordgo.Message {
 	dmChannel, err := s.UserChannelCreate(userID)
 	if err != nil {
-		log.Println(err)
+		logging.Error(err)
 	}
 	m, err := s.ChannelMessageSendEmbed(dmChannel.ID, message)
 	if err != nil {
-		log.Println(err)
+		logging.Error(err)
 	}
 	return m
 }
 
-func sendMessageEmbed(s *discordgo.Session, channelID string, message *discordgo.MessageEmbed) *discordgo.Message {
+func sendMessageEmbed(s DiscordClient, channelID string, message *discordgo.MessageEmbed) *discordgo.Message {
 	msg, err := s.ChannelMessageSendEmbed(channelID, message)
 	if err != nil {
-		log.Println(err)
+		logging.Error(err)
 	}
 	return msg
 }
 
 // editMessage provides a single interface to edit a message in a channel via discord
-func editMessage(s *discordgo.Session, channelID string, messageID string, message string) *discordgo.Message {
+func editMessage(s DiscordClient, channelID string, messageID string, message string) *discordgo.Message {
 	msg, err := s.ChannelMessageEdit(channelID, messageID, message)
 	if err != nil {
-		log.Println(err)
+		logging.Error(err)
 	}
 	return msg
 }
 
-func editMessageEmbed(s *discordgo.Session, channelID string, messageID string, message *discordgo.MessageEmbed) *discordgo.Message {
+func editMessageEmbed(s DiscordClient, channelID string, messageID string, message *discordgo.MessageEmbed) *discordgo.Message {
 	msg, err := s.ChannelMessageEditEmbed(channelID, messageID, message)
 	if err != nil {
-		log.Println(err)
+		logging.Error(err)
 	}
 	return msg
 }
 
-func deleteMessage(s *discordgo.Session, channelID string, messageID string) {
+func deleteMessage(s DiscordClient, channelID string, messageID string) {
 	err := s.ChannelMessageDelete(channelID, messageID)
 	if err != nil {
-		log.Println(err)
+		logging.Error(err)
 	}
 }
 
-func addReaction(s *discordgo.Session, channelID, messageID, emojiID string) {
+func addReaction(s DiscordClient, channelID, messageID, emojiID string) {
 	err := s.MessageReactionAdd(channelID, messageID, emojiID)
 	if err != nil {
-		log.Println(err)
+		logging.Error(err)
 	}
 }
 
-func removeAllReactions(s *discordgo.Session, channelID, messageID string) {
+func removeAllReactions(s DiscordClient, channelID, messageID string) {
 	err := s.MessageReactionsRemoveAll(channelID, messageID)
 	if err != nil {
-		log.Println(err)
+		logging.Error(err)
 	}
 }
@@ -0,0 +1,137 @@
+package discord
+
+import (
+	"strconv"
+
+	"github.com/denverquane/amongusdiscord/game"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "amongusdiscord"
+
+// outcomes of a guildMemberUpdate call
+const (
+	MemberUpdateSuccess = "success"
+	MemberUpdateNoNick  = "nick_failed"
+	MemberUpdateFailed  = "error"
+)
+
+const primarySessionLabel = "primary"
+const secondarySessionLabel = "secondary"
+
+var (
+	phaseTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "phase_transitions_total",
+		Help:      "Phase transitions received from the capture, by old and new phase",
+	}, []string{"from", "to"})
+
+	memberUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "member_updates_total",
+		Help:      "Mute/deafen/nickname requests issued to Discord, by outcome and the session that issued them",
+	}, []string{"outcome", "session"})
+
+	muteLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "mute_latency_seconds",
+		Help:      "Time from receiving a phase event until the resulting member update completed, including the configured delays",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 8, 13, 21},
+	})
+
+	statusMessageEdits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "status_message_edits_total",
+		Help:      "Status message edits, by whether they were sent, failed, coalesced into an edit that was already waiting, or skipped because nothing changed",
+	}, []string{"result"})
+
+	socketEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "socket_events_total",
+		Help:      "Socket.io events received from captures, by event type",
+	}, []string{"event"})
+
+	eventBusDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "event_bus_dropped_total",
+		Help:      "Guild updates that were dropped before reaching a listener, by update type and reason",
+	}, []string{"type", "reason"})
+
+	eventBusMerged = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "event_bus_merged_total",
+		Help:      "Guild updates that replaced an update of the same type that was still waiting",
+	}, []string{"type"})
+)
+
+func init() {
+	prometheus.MustRegister(phaseTransitions, memberUpdates, muteLatency, statusMessageEdits, socketEvents, eventBusDropped, eventBusMerged)
+}
+
+// registerMetrics adds the gauges that are read straight off the bot whenever /metrics is scraped
+func (bot *Bot) registerMetrics(shardID int) {
+	labels := prometheus.Labels{"shard": strconv.Itoa(shardID)}
+
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   metricsNamespace,
+		Name:        "active_games",
+		Help:        "Games that are currently running",
+		ConstLabels: labels,
+	}, func() float64 {
+		count := 0
+		for _, guild := range bot.AllGuilds.All() {
+			if guild.IsGameRunning() {
+				count++
+			}
+		}
+		return float64(count)
+	}))
+
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   metricsNamespace,
+		Name:        "connected_captures",
+		Help:        "Captures that are currently connected over socket.io",
+		ConstLabels: labels,
+	}, func() float64 {
+		return float64(bot.AllConns.Len())
+	}))
+
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   metricsNamespace,
+		Name:        "event_queue_depth",
+		Help:        "Guild updates waiting for their listener, summed over all guilds",
+		ConstLabels: labels,
+	}, func() float64 {
+		total, _ := bot.EventBus.Depths()
+		return float64(total)
+	}))
+
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   metricsNamespace,
+		Name:        "event_queue_max_depth",
+		Help:        "Guild updates waiting in the fullest queue",
+		ConstLabels: labels,
+	}, func() float64 {
+		_, max := bot.EventBus.Depths()
+		return float64(max)
+	}))
+}
+
+func observePhaseTransition(from, to game.Phase) {
+	phaseTransitions.WithLabelValues(phaseLabel(from), phaseLabel(to)).Inc()
+}
+
+func phaseLabel(phase game.Phase) string {
+	if name, ok := game.PhaseNames[phase]; ok {
+		return string(name)
+	}
+	return "UNINITIALIZED"
+}
+
+// sessionLabel tells which of the sessions issued a request
+func (sm *SessionManager) sessionLabel(s DiscordClient) string {
+	if sm.AltSession != nil && s == sm.AltSession {
+		return secondarySessionLabel
+	}
+	return primarySessionLabel
+}
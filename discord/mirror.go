@@ -0,0 +1,105 @@
+package discord
+
+import (
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// MaxMirrors is how many channels the status message can be mirrored to; every mirror is one more edit per update
+const MaxMirrors = 5
+
+// createStatusMessage posts the status message to the channel, and its mirrors to the guild's mirror channels
+func (guild *GuildState) createStatusMessage(s DiscordClient, channelID, authorID string) {
+	me := gameStateResponse(guild)
+	guild.GameStateMsg.CreateMessage(s, me, channelID, authorID)
+	for _, mirrorID := range guild.PGD().MirrorChannelIDs {
+		guild.GameStateMsg.AddMirror(s, me, mirrorID)
+	}
+}
+
+// findTextChannel finds the text channel by its mention, ID or name
+func findTextChannel(channels []*discordgo.Channel, input string) *discordgo.Channel {
+	input = strings.TrimSuffix(strings.TrimPrefix(input, "<#"), ">")
+	for _, c := range channels {
+		if c.Type == discordgo.ChannelTypeGuildText && (c.ID == input || strings.ToLower(c.Name) == strings.ToLower(input)) {
+			return c
+		}
+	}
+	return nil
+}
+
+func mentionChannels(ids []string) string {
+	mentions := make([]string, len(ids))
+	for i, id := range ids {
+		mentions[i] = "<#" + id + ">"
+	}
+	return strings.Join(mentions, ", ")
+}
+
+// handleMirrorCommand lists, adds or removes the channels the status message is mirrored to
+func (bot *Bot) handleMirrorCommand(guild *GuildState, s DiscordClient, m *discordgo.MessageCreate, args []string) {
+	pgd := guild.PGD()
+	if len(args) == 1 {
+		if len(pgd.MirrorChannelIDs) == 0 {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("mirror.none"))
+		} else {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("mirror.list", mentionChannels(pgd.MirrorChannelIDs)))
+		}
+		return
+	}
+	if len(args) < 3 || (args[1] != "add" && args[1] != "remove") {
+		s.ChannelMessageSend(m.ChannelID, guild.tr("mirror.usage", pgd.CommandPrefix))
+		return
+	}
+	channels, err := s.GuildChannels(m.GuildID)
+	if err != nil {
+		guild.logger().Error(err)
+	}
+	channel := findTextChannel(channels, args[2])
+	if channel == nil {
+		s.ChannelMessageSend(m.ChannelID, guild.tr("mirror.notFound", args[2]))
+		return
+	}
+
+	index := -1
+	for i, id := range pgd.MirrorChannelIDs {
+		if id == channel.ID {
+			index = i
+		}
+	}
+	if args[1] == "add" {
+		if index >= 0 {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("mirror.already", channel.ID))
+			return
+		}
+		if len(pgd.MirrorChannelIDs) >= MaxMirrors {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("mirror.tooMany", MaxMirrors))
+			return
+		}
+		pgd.MirrorChannelIDs = append(pgd.MirrorChannelIDs, channel.ID)
+		//a game that's already running gets its mirror right away
+		guild.GameStateMsg.AddMirror(s, gameStateResponse(guild), channel.ID)
+		guild.logger().Infof("Statusmeldung wird jetzt auch in %s gespiegelt", channel.ID)
+		s.ChannelMessageSend(m.ChannelID, guild.tr("mirror.added", channel.ID))
+	} else {
+		if index < 0 {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("mirror.notMirrored", channel.ID))
+			return
+		}
+		pgd.MirrorChannelIDs = append(pgd.MirrorChannelIDs[:index], pgd.MirrorChannelIDs[index+1:]...)
+		guild.GameStateMsg.RemoveMirror(s, channel.ID)
+		guild.logger().Infof("Statusmeldung wird nicht mehr in %s gespiegelt", channel.ID)
+		s.ChannelMessageSend(m.ChannelID, guild.tr("mirror.removed", channel.ID))
+	}
+
+	data, err := pgd.ToData()
+	if err != nil {
+		guild.logger().Error(err)
+		return
+	}
+	err = bot.StorageInterface.WriteGuildData(m.GuildID, data)
+	if err != nil {
+		guild.logger().Error(err)
+	}
+}
@@ -0,0 +1,72 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/game"
+)
+
+const flowSpectatorID = "5"
+
+func statusEmbeds(fake *FakeDiscord, channelID string) []*discordgo.MessageEmbed {
+	embeds := make([]*discordgo.MessageEmbed, 0)
+	for _, msg := range fake.Messages(channelID) {
+		if len(msg.Embeds) == 1 {
+			embeds = append(embeds, msg.Embeds[0])
+		}
+	}
+	return embeds
+}
+
+func TestMirroredStatusMessage(t *testing.T) {
+	bot, fake := fakeBot(t)
+	fake.AddChannel(flowGuildID, flowSpectatorID, "zuschauer", discordgo.ChannelTypeGuildText)
+	guild, _ := bot.AllGuilds.Get(flowGuildID)
+
+	sendCommand(bot, fake, ".au mirror add <#"+flowSpectatorID+">")
+	if len(guild.PGD().MirrorChannelIDs) != 1 {
+		t.Fatalf("der Spiegel sollte gespeichert sein: %v", guild.PGD().MirrorChannelIDs)
+	}
+
+	sendCommand(bot, fake, ".au new ABCDEF eu")
+	mirrors := statusEmbeds(fake, flowSpectatorID)
+	if len(mirrors) != 1 {
+		t.Fatalf("erwartet eine gespiegelte Statusmeldung, bekommen %d", len(mirrors))
+	}
+
+	bot.PushGuildPhaseUpdate(flowGuildID, game.LOBBY)
+	waitFor(t, "die Lobby im Spiegel", func() bool {
+		mirrors := statusEmbeds(fake, flowSpectatorID)
+		return len(mirrors) == 1 && mirrors[0].Title == guild.tr("phase.LOBBY")
+	})
+	for _, msg := range fake.Messages(flowSpectatorID) {
+		if len(fake.Reactions(msg.ID)) > 0 {
+			t.Error("nur die eigentliche Statusmeldung nimmt Reaktionen")
+		}
+	}
+
+	//bumping the message leaves the mirror where it is
+	mirrorID := fake.Messages(flowSpectatorID)[0].ID
+	first := guild.GameStateMsg.message.ID
+	guild.refreshStatusMessage(fake, flowTextID)
+	waitFor(t, "die alte Statusmeldung ist gelöscht", func() bool {
+		for _, msg := range fake.Messages(flowTextID) {
+			if msg.ID == first {
+				return false
+			}
+		}
+		return true
+	})
+	if messages := fake.Messages(flowSpectatorID); len(messages) != 1 || messages[0].ID != mirrorID {
+		t.Errorf("der Spiegel sollte bleiben, wie er ist: %+v", messages)
+	}
+
+	sendCommand(bot, fake, ".au mirror remove zuschauer")
+	waitFor(t, "der Spiegel ist gelöscht", func() bool {
+		return len(statusEmbeds(fake, flowSpectatorID)) == 0
+	})
+	if len(guild.PGD().MirrorChannelIDs) != 0 {
+		t.Errorf("der Spiegel sollte nicht mehr gespeichert sein: %v", guild.PGD().MirrorChannelIDs)
+	}
+}
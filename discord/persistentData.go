@@ -5,6 +5,9 @@ import (
 	"io/ioutil"
 	"os"
 	"sync"
+
+	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/locale"
 )
 
 type PersistentGuildData struct {
@@ -12,6 +15,7 @@ type PersistentGuildData struct {
 
 	CommandPrefix         string `json:"commandPrefix"`
 	DefaultTrackedChannel string `json:"defaultTrackedChannel"`
+	Language              string `json:"language"`
 
 	AdminUserIDs          []string   `json:"adminIDs"`
 	PermissionedRoleIDs   []string   `json:"permissionRoleIDs"`
@@ -19,6 +23,15 @@ type PersistentGuildData struct {
 	VoiceRules            VoiceRules `json:"voiceRules"`
 	ApplyNicknames        bool       `json:"applyNicknames"`
 	UnmuteDeadDuringTasks bool       `json:"UnmuteDeadDuringTasks"`
+	Seasons               []Season   `json:"seasons"`
+
+	InputAliases map[game.AliasKind]map[string]string `json:"inputAliases"`
+	//the status message is mirrored to these text channels
+	MirrorChannelIDs []string `json:"mirrorChannelIDs"`
+	//the status message is posted again after this many messages below it; 0 is off
+	AutoBump int `json:"autoBump"`
+	//milliseconds in which changes to the status message are collected into one edit; 0 is the bot's default
+	EditWindow int `json:"editWindow"`
 
 	lock sync.RWMutex
 }
@@ -28,6 +41,7 @@ func PGDDefault(id string) *PersistentGuildData {
 		GuildID:               id,
 		CommandPrefix:         ".au",
 		DefaultTrackedChannel: "",
+		Language:              locale.DefaultLanguage,
 		AdminUserIDs:          nil,
 		PermissionedRoleIDs:   nil,
 		Delays:                MakeDefaultDelays(),
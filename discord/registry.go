@@ -0,0 +1,91 @@
+package discord
+
+import (
+	"sync"
+)
+
+// GuildRegistry holds the state of every guild on the shard, by guild ID. It's read from the discordgo
+// handlers, the socket.io callbacks and the listeners at the same time
+type GuildRegistry struct {
+	guilds map[string]*GuildState
+	lock   sync.RWMutex
+}
+
+func MakeGuildRegistry() GuildRegistry {
+	return GuildRegistry{
+		guilds: make(map[string]*GuildState),
+		lock:   sync.RWMutex{},
+	}
+}
+
+func (gr *GuildRegistry) Get(guildID string) (*GuildState, bool) {
+	gr.lock.RLock()
+	defer gr.lock.RUnlock()
+	guild, ok := gr.guilds[guildID]
+	return guild, ok
+}
+
+func (gr *GuildRegistry) Set(guildID string, guild *GuildState) {
+	gr.lock.Lock()
+	gr.guilds[guildID] = guild
+	gr.lock.Unlock()
+}
+
+func (gr *GuildRegistry) Len() int {
+	gr.lock.RLock()
+	defer gr.lock.RUnlock()
+	return len(gr.guilds)
+}
+
+// All returns a snapshot of the guilds, so callers can take their time without holding the lock
+func (gr *GuildRegistry) All() []*GuildState {
+	gr.lock.RLock()
+	defer gr.lock.RUnlock()
+	guilds := make([]*GuildState, 0, len(gr.guilds))
+	for _, guild := range gr.guilds {
+		guilds = append(guilds, guild)
+	}
+	return guilds
+}
+
+// ConnRegistry maps the capture sockets to the guild they're linked to
+type ConnRegistry struct {
+	conns map[string]string
+	lock  sync.RWMutex
+}
+
+func MakeConnRegistry() ConnRegistry {
+	return ConnRegistry{
+		conns: make(map[string]string),
+		lock:  sync.RWMutex{},
+	}
+}
+
+// GuildID returns the guild the socket is linked to, and false if it isn't linked to any
+func (cr *ConnRegistry) GuildID(connID string) (string, bool) {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+	guildID, ok := cr.conns[connID]
+	return guildID, ok && guildID != ""
+}
+
+func (cr *ConnRegistry) Set(connID, guildID string) {
+	cr.lock.Lock()
+	cr.conns[connID] = guildID
+	cr.lock.Unlock()
+}
+
+// Remove forgets the socket, and returns the guild it was linked to
+func (cr *ConnRegistry) Remove(connID string) string {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+	guildID := cr.conns[connID]
+	delete(cr.conns, connID)
+	return guildID
+}
+
+func (cr *ConnRegistry) Len() int {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+	return len(cr.conns)
+}
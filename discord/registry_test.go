@@ -0,0 +1,83 @@
+package discord
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/denverquane/amongusdiscord/game"
+)
+
+// run with -race; the registries and flags are used from the socket.io callbacks, the discordgo
+// handlers and the listeners at the same time
+func TestRegistriesConcurrentAccess(t *testing.T) {
+	bot := &Bot{
+		AllConns:  MakeConnRegistry(),
+		AllGuilds: MakeGuildRegistry(),
+		EventBus:  MakeEventBus(),
+	}
+	const guilds = 20
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < guilds; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			guildID := fmt.Sprint(i)
+			bot.AllGuilds.Set(guildID, &GuildState{
+				persistentGuildData: PGDDefault(guildID),
+				UserData:            MakeUserDataSet(),
+				Tracking:            MakeTracking(),
+				GameStateMsg:        MakeGameStateMessage(),
+				AmongUsData:         game.NewAmongUsData(),
+			})
+			bot.EventBus.Register(guildID)
+			data, err := PGDDefault(guildID).ToData()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			connID := "socket" + guildID
+			for j := 0; j < 50; j++ {
+				bot.AllConns.Set(connID, guildID)
+				if gid, ok := bot.AllConns.GuildID(connID); ok {
+					bot.PushGuildPhaseUpdate(gid, game.TASKS)
+				}
+				if guild, ok := bot.AllGuilds.Get(guildID); ok {
+					guild.SetLinked(j%2 == 0)
+					guild.ToggleGameRunning()
+				}
+				//like the storage watcher does when the config file changes
+				bot.reloadGuildData(guildID, data)
+				bot.AllConns.Remove(connID)
+			}
+		}(i)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				for _, guild := range bot.AllGuilds.All() {
+					summarizeGuild(guild)
+				}
+				bot.EventBus.Depths()
+				bot.AllConns.Len()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if bot.AllGuilds.Len() != guilds {
+		t.Errorf("erwartet %d Gilden, bekommen %d", guilds, bot.AllGuilds.Len())
+	}
+	if bot.AllConns.Len() != 0 {
+		t.Errorf("alle Verbindungen sollten entfernt sein, %d übrig", bot.AllConns.Len())
+	}
+	for _, guild := range bot.AllGuilds.All() {
+		//toggled an even number of times
+		if guild.IsGameRunning() {
+			t.Errorf("Gilde %s sollte nicht laufen", guild.PGD().GuildID)
+		}
+	}
+}
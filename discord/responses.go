@@ -4,27 +4,29 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
 
 	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/locale"
 )
 
-func helpResponse(version, CommandPrefix string) string {
+// helpKeys are the commands in the order the help lists them
+var helpKeys = []string{"help", "new", "refresh", "end", "track", "link", "unlink", "settings", "aliases", "stats", "leaderboard", "emojis", "mirror", "debug", "force"}
+
+// tr renders the text for the key in the guild's language
+func (guild *GuildState) tr(key string, args ...interface{}) string {
+	return locale.T(guild.PGD().Language, key, args...)
+}
+
+func helpResponse(version, CommandPrefix, lang string) string {
 	buf := bytes.NewBuffer([]byte{})
-	buf.WriteString(fmt.Sprintf("Among Us Bot Commands (v%s):\n", version))
-	buf.WriteString("Hast du Probleme oder Vorschläge? Trete dem Discord-Server des originalen Entwickers bei (Englisch) <https://discord.gg/ZkqZSWF>!\n")
-	buf.WriteString(fmt.Sprintf("`%s help` oder `%s h`: Hilfeinformationen und Befehlsverwendung anzeigen.\n", CommandPrefix, CommandPrefix))
-	buf.WriteString(fmt.Sprintf("`%s new` oder `%s n`: Starten Sie das Spiel in diesem Textkanal. Akzeptiert Raumcode und Region als Argumente. z.B.: `%s new CODE eu`. Funktioniert auch zum Neustart.\n", CommandPrefix, CommandPrefix, CommandPrefix))
-	buf.WriteString(fmt.Sprintf("`%s refresh` oder `%s r`: Erstelle die Statusmeldung des Bots vollständig neu, falls sie zu weit oben im Chat landet.\n", CommandPrefix, CommandPrefix))
-	buf.WriteString(fmt.Sprintf("`%s end` oder `%s e`: Beende das Spiel vollständig und höre auf, Spieler zu verfolgen. Hebt die Stummschaltung auf und setzt den Status zurück.\n", CommandPrefix, CommandPrefix))
-	buf.WriteString(fmt.Sprintf("`%s track` oder `%s t`: Weise den Bot an, nur den bereitgestellten Sprachkanal für die Automatisierung zu verwenden. z.B.: `%s t <vc_name>`\n", CommandPrefix, CommandPrefix, CommandPrefix))
-	buf.WriteString(fmt.Sprintf("`%s link` oder `%s l`: Verknüpfe einen Spieler manuell mit seinem Namen oder seiner Farbe im Spiel. z.B.: `%s l @player cyan` oder `%s l @player bob`\n", CommandPrefix, CommandPrefix, CommandPrefix, CommandPrefix))
-	buf.WriteString(fmt.Sprintf("`%s unlink` oder `%s u`: Löse manuell die Verknüpfung eines Spielers. z.B.: `%s u @player`\n", CommandPrefix, CommandPrefix, CommandPrefix))
-	buf.WriteString(fmt.Sprintf("`%s settings` oder `%s s`: Anzeigen und Ändern von Einstellungen für den Bot, z.B. das Befehlspräfix oder das Stummschaltungsverhalten\n", CommandPrefix, CommandPrefix))
-	buf.WriteString(fmt.Sprintf("`%s force` oder `%s f`: Erzwinge einen Übergang zu einer Stufe, wenn der Status fehlerhaft ist. z.B.: `%s f task` or `%s f d`(discuss)\n", CommandPrefix, CommandPrefix, CommandPrefix, CommandPrefix))
+	buf.WriteString(locale.T(lang, "help.title", version) + "\n")
+	buf.WriteString(locale.T(lang, "help.support") + "\n")
+	for _, key := range helpKeys {
+		buf.WriteString(locale.T(lang, "help."+key, CommandPrefix) + "\n")
+	}
 
 	return buf.String()
 }
@@ -35,41 +37,41 @@ func (guild *GuildState) trackChannelResponse(channelName string, allChannels []
 
 			guild.Tracking.AddTrackedChannel(c.ID, c.Name, forGhosts)
 
-			log.Println(fmt.Sprintf("Verfolge jetzt \"%s\" Voice Channel für Automute (für Geister? %v)!", c.Name, forGhosts))
-			return fmt.Sprintf("Verfolge jetzt \"%s\" Voice Channel für Automute (für Geister? %v)", c.Name, forGhosts)
+			guild.logger().Infof("Verfolge jetzt \"%s\" Voice Channel für Automute (für Geister? %v)!", c.Name, forGhosts)
+			return guild.tr("track.tracking", c.Name, forGhosts)
 		}
 	}
-	return fmt.Sprintf("Kein Kanal mit dem Namen gefunden: %s!\n", channelName)
+	return guild.tr("track.notFound", channelName)
 }
 
-func (guild *GuildState) linkPlayerResponse(s *discordgo.Session, GuildID string, args []string) {
+func (guild *GuildState) linkPlayerResponse(s DiscordClient, GuildID string, args []string) {
 
-	g, err := s.State.Guild(guild.PersistentGuildData.GuildID)
+	g, err := s.CachedGuild(guild.PGD().GuildID)
 	if err != nil {
-		log.Println(err)
+		guild.logger().Error(err)
 		return
 	}
 
 	userID := getMemberFromString(s, GuildID, args[0])
 	if userID == "" {
-		log.Printf("Sorry, ich weiß nicht, wer `%s` ist. Du kannst die ID, den Nutzernamen, username#XXXX, den Nickanem eingeben oder @erwähnen", args[0])
+		guild.logger().Infof("Sorry, ich weiß nicht, wer `%s` ist. Du kannst die ID, den Nutzernamen, username#XXXX, den Nickanem eingeben oder @erwähnen", args[0])
 	}
 
 	_, added := guild.checkCacheAndAddUser(g, s, userID)
 	if !added {
-		log.Println("Keine Nutzer im Discord gefunden mit userID " + userID)
+		guild.logger().Info("Keine Nutzer im Discord gefunden mit userID " + userID)
 	}
 
 	combinedArgs := strings.ToLower(strings.Join(args[1:], ""))
 
-	if game.IsColorString(combinedArgs) {
-		playerData := guild.AmongUsData.GetByColor(combinedArgs)
+	if color, ok := guild.inputAliases().Color(combinedArgs); ok {
+		playerData := guild.AmongUsData.GetByColor(game.GetColorStringForInt(color))
 		if playerData != nil {
 			found := guild.UserData.UpdatePlayerData(userID, playerData)
 			if found {
-				log.Printf("%s wurde erfolgreich mit einer Farbe verknüpft\n", userID)
+				guild.logger().Infof("%s wurde erfolgreich mit einer Farbe verknüpft", userID)
 			} else {
-				log.Printf("Es wurde kein Spieler mit der ID %s gefunden\n", userID)
+				guild.logger().Infof("Es wurde kein Spieler mit der ID %s gefunden", userID)
 			}
 		}
 		return
@@ -78,9 +80,9 @@ func (guild *GuildState) linkPlayerResponse(s *discordgo.Session, GuildID string
 		if playerData != nil {
 			found := guild.UserData.UpdatePlayerData(userID, playerData)
 			if found {
-				log.Printf("%s erfolgreich mit Namen verknüpft\n", userID)
+				guild.logger().Infof("%s erfolgreich mit Namen verknüpft", userID)
 			} else {
-				log.Printf("Es wurde kein Spieler gefunden mit ID %s\n", userID)
+				guild.logger().Infof("Es wurde kein Spieler gefunden mit ID %s", userID)
 			}
 		}
 	}
@@ -98,26 +100,33 @@ func gameStateResponse(guild *GuildState) *discordgo.MessageEmbed {
 	return messages[guild.AmongUsData.GetPhase()](guild)
 }
 
-func lobbyMetaEmbedFields(tracking *Tracking, room, region string, playerCount int, linkedPlayers int) []*discordgo.MessageEmbedField {
-	str := tracking.ToStatusString()
+func lobbyMetaEmbedFields(tracking *Tracking, room, region string, playerCount int, linkedPlayers int, lang string) []*discordgo.MessageEmbedField {
+	str := tracking.ToStatusString(lang)
+	//the room and region are empty if they weren't given with the new command
+	if room == "" {
+		room = locale.T(lang, "status.notProvided")
+	}
+	if region == "" {
+		region = locale.T(lang, "status.notProvided")
+	}
 	gameInfoFields := make([]*discordgo.MessageEmbedField, 4)
 	gameInfoFields[0] = &discordgo.MessageEmbedField{
-		Name:   "Room Code",
+		Name:   locale.T(lang, "status.roomCode"),
 		Value:  fmt.Sprintf("%s", room),
 		Inline: true,
 	}
 	gameInfoFields[1] = &discordgo.MessageEmbedField{
-		Name:   "Region",
+		Name:   locale.T(lang, "status.region"),
 		Value:  fmt.Sprintf("%s", region),
 		Inline: true,
 	}
 	gameInfoFields[2] = &discordgo.MessageEmbedField{
-		Name:   "Verfolgung",
+		Name:   locale.T(lang, "status.tracking"),
 		Value:  str,
 		Inline: true,
 	}
 	gameInfoFields[3] = &discordgo.MessageEmbedField{
-		Name:   "Spieler verbunden",
+		Name:   locale.T(lang, "status.playersLinked"),
 		Value:  fmt.Sprintf("%v/%v", linkedPlayers, playerCount),
 		Inline: false,
 	}
@@ -135,22 +144,22 @@ var Thumbnail = discordgo.MessageEmbedThumbnail{
 
 func menuMessage(g *GuildState) *discordgo.MessageEmbed {
 	alarmFormatted := ":x:"
-	if v, ok := g.SpecialEmojis["alarm"]; ok {
+	if v, ok := g.Emojis().Special["alarm"]; ok {
 		alarmFormatted = v.FormatForInline()
 	}
 	color := 15158332 //red
 	desc := ""
-	if g.Linked {
+	if g.IsLinked() {
 		desc = g.makeDescription()
 		color = 3066993
 	} else {
-		desc = fmt.Sprintf("%s**Kein Capture verbunden! Klicke auf den Link in den DMs, um eine Verbindung herzustellen!**%s", alarmFormatted, alarmFormatted)
+		desc = g.tr("status.noCapture", alarmFormatted)
 	}
 
 	msg := discordgo.MessageEmbed{
 		URL:         "",
 		Type:        "",
-		Title:       "Hauptmenü",
+		Title:       g.tr("phase.MENU"),
 		Description: desc,
 		Timestamp:   "",
 		Footer:      nil,
@@ -165,6 +174,17 @@ func menuMessage(g *GuildState) *discordgo.MessageEmbed {
 	return &msg
 }
 
+// lobbyFooter tells the players how to link; with the text status there are no reactions for it
+func (guild *GuildState) lobbyFooter() string {
+	if !guild.GameStateMsg.NoComponents() {
+		return guild.tr("status.lobbyFooterControls")
+	}
+	if guild.Emojis().TextStatus {
+		return guild.tr("status.lobbyFooterText", guild.PGD().CommandPrefix)
+	}
+	return guild.tr("status.lobbyFooter")
+}
+
 func lobbyMessage(g *GuildState) *discordgo.MessageEmbed {
 	//gameInfoFields[2] = &discordgo.MessageEmbedField{
 	//	Name:   "\u200B",
@@ -172,32 +192,32 @@ func lobbyMessage(g *GuildState) *discordgo.MessageEmbed {
 	//	Inline: false,
 	//}
 	room, region := g.AmongUsData.GetRoomRegion()
-	gameInfoFields := lobbyMetaEmbedFields(&g.Tracking, room, region, g.AmongUsData.NumDetectedPlayers(), g.UserData.GetCountLinked())
+	gameInfoFields := lobbyMetaEmbedFields(&g.Tracking, room, region, g.AmongUsData.NumDetectedPlayers(), g.UserData.GetCountLinked(), g.PGD().Language)
 
-	listResp := g.UserData.ToEmojiEmbedFields(g.AmongUsData.NameColorMappings(), g.AmongUsData.NameAliveMappings(), g.StatusEmojis)
+	listResp := g.UserData.ToEmojiEmbedFields(g.AmongUsData.NameColorMappings(), g.AmongUsData.NameAliveMappings(), g.statusEmojis(), g.PGD().Language)
 	listResp = append(gameInfoFields, listResp...)
 
 	alarmFormatted := ":x:"
-	if v, ok := g.SpecialEmojis["alarm"]; ok {
+	if v, ok := g.Emojis().Special["alarm"]; ok {
 		alarmFormatted = v.FormatForInline()
 	}
 	color := 15158332 //red
 	desc := ""
-	if g.Linked {
+	if g.IsLinked() {
 		desc = g.makeDescription()
 		color = 3066993
 	} else {
-		desc = fmt.Sprintf("%s**Kein Capture verbunden! Klicke auf den Link in den DMs, um eine Verbindung herzustellen!**%s", alarmFormatted, alarmFormatted)
+		desc = g.tr("status.noCapture", alarmFormatted)
 	}
 
 	msg := discordgo.MessageEmbed{
 		URL:         "",
 		Type:        "",
-		Title:       "Lobby",
+		Title:       g.tr("phase.LOBBY"),
 		Description: desc,
 		Timestamp:   "",
 		Footer: &discordgo.MessageEmbedFooter{
-			Text:         "Reagiere auf diese Nachricht mit deiner Farbe im Spiel! (oder ❌ um zu verlassen)",
+			Text:         g.tr("status.lobbyFooter"),
 			IconURL:      "",
 			ProxyIconURL: "",
 		},
@@ -216,8 +236,8 @@ func gamePlayMessage(guild *GuildState) *discordgo.MessageEmbed {
 	// add the player list
 	//guild.UserDataLock.Lock()
 	room, region := guild.AmongUsData.GetRoomRegion()
-	gameInfoFields := lobbyMetaEmbedFields(&guild.Tracking, room, region, guild.AmongUsData.NumDetectedPlayers(), guild.UserData.GetCountLinked())
-	listResp := guild.UserData.ToEmojiEmbedFields(guild.AmongUsData.NameColorMappings(), guild.AmongUsData.NameAliveMappings(), guild.StatusEmojis)
+	gameInfoFields := lobbyMetaEmbedFields(&guild.Tracking, room, region, guild.AmongUsData.NumDetectedPlayers(), guild.UserData.GetCountLinked(), guild.PGD().Language)
+	listResp := guild.UserData.ToEmojiEmbedFields(guild.AmongUsData.NameColorMappings(), guild.AmongUsData.NameAliveMappings(), guild.statusEmojis(), guild.PGD().Language)
 	listResp = append(gameInfoFields, listResp...)
 	//guild.UserDataLock.Unlock()
 	var color int
@@ -236,7 +256,7 @@ func gamePlayMessage(guild *GuildState) *discordgo.MessageEmbed {
 	msg := discordgo.MessageEmbed{
 		URL:         "",
 		Type:        "",
-		Title:       string(phase.ToString()),
+		Title:       guild.tr("phase." + string(phase.ToString())),
 		Description: guild.makeDescription(),
 		Timestamp:   "",
 		Color:       color,
@@ -254,23 +274,23 @@ func gamePlayMessage(guild *GuildState) *discordgo.MessageEmbed {
 
 func (guild *GuildState) makeDescription() string {
 	buf := bytes.NewBuffer([]byte{})
-	if !guild.GameRunning {
-		buf.WriteString("\n**Bot ist angehalten! Stoppe die Pause mit `" + guild.PersistentGuildData.CommandPrefix + " p`!**\n\n")
+	if !guild.IsGameRunning() {
+		buf.WriteString("\n" + guild.tr("status.paused", guild.PGD().CommandPrefix) + "\n\n")
 	}
 
 	author := guild.GameStateMsg.leaderID
 	if author != "" {
-		buf.WriteString("<@" + author + "> führt ein Among Us Spiel aus!\nTDas Spiel findet statt in ")
+		buf.WriteString(guild.tr("status.leader", author) + "\n")
 	}
 
 	if len(guild.Tracking.tracking) == 0 {
-		buf.WriteString("jedem Sprachkanal!")
+		buf.WriteString(guild.tr("status.anyChannel"))
 	} else {
 		t, err := guild.Tracking.FindAnyTrackedChannel(false)
 		if err != nil {
-			buf.WriteString("einem ungültiger Sprachkanal!")
+			buf.WriteString(guild.tr("status.invalidChannel"))
 		} else {
-			buf.WriteString("dem **" + t.channelName + "** Sprachkanal!")
+			buf.WriteString(guild.tr("status.channel", t.channelName))
 		}
 	}
 
@@ -1,27 +1,37 @@
 package discord
 
 import (
-	"fmt"
+	"bytes"
 	"github.com/bwmarrin/discordgo"
 	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/locale"
 	"github.com/denverquane/amongusdiscord/storage"
-	"log"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
-func HandleSettingsCommand(s *discordgo.Session, m *discordgo.MessageCreate, guild *GuildState, storageInterface storage.StorageInterface, args []string) {
+// settingNames are the catalog groups of the settings, in the order they're listed
+var settingNames = []string{"prefix", "channel", "admins", "roles", "nicknames", "unmuteDead", "delays", "voiceRules", "seasons", "inputAliases", "autoBump", "editWindow", "language", "export", "import"}
+
+// settingUsage is the syntax of the setting, and what it does
+func (guild *GuildState) settingUsage(name string) string {
+	if name == "language" {
+		return guild.tr("settings.language.usage", strings.Join(locale.Languages(), "/"))
+	}
+	return guild.tr("settings." + name + ".usage")
+}
+
+func HandleSettingsCommand(s DiscordClient, m *discordgo.MessageCreate, guild *GuildState, storageInterface storage.StorageInterface, args []string) {
 	// if no arg passed, send them list of possible settings to change
 	if len(args) == 1 {
-		s.ChannelMessageSend(m.ChannelID, "Die Liste der möglichen Einstellungen ist:\n"+
-			"•`CommandPrefix [prefix]`: Ändere die Präfix des Bots auf diesem Server\n"+
-			"•`DefaultTrackedChannel [voiceChannel]`: Ändere den standardmäßigen Sprachkanal, den der Bot verfolgt\n"+
-			"•`AdminUserIDs [user 1] [user 2] [etc]`: Hinzufügen oder Entfernen von Bot-Administratoren und Benutzern, die dem Bot Befehle geben können\n"+
-			"•`PermissionRoleIDs [role 1] [role 2] [etc]`: Hinzufügen oder Entfernen von Bot-Administratorrollen, also Rollen, die dem Bot Befehle geben können.\n"+
-			"•`ApplyNicknames [true/false]`: Ob der Bot die Spitznamen der Spieler ändern soll, um die Farbe des Spielers wiederzugeben\n"+
-			"•`UnmuteDeadDuringTasks [true/false]`: Ob der Bot tote Spieler sofort stumm schalten soll, wenn sie sterben (**WARNUNG**: enthüllt Informationen)\n"+
-			"•`Delays [old game phase] [new game phase] [delay]`: Ändere die Verzögerung zwischen dem Ändern der Spielphase und dem Stummschalten/aufheben der Stummschaltung von Spielern\n"+
-			"•`VoiceRules [mute/deaf] [game phase] [alive/dead] [true/false]`: Ob lebende/tote Spieler während dieser Spielphase stumm geschaltet/betäubt werden sollen")
+		buf := bytes.NewBuffer([]byte{})
+		buf.WriteString(guild.tr("settings.title") + "\n")
+		for _, name := range settingNames {
+			buf.WriteString("•" + guild.settingUsage(name) + "\n")
+		}
+		s.ChannelMessageSend(m.ChannelID, buf.String())
 		return
 	}
 	// if command invalid, no need to reapply changes to json file
@@ -87,52 +97,77 @@ func HandleSettingsCommand(s *discordgo.Session, m *discordgo.MessageCreate, gui
 		fallthrough
 	case "vr":
 		isValid = SettingVoiceRules(s, m, guild, args)
+	case "seasons":
+		fallthrough
+	case "season":
+		isValid = SettingSeasons(s, m, guild, args)
+	case "inputaliases":
+		fallthrough
+	case "input":
+		fallthrough
+	case "ia":
+		isValid = SettingInputAliases(s, m, guild, args)
+	case "autobump":
+		fallthrough
+	case "bump":
+		isValid = SettingAutoBump(s, m, guild, args)
+	case "editwindow":
+		fallthrough
+	case "window":
+		isValid = SettingEditWindow(s, m, guild, args)
+	case "language":
+		fallthrough
+	case "lang":
+		fallthrough
+	case "sprache":
+		isValid = SettingLanguage(s, m, guild, args)
+	case "export":
+		guild.settingsExport(s, m)
+	case "import":
+		isValid = guild.settingsImport(s, m, args)
 	default:
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Sorry, `%s` ist keine gültige Einstellung!\n"+
-			"Gültige Einstellungen sind `CommandPrefix`, `DefaultTrackedChannel`, `AdminUserIDs`, `ApplyNicknames`, `UnmuteDeadDuringTasks`, `Delays` und `VoiceRules`.", args[1]))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.invalid", args[1]))
 	}
 	if isValid {
-		data, err := guild.PersistentGuildData.ToData()
+		guild.applyEditWindow()
+		data, err := guild.PGD().ToData()
 		if err != nil {
-			log.Println(err)
+			guild.logger().Error(err)
 		} else {
 			err := storageInterface.WriteGuildData(m.GuildID, data)
 			if err != nil {
-				log.Println(err)
+				guild.logger().Error(err)
 			}
 		}
 	}
 }
 
-func CommandPrefixSetting(s *discordgo.Session, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
+func CommandPrefixSetting(s DiscordClient, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
 	if len(args) == 2 {
-		s.ChannelMessageSend(m.ChannelID, "`CommandPrefix [prefix]`: Ändere die Prefix des Bots auf diesem Server.")
+		s.ChannelMessageSend(m.ChannelID, guild.settingUsage("prefix"))
 		return false
 	}
 	if len(args[2]) > 10 {
 		// prevent someone from setting something ridiculous lol
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Sorry, die Prefix `%s` es ist zu lang (%d Zeichen, max 10). Versuche etwas kürzeres.", args[2], len(args[2])))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.prefix.tooLong", args[2], len(args[2])))
 		return false
 	}
-	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Das Gildenpräfix wurde von `%s` zu `%s` geändert. Nutze das von jetzt an!",
-		guild.PersistentGuildData.CommandPrefix, args[2]))
-	guild.PersistentGuildData.CommandPrefix = args[2]
+	s.ChannelMessageSend(m.ChannelID, guild.tr("settings.prefix.changed", guild.PGD().CommandPrefix, args[2]))
+	guild.PGD().CommandPrefix = args[2]
 	return true
 }
 
-func SettingDefaultTrackedChannel(s *discordgo.Session, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
+func SettingDefaultTrackedChannel(s DiscordClient, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
 	if len(args) == 2 {
 		// give them both command syntax and current voice channel
 		channelList, _ := s.GuildChannels(m.GuildID)
 		for _, c := range channelList {
-			if c.ID == guild.PersistentGuildData.DefaultTrackedChannel {
-				s.ChannelMessageSend(m.ChannelID, "`DefaultTrackedChannel [voiceChannel]`: Ändere den standardmäßigen Sprachkanal, den der Bot verfolgt.\n"+
-					fmt.Sprintf("Derzeit verfolge ich den `%s` Sprachkanal", c.Name))
+			if c.ID == guild.PGD().DefaultTrackedChannel {
+				s.ChannelMessageSend(m.ChannelID, guild.settingUsage("channel")+"\n"+guild.tr("settings.channel.current", c.Name))
 				return false
 			}
 		}
-		s.ChannelMessageSend(m.ChannelID, "`DefaultTrackedChannel [voiceChannel]`: Ändere den standardmäßigen Sprachkanal, den der Bot verfolgt.\n"+
-			"Derzeit verfolge ich keinen Sprachkanal. Entweder ist die ID ungültig oder du hast mir keine gegeben.")
+		s.ChannelMessageSend(m.ChannelID, guild.settingUsage("channel")+"\n"+guild.tr("settings.channel.none"))
 		return false
 	}
 	// now to find the channel they are referencing
@@ -153,44 +188,40 @@ func SettingDefaultTrackedChannel(s *discordgo.Session, m *discordgo.MessageCrea
 	}
 	// check if channel was found
 	if channelID == "" {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Der Sprachkanal `%s` konnte nicht gefunden werden! Geben den Namen oder die ID ein und stelle sicher, dass der Bot sie sehen kann.", args[2]))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.channel.notFound", args[2]))
 		return false
 	} else {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Der Standard-Sprachkanal wurde geändert zu `%s`. Verwende das von nun an!",
-			channelName))
-		guild.PersistentGuildData.DefaultTrackedChannel = channelID
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.channel.changed", channelName))
+		guild.PGD().DefaultTrackedChannel = channelID
 		return true
 	}
 }
 
-func SettingAdminUserIDs(s *discordgo.Session, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
+func SettingAdminUserIDs(s DiscordClient, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
 	if len(args) == 2 {
-		adminCount := len(guild.PersistentGuildData.AdminUserIDs) // caching for optimisation
+		adminCount := len(guild.PGD().AdminUserIDs) // caching for optimisation
 		// make a nicely formatted string of all the admins: "user1, user2, user3 and user4"
 		if adminCount == 0 {
-			s.ChannelMessageSend(m.ChannelID, "`AdminUserIDs [user 1] [user 2] [etc]`: Hinzufügen oder Entfernen von Bot-Administratoren, also Benutzer, die dem Bot Befehle geben können.\n"+
-				"Derzeit gibt es keine Bot-Administratoren.")
+			s.ChannelMessageSend(m.ChannelID, guild.settingUsage("admins")+"\n"+guild.tr("settings.admins.none"))
 		} else if adminCount == 1 {
 			s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
-				Content: "`AdminUserIDs [user 1] [user 2] [etc]`: Hinzufügen oder Entfernen von Bot-Administratoren, also Benutzer, die dem Bot Befehle geben können.\n" +
-					fmt.Sprintf("Derzeit ist der einzige Administrator <@%s>.", guild.PersistentGuildData.AdminUserIDs[0]),
+				Content:         guild.settingUsage("admins") + "\n" + guild.tr("settings.admins.one", guild.PGD().AdminUserIDs[0]),
 				AllowedMentions: &discordgo.MessageAllowedMentions{Users: nil},
 			})
 		} else {
 			listOfAdmins := ""
-			for index, ID := range guild.PersistentGuildData.AdminUserIDs {
+			for index, ID := range guild.PGD().AdminUserIDs {
 				if index == 0 {
 					listOfAdmins += "<@" + ID + ">"
 				} else if index == adminCount-1 {
-					listOfAdmins += " and <@" + ID + ">"
+					listOfAdmins += guild.tr("settings.and") + "<@" + ID + ">"
 				} else {
 					listOfAdmins += ", <@" + ID + ">"
 				}
 			}
 			// mention users without pinging
 			s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
-				Content: "`AdminUserIDs [user 1] [user 2] [etc]`: Hinzufügen oder Entfernen von Bot-Administratoren, also Benutzer, die dem Bot Befehle geben können.\n" +
-					fmt.Sprintf("Derzeit sind die Admins %s.", listOfAdmins),
+				Content:         guild.settingUsage("admins") + "\n" + guild.tr("settings.admins.many", listOfAdmins),
 				AllowedMentions: &discordgo.MessageAllowedMentions{Users: nil},
 			})
 		}
@@ -206,7 +237,7 @@ func SettingAdminUserIDs(s *discordgo.Session, m *discordgo.MessageCreate, guild
 		}
 		ID := getMemberFromString(s, m.GuildID, userName)
 		if ID == "" {
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Entschuldigung, ich weiß nicht wer `%s` ist. Du kannst mir seine/ihre ID, seinen/ihren Nutzername, username#XXXX, nickname geben oder ihn/sie @erwähnen", userName))
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.admins.notFound", userName))
 			continue
 		}
 		// check if id is already in array
@@ -228,7 +259,7 @@ func SettingAdminUserIDs(s *discordgo.Session, m *discordgo.MessageCreate, guild
 
 	for _, ID := range userIDs {
 		// can't use guild.HasAdminPermissions() because we also need index
-		for index, adminID := range guild.PersistentGuildData.AdminUserIDs {
+		for index, adminID := range guild.PGD().AdminUserIDs {
 			if ID == adminID {
 				// add ID to IDs to be deleted
 				removeAdmins = append(removeAdmins, index)
@@ -237,10 +268,10 @@ func SettingAdminUserIDs(s *discordgo.Session, m *discordgo.MessageCreate, guild
 			}
 		}
 		if ID != "" {
-			guild.PersistentGuildData.AdminUserIDs = append(guild.PersistentGuildData.AdminUserIDs, ID)
+			guild.PGD().AdminUserIDs = append(guild.PGD().AdminUserIDs, ID)
 			// mention user without pinging
 			s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
-				Content:         fmt.Sprintf("<@%s> ist jetzt ein Bot-Administrator!", ID),
+				Content:         guild.tr("settings.admins.added", ID),
 				AllowedMentions: &discordgo.MessageAllowedMentions{Users: nil},
 			})
 			isValid = true
@@ -251,7 +282,7 @@ func SettingAdminUserIDs(s *discordgo.Session, m *discordgo.MessageCreate, guild
 		return isValid
 	}
 
-	// remove the values from guild.PersistentGuildData.AdminUserIDs by creating a
+	// remove the values from guild.PGD().AdminUserIDs by creating a
 	// new array with only the admins the user didn't remove, and replacing the
 	// current array with that one
 	var newAdminList []string
@@ -259,13 +290,13 @@ func SettingAdminUserIDs(s *discordgo.Session, m *discordgo.MessageCreate, guild
 	nextIndexToRemove := removeAdmins[0]
 	currentIndexInRemoveAdmins := 0
 
-	for currentIndex < len(guild.PersistentGuildData.AdminUserIDs) {
+	for currentIndex < len(guild.PGD().AdminUserIDs) {
 		if currentIndex != nextIndexToRemove {
 			// user didn't remove this admin, add it to the list
-			newAdminList = append(newAdminList, guild.PersistentGuildData.AdminUserIDs[currentIndex])
+			newAdminList = append(newAdminList, guild.PGD().AdminUserIDs[currentIndex])
 		} else {
 			s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
-				Content:         fmt.Sprintf("<@%s> ist kein Bot-Administrator mehr, RIP", guild.PersistentGuildData.AdminUserIDs[currentIndex]),
+				Content:         guild.tr("settings.admins.removed", guild.PGD().AdminUserIDs[currentIndex]),
 				AllowedMentions: &discordgo.MessageAllowedMentions{Users: nil},
 			})
 			currentIndexInRemoveAdmins++
@@ -273,46 +304,43 @@ func SettingAdminUserIDs(s *discordgo.Session, m *discordgo.MessageCreate, guild
 				nextIndexToRemove = removeAdmins[currentIndexInRemoveAdmins]
 			} else {
 				// reached the end of removeAdmins
-				newAdminList = append(newAdminList, guild.PersistentGuildData.AdminUserIDs[currentIndex+1:]...)
+				newAdminList = append(newAdminList, guild.PGD().AdminUserIDs[currentIndex+1:]...)
 				break
 			}
 		}
 		currentIndex++
 	}
 
-	guild.PersistentGuildData.AdminUserIDs = newAdminList
+	guild.PGD().AdminUserIDs = newAdminList
 	return true
 }
 
-func SettingPermissionRoleIDs(s *discordgo.Session, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
+func SettingPermissionRoleIDs(s DiscordClient, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
 	if len(args) == 2 {
-		adminRoleCount := len(guild.PersistentGuildData.PermissionedRoleIDs) // caching for optimisation
+		adminRoleCount := len(guild.PGD().PermissionedRoleIDs) // caching for optimisation
 		// make a nicely formatted string of all the roles: "role1, role2, role3 and role4"
 		if adminRoleCount == 0 {
-			s.ChannelMessageSend(m.ChannelID, "`PermissionRoleIDs [role 1] [role 2] [etc]`: Hinzufügen oder Entfernen von Bot-Administratorrollen, also Rollen, die dem Bot Befehle geben können.\n"+
-				"Derzeit gibt es keine Bot-Administratorrollen.")
+			s.ChannelMessageSend(m.ChannelID, guild.settingUsage("roles")+"\n"+guild.tr("settings.roles.none"))
 		} else if adminRoleCount == 1 {
 			// mention role without pinging
 			s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
-				Content: "`PermissionRoleIDs [role 1] [role 2] [etc]`: Hinzufügen oder Entfernen von Bot-Administratorrollen, also Rollen, die dem Bot Befehle geben können.\n" +
-					fmt.Sprintf("Derzeit ist die einzige Administratorrolle <&%s>.", guild.PersistentGuildData.PermissionedRoleIDs[0]),
+				Content:         guild.settingUsage("roles") + "\n" + guild.tr("settings.roles.one", guild.PGD().PermissionedRoleIDs[0]),
 				AllowedMentions: &discordgo.MessageAllowedMentions{Roles: nil},
 			})
 		} else {
 			listOfRoles := ""
-			for index, ID := range guild.PersistentGuildData.PermissionedRoleIDs {
+			for index, ID := range guild.PGD().PermissionedRoleIDs {
 				if index == 0 {
-					listOfRoles += "<&" + ID + ">"
+					listOfRoles += "<@&" + ID + ">"
 				} else if index == adminRoleCount-1 {
-					listOfRoles += " and <&" + ID + ">"
+					listOfRoles += guild.tr("settings.and") + "<@&" + ID + ">"
 				} else {
-					listOfRoles += ", <&" + ID + ">"
+					listOfRoles += ", <@&" + ID + ">"
 				}
 			}
 			// mention roles without pinging
 			s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
-				Content: "`PermissionRoleIDs [role 1] [role 2] [etc]`: Hinzufügen oder Entfernen von Bot-Administratorrollen, also Rollen, die dem Bot Befehle geben können.\n" +
-					fmt.Sprintf("Derzeit sind die Administratorrollen %s.", listOfRoles),
+				Content:         guild.settingUsage("roles") + "\n" + guild.tr("settings.roles.many", listOfRoles),
 				AllowedMentions: &discordgo.MessageAllowedMentions{Roles: nil},
 			})
 		}
@@ -329,7 +357,7 @@ func SettingPermissionRoleIDs(s *discordgo.Session, m *discordgo.MessageCreate,
 		}
 		ID := getRoleFromString(s, m.GuildID, roleName)
 		if ID == "" {
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Sorry, Ich kenne die Rolle `%s` nicht. Du kannst mir die Rollen-ID, den Rollen-Namen geben oder die  @rolle erwähnen", roleName))
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.roles.notFound", roleName))
 			continue
 		}
 		// check if id is already in array
@@ -351,7 +379,7 @@ func SettingPermissionRoleIDs(s *discordgo.Session, m *discordgo.MessageCreate,
 
 	for _, ID := range roleIDs {
 		// can't use guild.HasRolePermissions() because we also need index
-		for index, adminRoleID := range guild.PersistentGuildData.PermissionedRoleIDs {
+		for index, adminRoleID := range guild.PGD().PermissionedRoleIDs {
 			if ID == adminRoleID {
 				// add ID to IDs to be deleted
 				removeRoles = append(removeRoles, index)
@@ -360,10 +388,10 @@ func SettingPermissionRoleIDs(s *discordgo.Session, m *discordgo.MessageCreate,
 			}
 		}
 		if ID != "" {
-			guild.PersistentGuildData.PermissionedRoleIDs = append(guild.PersistentGuildData.PermissionedRoleIDs, ID)
+			guild.PGD().PermissionedRoleIDs = append(guild.PGD().PermissionedRoleIDs, ID)
 			// mention user without pinging
 			s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
-				Content:         fmt.Sprintf("<@&%s>s sind jetzt Bot Admins!", ID),
+				Content:         guild.tr("settings.roles.added", ID),
 				AllowedMentions: &discordgo.MessageAllowedMentions{Users: nil},
 			})
 			isValid = true
@@ -380,13 +408,13 @@ func SettingPermissionRoleIDs(s *discordgo.Session, m *discordgo.MessageCreate,
 	nextIndexToRemove := removeRoles[0]
 	currentIndexInRemoveAdminRoles := 0
 
-	for currentIndex < len(guild.PersistentGuildData.PermissionedRoleIDs) {
+	for currentIndex < len(guild.PGD().PermissionedRoleIDs) {
 		if currentIndex != nextIndexToRemove {
 			// user didn't remove this role, add it to the list
-			newAdminRoleList = append(newAdminRoleList, guild.PersistentGuildData.PermissionedRoleIDs[currentIndex])
+			newAdminRoleList = append(newAdminRoleList, guild.PGD().PermissionedRoleIDs[currentIndex])
 		} else {
 			s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
-				Content:         fmt.Sprintf("<@&%s>s sind keine Bot-Admins mehr.", guild.PersistentGuildData.PermissionedRoleIDs[currentIndex]),
+				Content:         guild.tr("settings.roles.removed", guild.PGD().PermissionedRoleIDs[currentIndex]),
 				AllowedMentions: &discordgo.MessageAllowedMentions{Users: nil},
 			})
 			currentIndexInRemoveAdminRoles++
@@ -394,132 +422,126 @@ func SettingPermissionRoleIDs(s *discordgo.Session, m *discordgo.MessageCreate,
 				nextIndexToRemove = removeRoles[currentIndexInRemoveAdminRoles]
 			} else {
 				// reached the end of removeRoles
-				newAdminRoleList = append(newAdminRoleList, guild.PersistentGuildData.PermissionedRoleIDs[currentIndex+1:]...)
+				newAdminRoleList = append(newAdminRoleList, guild.PGD().PermissionedRoleIDs[currentIndex+1:]...)
 				break
 			}
 		}
 		currentIndex++
 	}
 
-	guild.PersistentGuildData.PermissionedRoleIDs = newAdminRoleList
+	guild.PGD().PermissionedRoleIDs = newAdminRoleList
 	return true
 }
 
-func SettingApplyNicknames(s *discordgo.Session, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
+func SettingApplyNicknames(s DiscordClient, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
 	if len(args) == 2 {
-		if guild.PersistentGuildData.ApplyNicknames {
-			s.ChannelMessageSend(m.ChannelID, "`ApplyNicknames [true/false]`: Ob der Bot die Spitznamen der Spieler ändern soll, um die Farbe des Spielers wiederzugeben.\n"+
-				"Derzeit ändert der Bot Spitznamen.")
+		if guild.PGD().ApplyNicknames {
+			s.ChannelMessageSend(m.ChannelID, guild.settingUsage("nicknames")+"\n"+guild.tr("settings.nicknames.currentlyOn"))
 		} else {
-			s.ChannelMessageSend(m.ChannelID, "`ApplyNicknames [true/false]`: Ob der Bot die Spitznamen der Spieler ändern soll, um die Farbe des Spielers wiederzugeben.\n"+
-				"Derzeit ändert der Bot die Spitznamen **nicht**.")
+			s.ChannelMessageSend(m.ChannelID, guild.settingUsage("nicknames")+"\n"+guild.tr("settings.nicknames.currentlyOff"))
 		}
 		return false
 	}
 	if args[2] == "true" {
-		if guild.PersistentGuildData.ApplyNicknames {
-			s.ChannelMessageSend(m.ChannelID, "Es ist bereits auf true gestellt")
+		if guild.PGD().ApplyNicknames {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.alreadyTrue"))
 		} else {
-			s.ChannelMessageSend(m.ChannelID, "Ich werde jetzt die Spieler im Voice-Chat umbenennen.")
-			guild.PersistentGuildData.ApplyNicknames = true
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.nicknames.enabled"))
+			guild.PGD().ApplyNicknames = true
 			return true
 		}
 	} else if args[2] == "false" {
-		if guild.PersistentGuildData.ApplyNicknames {
-			s.ChannelMessageSend(m.ChannelID, "Ich werde die Spieler im Voice-Chat nicht mehr umbenennen.")
-			guild.PersistentGuildData.ApplyNicknames = false
+		if guild.PGD().ApplyNicknames {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.nicknames.disabled"))
+			guild.PGD().ApplyNicknames = false
 			return true
 		} else {
-			s.ChannelMessageSend(m.ChannelID, "Es ist bereits auf false gestellt")
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.alreadyFalse"))
 		}
 	} else {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Sorry, `%s` ist weder `true` noch `false`.", args[2]))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.notTrueOrFalse", args[2]))
 	}
 	return false
 }
 
-func SettingUnmuteDeadDuringTasks(s *discordgo.Session, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
+func SettingUnmuteDeadDuringTasks(s DiscordClient, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
 	if len(args) == 2 {
-		if guild.PersistentGuildData.UnmuteDeadDuringTasks {
-			s.ChannelMessageSend(m.ChannelID, "`UnmuteDeadDuringTasks [true/false]`: Ob der Bot tote Spieler sofort stumm schalten soll, wenn sie sterben. "+
-				"**WARNUNG**: enthüllt, wer gestorben ist, bevor die Diskussion beginnt! Benutzung auf eigene Gefahr.\n"+
-				"Derzeit hebt der Bot die Stummschaltung der Spieler unmittelbar nach dem Tod auf.")
+		if guild.PGD().UnmuteDeadDuringTasks {
+			s.ChannelMessageSend(m.ChannelID, guild.settingUsage("unmuteDead")+"\n"+guild.tr("settings.unmuteDead.warning")+"\n"+guild.tr("settings.unmuteDead.currentlyOn"))
 		} else {
-			s.ChannelMessageSend(m.ChannelID, "`UnmuteDeadDuringTasks [true/false]`: Ob der Bot tote Spieler sofort stumm schalten soll, wenn sie sterben. "+
-				"**WARNUNG**: enthüllt, wer gestorben ist, bevor die Diskussion beginnt! Benutzung auf eigene Gefahr.\n"+
-				"Derzeit macht der Bot die Spieler **nicht** sofort nach dem Tod stumm.")
+			s.ChannelMessageSend(m.ChannelID, guild.settingUsage("unmuteDead")+"\n"+guild.tr("settings.unmuteDead.warning")+"\n"+guild.tr("settings.unmuteDead.currentlyOff"))
 		}
 		return false
 	}
 	if args[2] == "true" {
-		if guild.PersistentGuildData.UnmuteDeadDuringTasks {
-			s.ChannelMessageSend(m.ChannelID, "Es ist bereits auf true gestellt!")
+		if guild.PGD().UnmuteDeadDuringTasks {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.alreadyTrue"))
 		} else {
-			s.ChannelMessageSend(m.ChannelID, "Ich werde jetzt die Toten sofort nach ihrem Tod stumm schalten. Vorsicht, dies zeigt, wer während des Spiels gestorben ist!")
-			guild.PersistentGuildData.UnmuteDeadDuringTasks = true
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.unmuteDead.enabled"))
+			guild.PGD().UnmuteDeadDuringTasks = true
 			return true
 		}
 	} else if args[2] == "false" {
-		if guild.PersistentGuildData.UnmuteDeadDuringTasks {
-			s.ChannelMessageSend(m.ChannelID, "Ich werde nicht länger sofort die Stummschaltung von Toten aufheben. Gute Wahl!")
-			guild.PersistentGuildData.UnmuteDeadDuringTasks = false
+		if guild.PGD().UnmuteDeadDuringTasks {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.unmuteDead.disabled"))
+			guild.PGD().UnmuteDeadDuringTasks = false
 			return true
 		} else {
-			s.ChannelMessageSend(m.ChannelID, "Es ist bereits auf false gestellt!")
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.alreadyFalse"))
 		}
 	} else {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Sorry, `%s` ist weder `true` noch `false`.", args[2]))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.notTrueOrFalse", args[2]))
 	}
 	return false
 }
 
-func SettingDelays(s *discordgo.Session, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
+func SettingDelays(s DiscordClient, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
 	if len(args) == 2 {
-		s.ChannelMessageSend(m.ChannelID, "`Delays [old game phase] [new game phase] [delay]`: CÄndern Sie die Verzögerung zwischen dem Ändern der Spielphase und dem Stummschalten / Aufheben der Stummschaltung von Spielern.")
+		s.ChannelMessageSend(m.ChannelID, guild.settingUsage("delays"))
 		return false
 	}
 	// user passes phase name, phase name and new delay value
 	if len(args) < 4 {
 		// user didn't pass 2 phases, tell them the list of game phases
-		s.ChannelMessageSend(m.ChannelID, "Die Liste der Spielphasen ist `Lobby`, `Tasks` und `Discussion`.\n"+
-			"Du musst beide Phasen eingeben, von denen das Spiel wechselt, und die Verzögerung ändern.") // find a better wording for this at some point
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.delays.phases")) // find a better wording for this at some point
 		return false
 	}
 	// now to find the actual game state from the string they passed
-	var gamePhase1 = getPhaseFromString(args[2])
-	var gamePhase2 = getPhaseFromString(args[3])
+	aliases := guild.inputAliases()
+	var gamePhase1 = aliases.Phase(args[2])
+	var gamePhase2 = aliases.Phase(args[3])
 	if gamePhase1 == game.UNINITIALIZED {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Ich weiß nicht was `%s` ist. Die Liste der Spielphasen ist `Lobby`, `Tasks` und `Discussion`.", args[2]))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.unknownPhase", args[2]))
 		return false
 	} else if gamePhase2 == game.UNINITIALIZED {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Ich weiß nicht was `%s` ist. Die Liste der Spielphasen ist `Lobby`, `Tasks` und `Discussion`.", args[3]))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.unknownPhase", args[3]))
 		return false
 	}
-	oldDelay := guild.PersistentGuildData.Delays.GetDelay(gamePhase1, gamePhase2)
+	oldDelay := guild.PGD().Delays.GetDelay(gamePhase1, gamePhase2)
 	if len(args) == 4 {
 		// no number was passed, user was querying the delay
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Derzeit ist die Verzögerung beim Übergeben von `%s` zu `%s` ist %d.", args[2], args[3], oldDelay))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.delays.current", args[2], args[3], oldDelay))
 		return false
 	}
 	newDelay, err := strconv.Atoi(args[4])
 	if err != nil || newDelay < 0 {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("`%s` ist keine gültige Nummer! Bitte versuche es erneut", args[4]))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.delays.invalidNumber", args[4]))
 		return false
 	}
-	guild.PersistentGuildData.Delays.Delays[game.PhaseNames[gamePhase1]][game.PhaseNames[gamePhase2]] = newDelay
-	s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Die Verzögerung beim Übergeben von `%s` zu `%s` wurde gewechselt von %d zu %d.", args[2], args[3], oldDelay, newDelay))
+	guild.PGD().Delays.Delays[game.PhaseNames[gamePhase1]][game.PhaseNames[gamePhase2]] = newDelay
+	s.ChannelMessageSend(m.ChannelID, guild.tr("settings.delays.changed", args[2], args[3], oldDelay, newDelay))
 	return true
 }
 
-func SettingVoiceRules(s *discordgo.Session, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
+func SettingVoiceRules(s DiscordClient, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
 	if len(args) == 2 {
-		s.ChannelMessageSend(m.ChannelID, "`VoiceRules [mute/deaf] [game phase] [alive/dead] [true/false]`: Ob lebende / tote Spieler während dieser Spielphase stumm geschaltet / betäubt werden sollen.")
+		s.ChannelMessageSend(m.ChannelID, guild.settingUsage("voiceRules"))
 		return false
 	}
 	// now for a bunch of input checking
 	if len(args) < 5 {
 		// user didn't pass enough args
-		s.ChannelMessageSend(m.ChannelID, "Du hast nicht genug Argumente angegeben! Richtige Syntax ist: `VoiceRules [mute/deaf] [game phase] [alive/dead] [true/false]`")
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.voiceRules.notEnough"))
 		return false
 	}
 	if args[2] == "deaf" {
@@ -527,31 +549,28 @@ func SettingVoiceRules(s *discordgo.Session, m *discordgo.MessageCreate, guild *
 	} else if args[2] == "mute" {
 		args[2] = "muted" // same here
 	} else {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("`%s` ist weder `mute` noch `deaf`!", args[2]))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.voiceRules.notMuteOrDeaf", args[2]))
 		return false
 	}
-	gamePhase := getPhaseFromString(args[3])
+	gamePhase := guild.inputAliases().Phase(args[3])
 	if gamePhase == game.UNINITIALIZED {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Ich weiß nicht was %s ist. Die Liste der Spielphasen ist `Lobby`, `Tasks` und `Discussion`.", args[3]))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.unknownPhase", args[3]))
 		return false
 	}
 	if args[4] != "alive" && args[4] != "dead" {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("`%s` ist weder `alive` oder `dead`!", args[4]))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.voiceRules.notAliveOrDead", args[4]))
 		return false
 	}
 	var oldValue bool
 	if args[2] == "muted" {
-		oldValue = guild.PersistentGuildData.VoiceRules.MuteRules[game.PhaseNames[gamePhase]][args[4]]
+		oldValue = guild.PGD().VoiceRules.MuteRules[game.PhaseNames[gamePhase]][args[4]]
 	} else {
-		oldValue = guild.PersistentGuildData.VoiceRules.DeafRules[game.PhaseNames[gamePhase]][args[4]]
+		oldValue = guild.PGD().VoiceRules.DeafRules[game.PhaseNames[gamePhase]][args[4]]
 	}
+	players := guild.tr("settings.voiceRules." + args[4])
 	if len(args) == 5 {
 		// user was only querying
-		if oldValue {
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Wenn in `%s` Phase, dann sind %s derzeit %s.", args[3], args[4], args[2]))
-		} else {
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Wenn in `%s` Phase, dann sind %s Spieler NICHT %s.", args[3], args[4], args[2]))
-		}
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.voiceRules.current", args[3], players, guild.voiceRuleState(args[2], oldValue)))
 		return false
 	}
 	var newValue bool
@@ -560,26 +579,248 @@ func SettingVoiceRules(s *discordgo.Session, m *discordgo.MessageCreate, guild *
 	} else if args[5] == "false" {
 		newValue = false
 	} else {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("`%s` ist weder `true` oder `false`!", args[5]))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.notTrueOrFalse", args[5]))
 		return false
 	}
 	if newValue == oldValue {
-		if newValue {
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Wenn in `%s` Phase, dann sind %s Spieler bereits %s!", args[3], args[4], args[2]))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.voiceRules.already", args[3], players, guild.voiceRuleState(args[2], newValue)))
+		return false
+	}
+	if args[2] == "muted" {
+		guild.PGD().VoiceRules.MuteRules[game.PhaseNames[gamePhase]][args[4]] = newValue
+	} else {
+		guild.PGD().VoiceRules.DeafRules[game.PhaseNames[gamePhase]][args[4]] = newValue
+	}
+	s.ChannelMessageSend(m.ChannelID, guild.tr("settings.voiceRules.changed", args[3], players, guild.voiceRuleState(args[2], newValue)))
+	return true
+}
+
+// voiceRuleState names what happens to the players, e.g. muted or unmuted
+func (guild *GuildState) voiceRuleState(rule string, value bool) string {
+	if value {
+		return guild.tr("settings.voiceRules." + rule)
+	}
+	return guild.tr("settings.voiceRules.un" + rule)
+}
+
+func SettingSeasons(s DiscordClient, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
+	if len(args) == 2 {
+		if len(guild.PGD().Seasons) == 0 {
+			s.ChannelMessageSend(m.ChannelID, guild.settingUsage("seasons")+"\n"+guild.tr("settings.seasons.none"))
+			return false
+		}
+		list := ""
+		for _, v := range guild.PGD().Seasons {
+			list += guild.tr("settings.seasons.item", v.Name, v.Start, v.End) + "\n"
+		}
+		s.ChannelMessageSend(m.ChannelID, guild.settingUsage("seasons")+"\n"+guild.tr("settings.seasons.list")+"\n"+list)
+		return false
+	}
+	switch args[2] {
+	case "add":
+		if len(args) < 6 {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.seasons.notEnough"))
+			return false
+		}
+		if guild.findSeason(args[3]) != nil {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.seasons.exists", args[3]))
+			return false
+		}
+		season := Season{
+			Name:  args[3],
+			Start: args[4],
+			End:   args[5],
+		}
+		start, end, err := season.Bounds()
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.seasons.dateFormat"))
+			return false
+		}
+		if end.Before(start) {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.seasons.endBeforeStart"))
+			return false
+		}
+		for _, other := range guild.PGD().Seasons {
+			if season.Overlaps(&other) {
+				s.ChannelMessageSend(m.ChannelID, guild.tr("settings.seasons.overlaps", other.Name, other.Start, other.End))
+				return false
+			}
+		}
+		guild.PGD().Seasons = append(guild.PGD().Seasons, season)
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.seasons.added", season.Name, season.Start, season.End))
+		return true
+	case "remove":
+		if len(args) < 4 {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.seasons.removeWhich"))
+			return false
+		}
+		for i, v := range guild.PGD().Seasons {
+			if strings.ToLower(v.Name) == args[3] {
+				guild.PGD().Seasons = append(guild.PGD().Seasons[:i], guild.PGD().Seasons[i+1:]...)
+				s.ChannelMessageSend(m.ChannelID, guild.tr("settings.seasons.removed", v.Name))
+				return true
+			}
+		}
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.seasons.unknown", args[3]))
+		return false
+	default:
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.seasons.notAddOrRemove", args[2]))
+		return false
+	}
+}
+
+func SettingInputAliases(s DiscordClient, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
+	if len(args) == 2 {
+		list := ""
+		for _, kind := range game.AliasKinds {
+			words := guild.PGD().InputAliases[kind]
+			sorted := make([]string, 0, len(words))
+			for word := range words {
+				sorted = append(sorted, word)
+			}
+			sort.Strings(sorted)
+			for _, word := range sorted {
+				list += guild.tr("settings.inputAliases.item", word, kind, words[word]) + "\n"
+			}
+		}
+		if list == "" {
+			s.ChannelMessageSend(m.ChannelID, guild.settingUsage("inputAliases")+"\n"+guild.tr("settings.inputAliases.none"))
 		} else {
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Wenn in `%s` Phase, dann sind %s Spieler bereits un%s!", args[3], args[4], args[2]))
+			s.ChannelMessageSend(m.ChannelID, guild.settingUsage("inputAliases")+"\n"+guild.tr("settings.inputAliases.list")+"\n"+list)
 		}
 		return false
 	}
-	if args[2] == "muted" {
-		guild.PersistentGuildData.VoiceRules.MuteRules[game.PhaseNames[gamePhase]][args[4]] = newValue
+	if args[2] != "add" && args[2] != "remove" {
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.inputAliases.notAddOrRemove", args[2]))
+		return false
+	}
+	if len(args) < 5 || (args[2] == "add" && len(args) < 6) {
+		if args[2] == "add" {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.inputAliases.notEnough"))
+		} else {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.inputAliases.removeWhich"))
+		}
+		return false
+	}
+	kind := game.AliasKind(args[3])
+	if !game.IsAliasKind(kind) {
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.inputAliases.unknownKind", args[3]))
+		return false
+	}
+	word := args[4]
+
+	if args[2] == "remove" {
+		name, ok := guild.PGD().InputAliases[kind][word]
+		if !ok {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.inputAliases.unknown", word))
+			return false
+		}
+		delete(guild.PGD().InputAliases[kind], word)
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.inputAliases.removed", word))
+		guild.logger().Infof("Eigenes Wort %s (%s) für %s entfernt", word, kind, name)
+		return true
+	}
+
+	if name, ok := game.DefaultAliases().Resolve(kind, word); ok {
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.inputAliases.builtin", word, name))
+		return false
+	}
+	//the meaning can be an alias itself, e.g. `rot` or `eu`
+	target := strings.Join(args[5:], " ")
+	name, ok := guild.inputAliases().Resolve(kind, target)
+	if !ok {
+		name, ok = game.CanonicalName(kind, target)
+	}
+	if !ok {
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.inputAliases.unknownTarget", target, strings.Join(game.CanonicalNames(kind), ", ")))
+		return false
+	}
+	if guild.PGD().InputAliases == nil {
+		guild.PGD().InputAliases = make(map[game.AliasKind]map[string]string)
+	}
+	if guild.PGD().InputAliases[kind] == nil {
+		guild.PGD().InputAliases[kind] = make(map[string]string)
+	}
+	guild.PGD().InputAliases[kind][word] = name
+	s.ChannelMessageSend(m.ChannelID, guild.tr("settings.inputAliases.added", word, name))
+	return true
+}
+
+func SettingAutoBump(s DiscordClient, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
+	if len(args) == 2 {
+		current := guild.tr("settings.autoBump.offValue")
+		if guild.PGD().AutoBump > 0 {
+			current = guild.tr("settings.autoBump.after", guild.PGD().AutoBump)
+		}
+		s.ChannelMessageSend(m.ChannelID, guild.settingUsage("autoBump")+"\n"+guild.tr("settings.autoBump.current", current))
+		return false
+	}
+	newValue := 0
+	if args[2] != "off" && args[2] != "aus" {
+		var err error
+		newValue, err = strconv.Atoi(args[2])
+		if err != nil || newValue < 0 || newValue > MaxAutoBump {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.autoBump.invalidNumber", args[2], MaxAutoBump))
+			return false
+		}
+	}
+	guild.PGD().AutoBump = newValue
+	if newValue == 0 {
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.autoBump.disabled"))
 	} else {
-		guild.PersistentGuildData.VoiceRules.DeafRules[game.PhaseNames[gamePhase]][args[4]] = newValue
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.autoBump.changed", newValue))
 	}
-	if newValue {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Von nun an, wenn in `%s` Phase, %s Spieler werden %s sein.", args[3], args[4], args[2]))
+	return true
+}
+
+func SettingEditWindow(s DiscordClient, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
+	defaultMs := int(DefaultEditWindow / time.Millisecond)
+	if len(args) == 2 {
+		current := guild.tr("settings.editWindow.defaultValue", defaultMs)
+		if guild.PGD().EditWindow > 0 {
+			current = guild.tr("settings.editWindow.ms", guild.PGD().EditWindow)
+		}
+		text := guild.settingUsage("editWindow") + "\n" + guild.tr("settings.editWindow.current", current)
+		if guild.GameStateMsg.Widened() {
+			text += "\n" + guild.tr("settings.editWindow.widened", int(guild.GameStateMsg.EditWindow()/time.Millisecond))
+		}
+		s.ChannelMessageSend(m.ChannelID, text)
+		return false
+	}
+	newValue := 0
+	if args[2] != "default" && args[2] != "standard" {
+		minMs, maxMs := int(MinEditWindow/time.Millisecond), int(MaxEditWindow/time.Millisecond)
+		var err error
+		newValue, err = strconv.Atoi(args[2])
+		if err != nil || newValue < minMs || newValue > maxMs {
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.editWindow.invalidNumber", args[2], minMs, maxMs))
+			return false
+		}
+	}
+	guild.PGD().EditWindow = newValue
+	if newValue == 0 {
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.editWindow.reset", defaultMs))
 	} else {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Von nun an, wenn in `%s` Phase, %s Spieler werden un%s sein.", args[3], args[4], args[2]))
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.editWindow.changed", newValue))
+	}
+	return true
+}
+
+func SettingLanguage(s DiscordClient, m *discordgo.MessageCreate, guild *GuildState, args []string) bool {
+	if len(args) == 2 {
+		lang := guild.PGD().Language
+		if lang == "" {
+			lang = locale.DefaultLanguage
+		}
+		s.ChannelMessageSend(m.ChannelID, guild.settingUsage("language")+"\n"+guild.tr("settings.language.current", guild.tr("language.name"), lang))
+		return false
+	}
+	if !locale.IsSupported(args[2]) {
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.language.unknown", args[2], strings.Join(locale.Languages(), ", ")))
+		return false
 	}
+	guild.PGD().Language = args[2]
+	//already in the new language, so whoever changed it sees that it worked
+	s.ChannelMessageSend(m.ChannelID, guild.tr("settings.language.changed", guild.tr("language.name")))
 	return true
 }
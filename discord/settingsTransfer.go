@@ -0,0 +1,459 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/locale"
+)
+
+// MaxSettingsFileSize is the largest settings attachment we're willing to download
+const MaxSettingsFileSize = 64 * 1024
+
+// SettingsDownloadTimeout is how long downloading a settings attachment may take
+const SettingsDownloadTimeout = 10 * time.Second
+
+// MaxMessageLength is the most characters Discord takes in one message
+const MaxMessageLength = 2000
+
+var settingsHTTPClient = &http.Client{Timeout: SettingsDownloadTimeout}
+
+// SettingsImportTimeout is how long an uploaded settings file waits for confirmation before it's discarded
+const SettingsImportTimeout = 5 * time.Minute
+
+// SettingsImport is an uploaded settings file that's been validated, and is waiting for an admin to confirm it
+type SettingsImport struct {
+	authorID string
+	created  time.Time
+	data     map[string]interface{}
+}
+
+func (guild *GuildState) settingsExport(s DiscordClient, m *discordgo.MessageCreate) {
+	jsonBytes, err := json.MarshalIndent(guild.PGD(), "", "    ")
+	if err != nil {
+		guild.logger().Error(err)
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.export.failed"))
+		return
+	}
+	_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content: guild.tr("settings.export.done", guild.PGD().CommandPrefix),
+		Files: []*discordgo.File{
+			{
+				Name:        guild.PGD().GuildID + "_settings.json",
+				ContentType: "application/json",
+				Reader:      bytes.NewReader(jsonBytes),
+			},
+		},
+	})
+	if err != nil {
+		guild.logger().Error(err)
+	}
+}
+
+// settingsImport handles `settings import [confirm/cancel]`, and returns true if new settings were applied
+func (guild *GuildState) settingsImport(s DiscordClient, m *discordgo.MessageCreate, args []string) bool {
+	if len(args) > 2 {
+		switch args[2] {
+		case "confirm":
+			fallthrough
+		case "yes":
+			//taken in one step, so the import can only be applied once, and not after it was cancelled
+			pending, taken := guild.takePendingImport(m.Author.ID)
+			if pending == nil {
+				s.ChannelMessageSend(m.ChannelID, guild.tr("settings.import.nothingPending"))
+				return false
+			}
+			if !taken {
+				s.ChannelMessageSend(m.ChannelID, guild.tr("settings.import.notAuthor"))
+				return false
+			}
+			pgd, err := guild.PGD().mergeSettings(pending.data)
+			if err != nil {
+				s.ChannelMessageSend(m.ChannelID, guild.tr("settings.import.failed", locale.ErrorText(guild.PGD().Language, err)))
+				return false
+			}
+			guild.SetPGD(pgd)
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.import.applied"))
+			return true
+		case "cancel":
+			fallthrough
+		case "no":
+			if pending, _ := guild.takePendingImport(""); pending == nil {
+				s.ChannelMessageSend(m.ChannelID, guild.tr("settings.import.nothingPending"))
+				return false
+			}
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.import.cancelled"))
+			return false
+		default:
+			s.ChannelMessageSend(m.ChannelID, guild.tr("settings.import.notConfirmOrCancel", args[2]))
+			return false
+		}
+	}
+
+	if len(m.Attachments) == 0 {
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.import.attach"))
+		return false
+	}
+
+	data, err := downloadSettingsAttachment(m.Attachments[0])
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.import.unreadable", locale.ErrorText(guild.PGD().Language, err)))
+		return false
+	}
+	newPgd, err := guild.PGD().mergeSettings(data)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.import.invalid", locale.ErrorText(guild.PGD().Language, err)))
+		return false
+	}
+
+	diff, err := settingsDiff(guild.PGD(), newPgd)
+	if err != nil {
+		guild.logger().Error(err)
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.import.compareFailed"))
+		return false
+	}
+	if len(diff) == 0 {
+		s.ChannelMessageSend(m.ChannelID, guild.tr("settings.import.unchanged"))
+		return false
+	}
+
+	guild.setPendingImport(&SettingsImport{
+		authorID: m.Author.ID,
+		created:  time.Now(),
+		data:     data,
+	})
+
+	guild.sendSettingsDiff(s, m.ChannelID, diff)
+	return false
+}
+
+// setPendingImport replaces the import that waits for confirmation
+func (guild *GuildState) setPendingImport(pending *SettingsImport) {
+	guild.pendingImportLock.Lock()
+	defer guild.pendingImportLock.Unlock()
+	guild.pendingSettingsImport = pending
+}
+
+// takePendingImport returns the import that waits for confirmation, or nil if there is none or it expired. It's
+// cleared and true is returned if the user is the one who uploaded it; an empty userID clears it for anyone
+func (guild *GuildState) takePendingImport(userID string) (*SettingsImport, bool) {
+	guild.pendingImportLock.Lock()
+	defer guild.pendingImportLock.Unlock()
+	pending := guild.pendingSettingsImport
+	if pending == nil || time.Since(pending.created) > SettingsImportTimeout {
+		guild.pendingSettingsImport = nil
+		return nil, false
+	}
+	if userID != "" && pending.authorID != userID {
+		return pending, false
+	}
+	guild.pendingSettingsImport = nil
+	return pending, true
+}
+
+// sendSettingsDiff shows the changes an import would make. If they don't fit in a message, they're attached as a file
+func (guild *GuildState) sendSettingsDiff(s DiscordClient, channelID string, diff []string) {
+	diffText := strings.Join(diff, "\n")
+	confirm := guild.tr("settings.import.confirm", guild.PGD().CommandPrefix)
+	content := guild.tr("settings.import.diff") + "\n```diff\n" + diffText + "\n```" + confirm
+	if len([]rune(content)) <= MaxMessageLength {
+		if _, err := s.ChannelMessageSend(channelID, content); err != nil {
+			guild.logger().Error(err)
+		}
+		return
+	}
+	_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: guild.tr("settings.import.diffAttached") + "\n" + confirm,
+		Files: []*discordgo.File{
+			{
+				Name:        "settings.diff",
+				ContentType: "text/plain",
+				Reader:      strings.NewReader(diffText + "\n"),
+			},
+		},
+	})
+	if err != nil {
+		guild.logger().Error(err)
+	}
+}
+
+func downloadSettingsAttachment(attachment *discordgo.MessageAttachment) (map[string]interface{}, error) {
+	if attachment.Size > MaxSettingsFileSize {
+		return nil, locale.Errorf("settings.import.tooLarge", attachment.Size, MaxSettingsFileSize)
+	}
+	return downloadSettings(attachment.URL)
+}
+
+func downloadSettings(url string) (map[string]interface{}, error) {
+	response, err := settingsHTTPClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, locale.Errorf("settings.import.httpStatus", response.Status)
+	}
+	if response.ContentLength > MaxSettingsFileSize {
+		return nil, locale.Errorf("settings.import.tooLarge", response.ContentLength, MaxSettingsFileSize)
+	}
+	//the size in the attachment is only what Discord claims, so read one byte more to notice a larger file
+	jsonBytes, err := ioutil.ReadAll(io.LimitReader(response.Body, MaxSettingsFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(jsonBytes) > MaxSettingsFileSize {
+		return nil, locale.Errorf("settings.import.tooLarge", len(jsonBytes), MaxSettingsFileSize)
+	}
+	var data map[string]interface{}
+	err = json.Unmarshal(jsonBytes, &data)
+	if err != nil {
+		return nil, locale.Errorf("settings.import.notJSON", err)
+	}
+	return data, nil
+}
+
+// mergeSettings applies a (possibly partial) settings document on top of the current settings, and validates the result.
+// The receiver is left unchanged
+func (pgd *PersistentGuildData) mergeSettings(data map[string]interface{}) (*PersistentGuildData, error) {
+	current, err := pgd.ToData()
+	if err != nil {
+		return nil, err
+	}
+	merged := mergeMaps(current, data)
+	//settings always belong to the guild they're imported into
+	merged["guildID"] = pgd.GuildID
+
+	jsonBytes, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.DisallowUnknownFields()
+	newPgd := PersistentGuildData{}
+	err = decoder.Decode(&newPgd)
+	if err != nil {
+		return nil, err
+	}
+	err = newPgd.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &newPgd, nil
+}
+
+// Validate checks the settings for values the bot can't work with
+func (pgd *PersistentGuildData) Validate() error {
+	if pgd.CommandPrefix == "" {
+		return locale.Errorf("settings.validate.emptyPrefix")
+	}
+	if len(pgd.CommandPrefix) > 10 {
+		return locale.Errorf("settings.validate.longPrefix", pgd.CommandPrefix)
+	}
+	//guilds from before there were languages have none
+	if pgd.Language != "" && !locale.IsSupported(pgd.Language) {
+		return locale.Errorf("settings.validate.language", pgd.Language)
+	}
+	for _, id := range pgd.AdminUserIDs {
+		if !isSnowflake(id) {
+			return locale.Errorf("settings.validate.adminID", id)
+		}
+	}
+	for _, id := range pgd.PermissionedRoleIDs {
+		if !isSnowflake(id) {
+			return locale.Errorf("settings.validate.roleID", id)
+		}
+	}
+	for _, id := range pgd.MirrorChannelIDs {
+		if !isSnowflake(id) {
+			return locale.Errorf("settings.validate.mirrorID", id)
+		}
+	}
+	if pgd.AutoBump < 0 || pgd.AutoBump > MaxAutoBump {
+		return locale.Errorf("settings.validate.autoBump", pgd.AutoBump, MaxAutoBump)
+	}
+	if pgd.EditWindow != 0 && (pgd.EditWindow < int(MinEditWindow/time.Millisecond) || pgd.EditWindow > int(MaxEditWindow/time.Millisecond)) {
+		return locale.Errorf("settings.validate.editWindow", pgd.EditWindow, int(MinEditWindow/time.Millisecond), int(MaxEditWindow/time.Millisecond))
+	}
+	seasonNames := map[string]bool{}
+	for i, season := range pgd.Seasons {
+		if season.Name == "" {
+			return locale.Errorf("settings.validate.seasonName")
+		}
+		if seasonNames[strings.ToLower(season.Name)] {
+			return locale.Errorf("settings.validate.seasonTwice", season.Name)
+		}
+		seasonNames[strings.ToLower(season.Name)] = true
+		start, end, err := season.Bounds()
+		if err != nil {
+			return locale.Errorf("settings.validate.seasonDates", season.Name)
+		}
+		if end.Before(start) {
+			return locale.Errorf("settings.validate.seasonEnd", season.Name)
+		}
+		for _, other := range pgd.Seasons[:i] {
+			if season.Overlaps(&other) {
+				return locale.Errorf("settings.validate.seasonOverlap", season.Name, other.Name)
+			}
+		}
+	}
+	for origin, dests := range pgd.Delays.Delays {
+		if !isPhaseName(origin) {
+			return locale.Errorf("settings.validate.delayPhase", origin)
+		}
+		for dest, delay := range dests {
+			if !isPhaseName(dest) {
+				return locale.Errorf("settings.validate.delayDestPhase", origin, dest)
+			}
+			if delay < 0 {
+				return locale.Errorf("settings.validate.negativeDelay", origin, dest)
+			}
+		}
+	}
+	for name, rules := range map[string]map[game.PhaseNameString]map[string]bool{
+		"MuteRules": pgd.VoiceRules.MuteRules,
+		"DeafRules": pgd.VoiceRules.DeafRules,
+	} {
+		for phase, states := range rules {
+			if !isPhaseName(phase) {
+				return locale.Errorf("settings.validate.rulePhase", name, phase)
+			}
+			for state := range states {
+				if state != "alive" && state != "dead" {
+					return locale.Errorf("settings.validate.ruleState", name, phase, state)
+				}
+			}
+		}
+	}
+	for kind, words := range pgd.InputAliases {
+		if !game.IsAliasKind(kind) {
+			return locale.Errorf("settings.validate.aliasKind", kind)
+		}
+		for word, name := range words {
+			if strings.TrimSpace(word) == "" {
+				return locale.Errorf("settings.validate.aliasWord", kind)
+			}
+			if canonical, ok := game.CanonicalName(kind, name); !ok || canonical != name {
+				return locale.Errorf("settings.validate.aliasTarget", kind, word, name)
+			}
+		}
+	}
+	return nil
+}
+
+func isPhaseName(name game.PhaseNameString) bool {
+	for phase, v := range game.PhaseNames {
+		if v == name && phase != game.MENU {
+			return true
+		}
+	}
+	return false
+}
+
+func isSnowflake(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeMaps returns a copy of base with overlay applied on top. Nested objects are merged key-by-key,
+// so a partial document only needs to contain the values that should change
+func mergeMaps(base, overlay map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overlay {
+		baseChild, baseIsMap := merged[k].(map[string]interface{})
+		overlayChild, overlayIsMap := v.(map[string]interface{})
+		if baseIsMap && overlayIsMap {
+			merged[k] = mergeMaps(baseChild, overlayChild)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// settingsDiff lists every setting that differs between the two, formatted for a ```diff code block
+func settingsDiff(oldPgd, newPgd *PersistentGuildData) ([]string, error) {
+	oldData, err := oldPgd.ToData()
+	if err != nil {
+		return nil, err
+	}
+	newData, err := newPgd.ToData()
+	if err != nil {
+		return nil, err
+	}
+	oldFlat := map[string]interface{}{}
+	newFlat := map[string]interface{}{}
+	flattenMap("", oldData, oldFlat)
+	flattenMap("", newData, newFlat)
+
+	keys := make([]string, 0)
+	for k := range oldFlat {
+		keys = append(keys, k)
+	}
+	for k := range newFlat {
+		if _, ok := oldFlat[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0)
+	for _, k := range keys {
+		oldV, hadOld := oldFlat[k]
+		newV, hasNew := newFlat[k]
+		if hadOld && hasNew && reflect.DeepEqual(oldV, newV) {
+			continue
+		}
+		if hadOld {
+			lines = append(lines, fmt.Sprintf("- %s: %s", k, formatSettingValue(oldV)))
+		}
+		if hasNew {
+			lines = append(lines, fmt.Sprintf("+ %s: %s", k, formatSettingValue(newV)))
+		}
+	}
+	return lines, nil
+}
+
+func flattenMap(prefix string, data map[string]interface{}, out map[string]interface{}) {
+	for k, v := range data {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if child, ok := v.(map[string]interface{}); ok {
+			flattenMap(key, child, out)
+		} else {
+			out[key] = v
+		}
+	}
+}
+
+func formatSettingValue(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return strings.TrimSpace(string(jsonBytes))
+}
@@ -0,0 +1,202 @@
+package discord
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/locale"
+)
+
+func errorKey(err error) string {
+	if e, ok := err.(*locale.Error); ok {
+		return e.Key
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func TestMergeSettings(t *testing.T) {
+	pgd := PGDDefault("1")
+	merged, err := pgd.mergeSettings(map[string]interface{}{
+		"guildID":       "2",
+		"commandPrefix": "!au",
+		"delays": map[string]interface{}{
+			"delays": map[string]interface{}{
+				"LOBBY": map[string]interface{}{"TASKS": 3},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.CommandPrefix != "!au" {
+		t.Errorf("Präfix ist %q, erwartet !au", merged.CommandPrefix)
+	}
+	if merged.GuildID != "1" {
+		t.Errorf("die Einstellungen gehören zur Gilde, in die importiert wird, nicht zu %s", merged.GuildID)
+	}
+	if delay := merged.Delays.GetDelay(game.LOBBY, game.TASKS); delay != 3 {
+		t.Errorf("Verzögerung LOBBY -> TASKS ist %d, erwartet 3", delay)
+	}
+	//only the one delay was in the file
+	if delay := merged.Delays.GetDelay(game.TASKS, game.DISCUSS); delay != pgd.Delays.GetDelay(game.TASKS, game.DISCUSS) {
+		t.Errorf("Verzögerung TASKS -> DISCUSS hat sich auf %d geändert", delay)
+	}
+	if pgd.CommandPrefix != ".au" {
+		t.Error("die bisherigen Einstellungen dürfen sich nicht ändern")
+	}
+
+	if _, err := pgd.mergeSettings(map[string]interface{}{"gibtsNicht": true}); err == nil {
+		t.Error("unbekannte Einstellungen müssen abgelehnt werden")
+	}
+	if _, err := pgd.mergeSettings(map[string]interface{}{"commandPrefix": ""}); errorKey(err) != "settings.validate.emptyPrefix" {
+		t.Errorf("ungültige Einstellungen müssen abgelehnt werden, bekommen %v", err)
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(pgd *PersistentGuildData)
+		key    string
+	}{
+		{"Standard", func(pgd *PersistentGuildData) {}, ""},
+		{"leeres Präfix", func(pgd *PersistentGuildData) { pgd.CommandPrefix = "" }, "settings.validate.emptyPrefix"},
+		{"langes Präfix", func(pgd *PersistentGuildData) { pgd.CommandPrefix = "sehrlangespräfix" }, "settings.validate.longPrefix"},
+		{"Sprache", func(pgd *PersistentGuildData) { pgd.Language = "xx" }, "settings.validate.language"},
+		{"Admin", func(pgd *PersistentGuildData) { pgd.AdminUserIDs = []string{"alice"} }, "settings.validate.adminID"},
+		{"Spiegel", func(pgd *PersistentGuildData) { pgd.MirrorChannelIDs = []string{""} }, "settings.validate.mirrorID"},
+		{"autoBump", func(pgd *PersistentGuildData) { pgd.AutoBump = -1 }, "settings.validate.autoBump"},
+		{"Season ohne Namen", func(pgd *PersistentGuildData) { pgd.Seasons = []Season{{Start: "2021-01-01", End: "2021-02-01"}} }, "settings.validate.seasonName"},
+		{"Season rückwärts", func(pgd *PersistentGuildData) {
+			pgd.Seasons = []Season{{Name: "s1", Start: "2021-02-01", End: "2021-01-01"}}
+		}, "settings.validate.seasonEnd"},
+		{"Seasons überschneiden sich", func(pgd *PersistentGuildData) {
+			pgd.Seasons = []Season{{Name: "s1", Start: "2021-01-01", End: "2021-02-01"}, {Name: "s2", Start: "2021-02-01", End: "2021-03-01"}}
+		}, "settings.validate.seasonOverlap"},
+		{"Phase", func(pgd *PersistentGuildData) { pgd.Delays.Delays["MENU"] = map[game.PhaseNameString]int{} }, "settings.validate.delayPhase"},
+		{"negative Verzögerung", func(pgd *PersistentGuildData) { pgd.Delays.Delays["LOBBY"]["TASKS"] = -1 }, "settings.validate.negativeDelay"},
+		{"Regel", func(pgd *PersistentGuildData) { pgd.VoiceRules.MuteRules["TASKS"]["untot"] = true }, "settings.validate.ruleState"},
+	}
+	for _, test := range tests {
+		pgd := PGDDefault("1")
+		test.change(pgd)
+		if key := errorKey(pgd.Validate()); key != test.key {
+			t.Errorf("%s: Fehler %q, erwartet %q", test.name, key, test.key)
+		}
+	}
+}
+
+func TestSettingsDiff(t *testing.T) {
+	oldPgd := PGDDefault("1")
+	newPgd := PGDDefault("1")
+	newPgd.CommandPrefix = "!au"
+	newPgd.MirrorChannelIDs = []string{"5"}
+	newPgd.Delays.Delays["LOBBY"]["TASKS"] = 3
+
+	diff, err := settingsDiff(oldPgd, newPgd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`- commandPrefix: ".au"`,
+		`+ commandPrefix: "!au"`,
+		`- delays.delays.LOBBY.TASKS: ` + formatSettingValue(oldPgd.Delays.Delays["LOBBY"]["TASKS"]),
+		`+ delays.delays.LOBBY.TASKS: 3`,
+		`- mirrorChannelIDs: null`,
+		`+ mirrorChannelIDs: ["5"]`,
+	}
+	if strings.Join(diff, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unterschiede:\n%s\nerwartet:\n%s", strings.Join(diff, "\n"), strings.Join(expected, "\n"))
+	}
+
+	if diff, _ := settingsDiff(oldPgd, PGDDefault("1")); len(diff) != 0 {
+		t.Errorf("gleiche Einstellungen haben keine Unterschiede: %v", diff)
+	}
+}
+
+func TestSendSettingsDiff(t *testing.T) {
+	fake := NewFakeDiscord("99")
+	guild := &GuildState{persistentGuildData: PGDDefault("1")}
+
+	guild.sendSettingsDiff(fake, "3", []string{"+ commandPrefix: \"!au\""})
+	long := make([]string, 0)
+	for i := 0; i < 100; i++ {
+		long = append(long, fmt.Sprintf("+ seasons.%d.name: \"Season %d\"", i, i))
+	}
+	guild.sendSettingsDiff(fake, "3", long)
+
+	messages := fake.Messages("3")
+	if len(messages) != 2 {
+		t.Fatalf("erwartet 2 Nachrichten, bekommen %d", len(messages))
+	}
+	if !strings.Contains(messages[0].Content, "!au") || len(messages[0].Attachments) > 0 {
+		t.Errorf("kurze Unterschiede gehören in die Nachricht: %+v", messages[0])
+	}
+	if len([]rune(messages[1].Content)) > MaxMessageLength || len(messages[1].Attachments) != 1 {
+		t.Errorf("lange Unterschiede gehören in eine Datei: %d Zeichen, %d Anhänge", len([]rune(messages[1].Content)), len(messages[1].Attachments))
+	}
+}
+
+func TestTakePendingImport(t *testing.T) {
+	guild := &GuildState{persistentGuildData: PGDDefault("1")}
+	guild.setPendingImport(&SettingsImport{authorID: "2", created: time.Now()})
+
+	if pending, taken := guild.takePendingImport("3"); pending == nil || taken {
+		t.Error("nur wer die Datei hochgeladen hat, darf sie bestätigen")
+	}
+
+	//a confirm and a cancel at once: only one of them gets the import
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	takers := 0
+	for _, userID := range []string{"2", "", "2"} {
+		wg.Add(1)
+		go func(userID string) {
+			defer wg.Done()
+			if _, taken := guild.takePendingImport(userID); taken {
+				lock.Lock()
+				takers++
+				lock.Unlock()
+			}
+		}(userID)
+	}
+	wg.Wait()
+	if takers != 1 {
+		t.Errorf("der Import darf nur einmal genommen werden, nicht %d-mal", takers)
+	}
+
+	guild.setPendingImport(&SettingsImport{authorID: "2", created: time.Now().Add(-SettingsImportTimeout - time.Second)})
+	if pending, _ := guild.takePendingImport("2"); pending != nil {
+		t.Error("ein abgelaufener Import kann nicht mehr bestätigt werden")
+	}
+}
+
+func TestDownloadSettingsLimitsSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gross" {
+			//no Content-Length, so only reading tells how large it is
+			w.(http.Flusher).Flush()
+			w.Write([]byte(`{"commandPrefix": "` + strings.Repeat("a", MaxSettingsFileSize) + `"}`))
+			return
+		}
+		w.Write([]byte(`{"commandPrefix": "!au"}`))
+	}))
+	defer server.Close()
+
+	data, err := downloadSettings(server.URL + "/klein")
+	if err != nil || data["commandPrefix"] != "!au" {
+		t.Errorf("die Datei sollte gelesen werden: %v %v", data, err)
+	}
+	if _, err := downloadSettings(server.URL + "/gross"); errorKey(err) != "settings.import.tooLarge" {
+		t.Errorf("eine zu große Datei muss abgelehnt werden, bekommen %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package discord
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// ShutdownConcurrency is how many unmute/nickname requests are in flight at once while shutting down,
+// so a big shard doesn't run straight into Discord's rate limits
+const ShutdownConcurrency = 5
+
+type restoreJob struct {
+	guild *GuildState
+	//what to issue the request with, chosen round-robin from the sessions
+	session DiscordClient
+	userID  string
+	unmute  bool
+	//the mute and deafen the user keeps if they aren't unmuted
+	mute bool
+	deaf bool
+	//nil if the nickname should stay as it is
+	nick *string
+}
+
+func (bot *Bot) isShuttingDown() bool {
+	return atomic.LoadInt32(&bot.shuttingDown) == 1
+}
+
+// Shutdown stops taking capture events, tells every game channel, lifts the mutes and nicknames the bot
+// applied and writes everything to storage. It gives up on whatever is left once ctx is done
+func (bot *Bot) Shutdown(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&bot.shuttingDown, 0, 1) {
+		return nil
+	}
+	bot.logger().Info("Fahre herunter, nehme keine Ereignisse von der Erfassung mehr an")
+	s := bot.SessionManager.GetPrimarySession()
+
+	for _, guild := range bot.AllGuilds.All() {
+		if ctx.Err() != nil {
+			bot.logger().Warn("Frist beim Herunterfahren abgelaufen, nicht alle Spiele wurden benachrichtigt")
+			break
+		}
+		if channelID := guild.GameStateMsg.ChannelID(); channelID != "" {
+			sendMessage(s, channelID, guild.tr("shutdown.now"))
+		}
+	}
+
+	jobs := make([]restoreJob, 0)
+	for _, guild := range bot.AllGuilds.All() {
+		jobs = append(jobs, bot.restoreJobs(guild)...)
+	}
+	restored := bot.runRestoreJobs(ctx, jobs)
+	bot.logger().Infof("%d von %d Benutzern beim Herunterfahren wiederhergestellt", restored, len(jobs))
+
+	for _, guild := range bot.AllGuilds.All() {
+		if ctx.Err() != nil {
+			break
+		}
+		bot.flushGuild(guild)
+	}
+	return ctx.Err()
+}
+
+// restoreJobs finds the linked players in the game's voice channels that the bot muted, deafened or renamed.
+// Spectators and anyone whose mute isn't the one the bot set (e.g. a moderator muted them) are left alone
+func (bot *Bot) restoreJobs(guild *GuildState) []restoreJob {
+	jobs := make([]restoreJob, 0)
+	if !guild.GameStateMsg.Exists() {
+		return jobs
+	}
+	s := bot.SessionManager.GetPrimarySession()
+	g, err := s.CachedGuild(guild.PGD().GuildID)
+	if err != nil {
+		guild.logger().Error(err)
+		return jobs
+	}
+
+	for _, voiceState := range g.VoiceStates {
+		userData, err := guild.UserData.GetUser(voiceState.UserID)
+		if err != nil || !userData.IsLinked() || !guild.Tracking.IsTracked(voiceState.ChannelID) {
+			//we never touched anyone who doesn't play
+			continue
+		}
+		applied, ok := guild.UserData.Applied(voiceState.UserID)
+		job := restoreJob{
+			guild:  guild,
+			userID: voiceState.UserID,
+			unmute: ok && (applied.Mute || applied.Deaf) && applied.Mute == voiceState.Mute && applied.Deaf == voiceState.Deaf,
+		}
+		if !job.unmute {
+			job.mute, job.deaf = voiceState.Mute, voiceState.Deaf
+		}
+		if guild.PGD().ApplyNicknames && userData.GetPlayerName() != "" && userData.GetOriginalNickName() != userData.GetPlayerName() {
+			member, err := s.CachedMember(g.ID, voiceState.UserID)
+			if err == nil && member.Nick == userData.GetPlayerName() {
+				nick := userData.GetOriginalNickName()
+				job.nick = &nick
+			}
+		}
+		if job.unmute || job.nick != nil {
+			job.session = bot.SessionManager.GetSessionForRequest()
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+// runRestoreJobs works through the jobs with at most ShutdownConcurrency at once, and returns how many went through
+func (bot *Bot) runRestoreJobs(ctx context.Context, jobs []restoreJob) int {
+	queue := make(chan restoreJob)
+	restored := int32(0)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < ShutdownConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				err := restoreMember(job.session, job.guild.PGD().GuildID, job.userID, job.mute, job.deaf, job.nick)
+				outcome := MemberUpdateSuccess
+				if err != nil {
+					job.guild.logger().Errorf("Benutzer %s konnte nicht wiederhergestellt werden: %s", job.userID, err)
+					outcome = MemberUpdateFailed
+				} else {
+					atomic.AddInt32(&restored, 1)
+				}
+				memberUpdates.WithLabelValues(outcome, bot.SessionManager.sessionLabel(job.session)).Inc()
+			}
+		}()
+	}
+
+dispatch:
+	for _, job := range jobs {
+		select {
+		case <-ctx.Done():
+			bot.logger().Warn("Frist beim Herunterfahren abgelaufen, die übrigen Benutzer bleiben wie sie sind")
+			break dispatch
+		case queue <- job:
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return int(restored)
+}
+
+// flushGuild drops the game that was interrupted, and writes what's worth keeping
+func (bot *Bot) flushGuild(guild *GuildState) {
+	if guild.GameEventLog.IsActive() {
+		//the game itself never finished, but who played as who is still true
+		bot.recordLinkHistory(guild)
+	}
+	guild.GameEventLog.Discard()
+
+	data, err := guild.PGD().ToData()
+	if err != nil {
+		guild.logger().Error(err)
+		return
+	}
+	err = bot.StorageInterface.WriteGuildData(guild.PGD().GuildID, data)
+	if err != nil {
+		guild.logger().Errorf("Konfiguration konnte beim Herunterfahren nicht gespeichert werden: %s", err)
+	}
+}
@@ -0,0 +1,60 @@
+package discord
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/denverquane/amongusdiscord/game"
+)
+
+func TestShutdownRestoresOnlyWhatTheBotApplied(t *testing.T) {
+	bot, fake := fakeBot(t)
+	guild, _ := bot.AllGuilds.Get(flowGuildID)
+	guild.PGD().Delays = GameDelays{}
+
+	sendCommand(bot, fake, ".au new ABCDEF eu")
+	bot.PushGuildPhaseUpdate(flowGuildID, game.LOBBY)
+	bot.PushGuildPlayerUpdate(flowGuildID, game.Player{Action: game.JOINED, Name: "alice", Color: 0})
+	bot.PushGuildPlayerUpdate(flowGuildID, game.Player{Action: game.JOINED, Name: "bob", Color: 1})
+	waitFor(t, "beide Spieler verknüpft", func() bool { return guild.UserData.GetCountLinked() == 2 })
+	bot.PushGuildPhaseUpdate(flowGuildID, game.TASKS)
+	waitFor(t, "alice stumm und taub", voiceIs(fake, flowAliceID, true, true))
+	waitFor(t, "bob stumm und taub", voiceIs(fake, flowBobID, true, true))
+
+	//a moderator changes bob's mute and mutes the owner, who doesn't play; the bot doesn't see it
+	fake.OnVoiceStateUpdate = nil
+	fake.PatchMember(flowGuildID, flowBobID, MemberPatch{Mute: true})
+	fake.PatchMember(flowGuildID, flowOwnerID, MemberPatch{Mute: true})
+
+	ctx, cancel := context.WithTimeout(context.Background(), flowWaitTime)
+	defer cancel()
+	if err := bot.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !voiceIs(fake, flowAliceID, false, false)() {
+		t.Error("alice hat der Bot stummgeschaltet, also muss er sie wiederherstellen")
+	}
+	if !voiceIs(fake, flowBobID, true, false)() {
+		t.Error("bob hat ein Moderator stummgeschaltet, das muss bleiben")
+	}
+	if !voiceIs(fake, flowOwnerID, true, false)() {
+		t.Error("wer nicht mitspielt, bleibt wie er ist")
+	}
+}
+
+func TestShutdownStopsNotifyingOnceCancelled(t *testing.T) {
+	bot, fake := fakeBot(t)
+	sendCommand(bot, fake, ".au new ABCDEF eu")
+	before := len(fake.Messages(flowTextID))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	if err := bot.Shutdown(ctx); err == nil {
+		t.Error("die abgelaufene Frist sollte gemeldet werden")
+	}
+	if after := len(fake.Messages(flowTextID)); after != before {
+		t.Errorf("nach Ablauf der Frist darf nichts mehr gesendet werden, %d neue Nachrichten", after-before)
+	}
+}
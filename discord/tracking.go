@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"sync"
+
+	"github.com/denverquane/amongusdiscord/locale"
 )
 
 // Tracking struct
@@ -41,12 +43,12 @@ func (tracking *Tracking) IsTracked(channelID string) bool {
 	return false
 }
 
-func (tracking *Tracking) ToStatusString() string {
+func (tracking *Tracking) ToStatusString(lang string) string {
 	tracking.lock.RLock()
 	defer tracking.lock.RUnlock()
 
 	if len(tracking.tracking) == 0 {
-		return "Beliebiger Sprachkanal"
+		return locale.T(lang, "track.any")
 	}
 
 	buf := bytes.NewBuffer([]byte{})
@@ -54,10 +56,10 @@ func (tracking *Tracking) ToStatusString() string {
 	for _, v := range tracking.tracking {
 		buf.WriteString(fmt.Sprintf("%s ", v.channelName))
 		if v.forGhosts {
-			buf.WriteString(" (ghosts) ")
+			buf.WriteString(" " + locale.T(lang, "track.ghosts") + " ")
 		}
 		if i < len(tracking.tracking)-1 {
-			buf.WriteString("or ")
+			buf.WriteString(locale.T(lang, "track.or") + " ")
 		}
 		i++
 	}
@@ -91,3 +93,15 @@ func (tracking *Tracking) AddTrackedChannel(id, name string, forGhosts bool) {
 	}
 	tracking.lock.Unlock()
 }
+
+// Channels returns a copy of the tracked channels
+func (tracking *Tracking) Channels() []TrackingChannel {
+	tracking.lock.RLock()
+	defer tracking.lock.RUnlock()
+
+	channels := make([]TrackingChannel, 0, len(tracking.tracking))
+	for _, v := range tracking.tracking {
+		channels = append(channels, v)
+	}
+	return channels
+}
@@ -3,25 +3,52 @@ package discord
 import (
 	"errors"
 	"fmt"
-	"strings"
 	"sync"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/denverquane/amongusdiscord/game"
+	"github.com/denverquane/amongusdiscord/locale"
 )
 
 type UserDataSet struct {
 	userDataSet map[string]game.UserData
-	lock        sync.RWMutex
+	//the mute and deafen the bot last set for each user, so it doesn't undo what a moderator did
+	applied map[string]AppliedVoiceState
+	lock    sync.RWMutex
+}
+
+// AppliedVoiceState is a server mute and deafen the bot set
+type AppliedVoiceState struct {
+	Mute bool
+	Deaf bool
 }
 
 func MakeUserDataSet() UserDataSet {
 	return UserDataSet{
 		userDataSet: map[string]game.UserData{},
+		applied:     map[string]AppliedVoiceState{},
 		lock:        sync.RWMutex{},
 	}
 }
 
+// SetApplied remembers the mute and deafen Discord accepted from the bot for the user
+func (uds *UserDataSet) SetApplied(userID string, mute, deaf bool) {
+	uds.lock.Lock()
+	if uds.applied == nil {
+		uds.applied = map[string]AppliedVoiceState{}
+	}
+	uds.applied[userID] = AppliedVoiceState{Mute: mute, Deaf: deaf}
+	uds.lock.Unlock()
+}
+
+// Applied is the mute and deafen the bot last set for the user; false if it never changed them
+func (uds *UserDataSet) Applied(userID string) (AppliedVoiceState, bool) {
+	uds.lock.RLock()
+	defer uds.lock.RUnlock()
+	state, ok := uds.applied[userID]
+	return state, ok
+}
+
 func (uds *UserDataSet) Size() int {
 	uds.lock.RLock()
 	defer uds.lock.RUnlock()
@@ -70,7 +97,7 @@ func (uds *UserDataSet) UpdatePlayerMappingByName(name string, data *game.Player
 	uds.lock.Lock()
 	defer uds.lock.Unlock()
 	for userID, v := range uds.userDataSet {
-		if v.GetPlayerName() == name {
+		if v.GetPlayerName() == name && !v.IsSpectating() {
 			v.SetPlayerData(data)
 			uds.userDataSet[userID] = v
 			return
@@ -81,10 +108,10 @@ func (uds *UserDataSet) UpdatePlayerMappingByName(name string, data *game.Player
 func (uds *UserDataSet) AttemptPairingByMatchingNames(name string, data *game.PlayerData) bool {
 	uds.lock.Lock()
 	defer uds.lock.Unlock()
-	name = strings.ReplaceAll(strings.ToLower(name), " ", "")
+	name = normalizeAliasName(name)
 	for userID, v := range uds.userDataSet {
-		if !v.IsLinked() {
-			if strings.ReplaceAll(strings.ToLower(v.GetUserName()), " ", "") == name || strings.ReplaceAll(strings.ToLower(v.GetNickName()), " ", "") == name {
+		if !v.IsLinked() && !v.IsSpectating() {
+			if normalizeAliasName(v.GetUserName()) == name || normalizeAliasName(v.GetNickName()) == name {
 				v.SetPlayerData(data)
 				uds.userDataSet[userID] = v
 				return true
@@ -94,6 +121,88 @@ func (uds *UserDataSet) AttemptPairingByMatchingNames(name string, data *game.Pl
 	return false
 }
 
+// IsPlayerNameLinked compares names like the link history does, so "Rot Käppchen" is taken if "rotkäppchen" is
+func (uds *UserDataSet) IsPlayerNameLinked(name string) bool {
+	uds.lock.RLock()
+	defer uds.lock.RUnlock()
+	name = normalizeAliasName(name)
+	for _, v := range uds.userDataSet {
+		if v.IsLinked() && normalizeAliasName(v.GetPlayerName()) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUnlinkedUserIDs are the users who could still be linked; spectators don't want to be
+func (uds *UserDataSet) GetUnlinkedUserIDs() []string {
+	uds.lock.RLock()
+	defer uds.lock.RUnlock()
+	ids := make([]string, 0)
+	for userID, v := range uds.userDataSet {
+		if !v.IsLinked() && !v.IsSpectating() {
+			ids = append(ids, userID)
+		}
+	}
+	return ids
+}
+
+func (uds *UserDataSet) GetLinkedUsers() []game.UserData {
+	uds.lock.RLock()
+	defer uds.lock.RUnlock()
+	users := make([]game.UserData, 0)
+	for _, v := range uds.userDataSet {
+		if v.IsLinked() {
+			users = append(users, v)
+		}
+	}
+	return users
+}
+
+// AutoLink links the user to the player, unless they're linked already or chose to spectate; the check and the link
+// happen under one lock, so a user who starts spectating meanwhile isn't put back on a player
+func (uds *UserDataSet) AutoLink(userID string, data *game.PlayerData) bool {
+	uds.lock.Lock()
+	defer uds.lock.Unlock()
+	v, ok := uds.userDataSet[userID]
+	if !ok || v.IsLinked() || v.IsSpectating() {
+		return false
+	}
+	v.SetPlayerData(data)
+	uds.userDataSet[userID] = v
+	return true
+}
+
+// LinkUnlessTaken links the user to the player, unless someone else is linked to the player's color already. Both
+// happen under one lock, so two users can't take the same color at once. If it's taken, it returns who has it; if
+// the user isn't known, it returns nothing
+func (uds *UserDataSet) LinkUnlessTaken(userID string, data *game.PlayerData) (string, bool) {
+	uds.lock.Lock()
+	defer uds.lock.Unlock()
+	for otherID, v := range uds.userDataSet {
+		if otherID != userID && v.IsLinked() && v.GetColor() == data.Color {
+			return otherID, false
+		}
+	}
+	v, ok := uds.userDataSet[userID]
+	if !ok {
+		return "", false
+	}
+	v.SetPlayerData(data)
+	uds.userDataSet[userID] = v
+	return "", true
+}
+
+// SetSpectating unlinks the user and keeps them from being linked automatically, until they link themselves again
+func (uds *UserDataSet) SetSpectating(userID string) {
+	uds.lock.Lock()
+	if v, ok := uds.userDataSet[userID]; ok {
+		v.SetSpectating(true)
+		uds.userDataSet[userID] = v
+	}
+	uds.lock.Unlock()
+}
+
 func (uds *UserDataSet) ClearPlayerData(userID string) {
 	uds.lock.Lock()
 	if v, ok := uds.userDataSet[userID]; ok {
@@ -133,7 +242,7 @@ func (uds *UserDataSet) GetUser(userID string) (game.UserData, error) {
 	return game.UserData{}, errors.New(fmt.Sprintf("Kein Benutzer gefunden mit der ID %s", userID))
 }
 
-func (uds *UserDataSet) ToEmojiEmbedFields(nameColorMap map[string]int, nameAliveMap map[string]bool, emojis AlivenessEmojis) []*discordgo.MessageEmbedField {
+func (uds *UserDataSet) ToEmojiEmbedFields(nameColorMap map[string]int, nameAliveMap map[string]bool, emojis AlivenessEmojis, lang string) []*discordgo.MessageEmbedField {
 	uds.lock.RLock()
 	defer uds.lock.RUnlock()
 
@@ -157,7 +266,7 @@ func (uds *UserDataSet) ToEmojiEmbedFields(nameColorMap map[string]int, nameAliv
 			emoji := emojis[nameAliveMap[name]][color]
 			unsorted[color] = &discordgo.MessageEmbedField{
 				Name:   fmt.Sprintf("%s", name),
-				Value:  fmt.Sprintf("%s **Unlinked**", emoji.FormatForInline()),
+				Value:  fmt.Sprintf("%s %s", emoji.FormatForInline(), locale.T(lang, "status.unlinked")),
 				Inline: true,
 			}
 		}
@@ -0,0 +1,216 @@
+package game
+
+import (
+	"strings"
+)
+
+// AliasKind is what an alias stands for
+type AliasKind string
+
+const (
+	ColorAlias  AliasKind = "color"
+	PhaseAlias  AliasKind = "phase"
+	RegionAlias AliasKind = "region"
+)
+
+// AliasKinds are all the kinds of aliases, in the order they're listed
+var AliasKinds = []AliasKind{ColorAlias, PhaseAlias, RegionAlias}
+
+// BuiltinAliases are the words players can type for the colors, phases and regions, per language. They stand for
+// the canonical names: the keys of ColorStrings, the PhaseNames and the Region names
+var BuiltinAliases = map[string]map[AliasKind]map[string]string{
+	"en": {
+		ColorAlias: {
+			"red":    "red",
+			"blue":   "blue",
+			"green":  "green",
+			"pink":   "pink",
+			"orange": "orange",
+			"yellow": "yellow",
+			"black":  "black",
+			"white":  "white",
+			"purple": "purple",
+			"brown":  "brown",
+			"cyan":   "cyan",
+			"lime":   "lime",
+		},
+		PhaseAlias: {
+			"lobby":      "LOBBY",
+			"l":          "LOBBY",
+			"task":       "TASKS",
+			"tasks":      "TASKS",
+			"t":          "TASKS",
+			"game":       "TASKS",
+			"g":          "TASKS",
+			"discuss":    "DISCUSSION",
+			"disc":       "DISCUSSION",
+			"discussion": "DISCUSSION",
+			"d":          "DISCUSSION",
+		},
+		RegionAlias: {
+			"na":           "North America",
+			"us":           "North America",
+			"usa":          "North America",
+			"north":        "North America",
+			"northamerica": "North America",
+			"eu":           "Europe",
+			"europe":       "Europe",
+			"as":           "Asia",
+			"asia":         "Asia",
+		},
+	},
+	"de": {
+		ColorAlias: {
+			"rot":       "red",
+			"blau":      "blue",
+			"grün":      "green",
+			"gruen":     "green",
+			"rosa":      "pink",
+			"gelb":      "yellow",
+			"schwarz":   "black",
+			"weiß":      "white",
+			"weiss":     "white",
+			"lila":      "purple",
+			"violett":   "purple",
+			"braun":     "brown",
+			"türkis":    "cyan",
+			"tuerkis":   "cyan",
+			"hellgrün":  "lime",
+			"hellgruen": "lime",
+			"limette":   "lime",
+		},
+		PhaseAlias: {
+			"aufgaben":    "TASKS",
+			"aufgabe":     "TASKS",
+			"spiel":       "TASKS",
+			"diskussion":  "DISCUSSION",
+			"disk":        "DISCUSSION",
+			"besprechung": "DISCUSSION",
+		},
+		RegionAlias: {
+			"nordamerika": "North America",
+			"europa":      "Europe",
+			"asien":       "Asia",
+		},
+	},
+}
+
+// Aliases resolves what players type to the canonical names. Every builtin language is always understood, no
+// matter which language the guild chose, and the guild's own aliases come on top
+type Aliases struct {
+	words map[AliasKind]map[string]string
+}
+
+// NewAliases makes the registry from the builtin aliases and the custom ones, which win over the builtin ones
+func NewAliases(custom map[AliasKind]map[string]string) *Aliases {
+	aliases := Aliases{words: make(map[AliasKind]map[string]string, len(AliasKinds))}
+	for _, kind := range AliasKinds {
+		aliases.words[kind] = make(map[string]string)
+	}
+	for _, lang := range BuiltinAliases {
+		for kind, words := range lang {
+			for word, name := range words {
+				aliases.words[kind][word] = name
+			}
+		}
+	}
+	for kind, words := range custom {
+		if _, ok := aliases.words[kind]; !ok {
+			continue
+		}
+		for word, name := range words {
+			aliases.words[kind][normalizeAlias(word)] = name
+		}
+	}
+	return &aliases
+}
+
+// DefaultAliases only knows the builtin aliases
+func DefaultAliases() *Aliases {
+	return NewAliases(nil)
+}
+
+func normalizeAlias(word string) string {
+	return strings.ToLower(strings.TrimSpace(word))
+}
+
+// Resolve returns the canonical name the input stands for
+func (aliases *Aliases) Resolve(kind AliasKind, input string) (string, bool) {
+	input = normalizeAlias(input)
+	if name, ok := aliases.words[kind][input]; ok {
+		return name, true
+	}
+	//"hell grün" is typed as two args, but only ever registered as one word
+	name, ok := aliases.words[kind][strings.Join(strings.Fields(input), "")]
+	return name, ok
+}
+
+// Color returns the color the input stands for
+func (aliases *Aliases) Color(input string) (int, bool) {
+	name, ok := aliases.Resolve(ColorAlias, input)
+	if !ok {
+		return 0, false
+	}
+	color, ok := ColorStrings[name]
+	return color, ok
+}
+
+// Phase returns the phase the input stands for, or UNINITIALIZED
+func (aliases *Aliases) Phase(input string) Phase {
+	name, ok := aliases.Resolve(PhaseAlias, input)
+	if !ok {
+		return UNINITIALIZED
+	}
+	for phase, v := range PhaseNames {
+		if string(v) == name && phase != MENU {
+			return phase
+		}
+	}
+	return UNINITIALIZED
+}
+
+// Region returns the region the input stands for. Regions the bot doesn't know are kept as they were typed,
+// there are private servers after all
+func (aliases *Aliases) Region(input string) string {
+	if name, ok := aliases.Resolve(RegionAlias, input); ok {
+		return name
+	}
+	return input
+}
+
+// IsAliasKind is true if there are aliases of the kind
+func IsAliasKind(kind AliasKind) bool {
+	for _, k := range AliasKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// CanonicalName returns how the name aliases of the kind can stand for is spelled, regardless of case
+func CanonicalName(kind AliasKind, name string) (string, bool) {
+	for _, canonical := range CanonicalNames(kind) {
+		if strings.EqualFold(canonical, strings.TrimSpace(name)) {
+			return canonical, true
+		}
+	}
+	return "", false
+}
+
+// CanonicalNames are the names aliases of the kind can stand for
+func CanonicalNames(kind AliasKind) []string {
+	switch kind {
+	case ColorAlias:
+		names := make([]string, len(ColorStrings))
+		for name, color := range ColorStrings {
+			names[color] = name
+		}
+		return names
+	case PhaseAlias:
+		return []string{string(PhaseNames[LOBBY]), string(PhaseNames[TASKS]), string(PhaseNames[DISCUSS])}
+	case RegionAlias:
+		return []string{NA.ToString(), EU.ToString(), AS.ToString()}
+	}
+	return nil
+}
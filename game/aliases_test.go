@@ -0,0 +1,53 @@
+package game
+
+import "testing"
+
+func TestBuiltinAliases(t *testing.T) {
+	seen := map[AliasKind]map[string]string{}
+	for lang, kinds := range BuiltinAliases {
+		for kind, words := range kinds {
+			if seen[kind] == nil {
+				seen[kind] = map[string]string{}
+			}
+			for word, name := range words {
+				if canonical, ok := CanonicalName(kind, name); !ok || canonical != name {
+					t.Errorf("%s: %s `%s` steht für `%s`, das es nicht gibt", lang, kind, word, name)
+				}
+				//the languages are merged, so they must agree on every word
+				if other, ok := seen[kind][word]; ok && other != name {
+					t.Errorf("%s: %s `%s` steht für `%s` und `%s`", lang, kind, word, name, other)
+				}
+				seen[kind][word] = name
+			}
+		}
+	}
+}
+
+func TestResolveAliases(t *testing.T) {
+	aliases := NewAliases(map[AliasKind]map[string]string{
+		ColorAlias: {"koralle": "red"},
+		PhaseAlias: {"quatschen": "DISCUSSION"},
+	})
+
+	for input, expected := range map[string]int{"Rot": Red, "red": Red, "koralle": Red, "hell grün": Lime, "weiß": White} {
+		if color, ok := aliases.Color(input); !ok || color != expected {
+			t.Errorf("%q ist %d (%v), erwartet %d", input, color, ok, expected)
+		}
+	}
+	if _, ok := aliases.Color("kariert"); ok {
+		t.Error("kariert sollte keine Farbe sein")
+	}
+	for input, expected := range map[string]Phase{"d": DISCUSS, "Diskussion": DISCUSS, "quatschen": DISCUSS, "aufgaben": TASKS, "lobby": LOBBY, "menu": UNINITIALIZED} {
+		if phase := aliases.Phase(input); phase != expected {
+			t.Errorf("%q ist die Phase %d, erwartet %d", input, phase, expected)
+		}
+	}
+	for input, expected := range map[string]string{"europa": "Europe", "na": "North America", "mein server": "mein server"} {
+		if region := aliases.Region(input); region != expected {
+			t.Errorf("%q ist die Region %q, erwartet %q", input, region, expected)
+		}
+	}
+	if _, ok := DefaultAliases().Color("koralle"); ok {
+		t.Error("die eigenen Wörter eines Servers sollten nicht für alle gelten")
+	}
+}
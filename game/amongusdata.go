@@ -2,9 +2,10 @@ package game
 
 import (
 	"fmt"
-	"log"
 	"strings"
 	"sync"
+
+	"github.com/denverquane/amongusdiscord/logging"
 )
 
 //TODO make this private?
@@ -107,7 +108,7 @@ func (auData *AmongUsData) ApplyPlayerUpdate(update Player) (bool, bool) {
 			Name:    update.Name,
 			IsAlive: !update.IsDead,
 		}
-		log.Printf("Neue Player-Instanz für %s hinzugefügt\n", update.Name)
+		logging.Debugf("Neue Player-Instanz für %s hinzugefügt", update.Name)
 		return true, false
 	}
 	guildDataTempPtr := auData.playerData[update.Name]
@@ -117,7 +118,7 @@ func (auData *AmongUsData) ApplyPlayerUpdate(update Player) (bool, bool) {
 		(*auData.playerData[update.Name]).Color = update.Color
 		(*auData.playerData[update.Name]).Name = update.Name
 		(*auData.playerData[update.Name]).IsAlive = !update.IsDead
-		log.Printf("Aktualisiert %s", (*auData.playerData[update.Name]).ToString())
+		logging.Debugf("Aktualisiert %s", (*auData.playerData[update.Name]).ToString())
 	}
 
 	return isUpdate, isAliveUpdate
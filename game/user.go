@@ -19,6 +19,8 @@ type UserData struct {
 	pendingVoiceUpdate bool
 	cachedPlayerName   string
 	auData             *PlayerData //we want to point to player data that isn't necessarily correlated with a player yet...
+	//a spectator doesn't play, so they're never linked automatically
+	spectating bool
 }
 
 func MakeUserDataFromDiscordUser(dUser *discordgo.User, nick string) UserData {
@@ -83,11 +85,25 @@ func (user *UserData) GetPlayerName() string {
 func (user *UserData) SetPlayerData(player *PlayerData) {
 	if player != nil {
 		user.cachedPlayerName = player.Name
+		user.spectating = false
 	}
 
 	user.auData = player
 }
 
+// IsSpectating is true if the user chose to only watch the game
+func (user *UserData) IsSpectating() bool {
+	return user.spectating
+}
+
+// SetSpectating unlinks the user, if they spectate
+func (user *UserData) SetSpectating(spectating bool) {
+	if spectating {
+		user.auData = nil
+	}
+	user.spectating = spectating
+}
+
 func (user *UserData) GetColor() int {
 	if user.auData != nil {
 		return user.auData.Color
@@ -1,14 +1,21 @@
 module github.com/denverquane/amongusdiscord
 
-go 1.15
+go 1.16
 
-// +heroku goVersion go1.15
+// +heroku goVersion go1.16
 
 require (
 	cloud.google.com/go/firestore v1.3.0
+	github.com/alicebob/miniredis/v2 v2.14.1
 	github.com/bwmarrin/discordgo v0.22.0
+	github.com/go-redis/redis/v8 v8.4.4
 	github.com/googollee/go-socket.io v1.4.4
 	github.com/gorilla/mux v1.8.0
+	github.com/gorilla/websocket v1.4.1
 	github.com/joho/godotenv v1.3.0
+	github.com/prometheus/client_golang v1.8.0
+	github.com/sirupsen/logrus v1.6.0
 	google.golang.org/api v0.29.0
+	gopkg.in/natefinch/lumberjack.v2 v2.0.0
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,157 @@
+// Package locale renders the texts the bot shows in Discord, in the language a guild chose. The catalogs
+// are YAML files embedded in the binary, one per language, e.g. de.yaml. Their nested keys are joined with
+// dots, so settings.prefix.changed is the key of
+//
+//	settings:
+//	  prefix:
+//	    changed: "..."
+//
+// The texts are fmt format strings; a key that's missing in the guild's language falls back to
+// DefaultLanguage, and to the key itself if it's missing there too
+package locale
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/denverquane/amongusdiscord/logging"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultLanguage is used by guilds that didn't choose one, and for every key another catalog is missing
+const DefaultLanguage = "de"
+
+//go:embed *.yaml
+var files embed.FS
+
+var catalogs = mustLoad()
+
+// every missing key is only logged once, not on every status message edit
+var missing = sync.Map{}
+
+func mustLoad() map[string]map[string]string {
+	loaded, err := load(files)
+	if err != nil {
+		panic(err)
+	}
+	return loaded
+}
+
+func load(fs embed.FS) (map[string]map[string]string, error) {
+	entries, err := fs.ReadDir(".")
+	if err != nil {
+		return nil, err
+	}
+	loaded := make(map[string]map[string]string)
+	for _, entry := range entries {
+		data, err := fs.ReadFile(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		var tree map[string]interface{}
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, fmt.Errorf("%s: %s", entry.Name(), err)
+		}
+		catalog := make(map[string]string)
+		if err := flatten("", tree, catalog); err != nil {
+			return nil, fmt.Errorf("%s: %s", entry.Name(), err)
+		}
+		loaded[strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))] = catalog
+	}
+	if _, ok := loaded[DefaultLanguage]; !ok {
+		return nil, fmt.Errorf("es fehlt der Katalog der Standardsprache %s", DefaultLanguage)
+	}
+	return loaded, nil
+}
+
+func flatten(prefix string, tree map[string]interface{}, catalog map[string]string) error {
+	for k, v := range tree {
+		key := prefix + k
+		switch value := v.(type) {
+		case string:
+			catalog[key] = value
+		case map[interface{}]interface{}:
+			child := make(map[string]interface{}, len(value))
+			for ck, cv := range value {
+				//YAML reads unquoted keys like on, off or yes as booleans
+				name, ok := ck.(string)
+				if !ok {
+					return fmt.Errorf("%s.%v ist kein Text, der Schlüssel muss in Anführungszeichen", key, ck)
+				}
+				child[name] = cv
+			}
+			if err := flatten(key+".", child, catalog); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s ist weder Text noch Gruppe", key)
+		}
+	}
+	return nil
+}
+
+// T renders the text for the key in the language, formatted with the args
+func T(lang, key string, args ...interface{}) string {
+	text, ok := lookup(lang, key)
+	if !ok {
+		return key
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+func lookup(lang, key string) (string, bool) {
+	if lang == "" {
+		lang = DefaultLanguage
+	}
+	if text, ok := catalogs[lang][key]; ok {
+		return text, true
+	}
+	if _, reported := missing.LoadOrStore(lang+":"+key, true); !reported {
+		logging.Warnf("Der Text %s fehlt in der Sprache %s", key, lang)
+	}
+	text, ok := catalogs[DefaultLanguage][key]
+	return text, ok
+}
+
+// Languages are the codes of all the catalogs, sorted
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// IsSupported is true if there's a catalog for the language
+func IsSupported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Error is an error whose message comes from the catalog, so it can be shown in the guild's language
+type Error struct {
+	Key  string
+	Args []interface{}
+}
+
+// Errorf is like fmt.Errorf, but with a catalog key as the format
+func Errorf(key string, args ...interface{}) error {
+	return &Error{Key: key, Args: args}
+}
+
+func (e *Error) Error() string {
+	return T(DefaultLanguage, e.Key, e.Args...)
+}
+
+// ErrorText is the error's message in the language, if it comes from the catalog
+func ErrorText(lang string, err error) string {
+	if e, ok := err.(*Error); ok {
+		return T(lang, e.Key, e.Args...)
+	}
+	return err.Error()
+}
@@ -0,0 +1,116 @@
+package locale
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+var verbs = regexp.MustCompile(`%(\[\d+\])?[-+# 0]*\d*(\.\d+)?[a-zA-Z%]`)
+
+// the verbs of a text, so a translation can't ask for other arguments than the code passes
+func verbsOf(text string) string {
+	found := verbs.FindAllString(text, -1)
+	sort.Strings(found)
+	return strings.Join(found, " ")
+}
+
+func TestCatalogsMatch(t *testing.T) {
+	for _, lang := range Languages() {
+		for key, text := range catalogs[DefaultLanguage] {
+			translated, ok := catalogs[lang][key]
+			if !ok {
+				t.Errorf("%s: %s fehlt", lang, key)
+				continue
+			}
+			if verbsOf(translated) != verbsOf(text) {
+				t.Errorf("%s: %s hat andere Platzhalter (%s) als %s (%s)", lang, key, verbsOf(translated), DefaultLanguage, verbsOf(text))
+			}
+		}
+		for key := range catalogs[lang] {
+			if _, ok := catalogs[DefaultLanguage][key]; !ok {
+				t.Errorf("%s: %s gibt es in %s nicht", lang, key, DefaultLanguage)
+			}
+		}
+	}
+}
+
+func TestFallbacks(t *testing.T) {
+	catalogs["xx"] = map[string]string{"help.title": "Hilfe %s"}
+	defer delete(catalogs, "xx")
+
+	if text := T("xx", "help.title", "1"); text != "Hilfe 1" {
+		t.Errorf("erwartet den eigenen Text, bekommen %q", text)
+	}
+	if text := T("xx", "settings.title"); text != catalogs[DefaultLanguage]["settings.title"] {
+		t.Errorf("ein fehlender Text sollte aus %s kommen, nicht %q", DefaultLanguage, text)
+	}
+	if text := T("", "settings.title"); text != catalogs[DefaultLanguage]["settings.title"] {
+		t.Errorf("ohne Sprache sollte %s gelten, nicht %q", DefaultLanguage, text)
+	}
+	if text := T("en", "gibt.es.nicht"); text != "gibt.es.nicht" {
+		t.Errorf("ein unbekannter Schlüssel sollte selbst angezeigt werden, nicht %q", text)
+	}
+	if IsSupported("xy") || !IsSupported("en") {
+		t.Error("IsSupported kennt die Kataloge nicht")
+	}
+}
+
+func TestErrorText(t *testing.T) {
+	err := Errorf("settings.validate.adminID", "abc")
+	if err.Error() != T(DefaultLanguage, "settings.validate.adminID", "abc") {
+		t.Errorf("Error() sollte in %s sein: %s", DefaultLanguage, err)
+	}
+	if text := ErrorText("en", err); text != "adminIDs: `abc` is not a valid user ID" {
+		t.Errorf("erwartet den englischen Text, bekommen %q", text)
+	}
+	if text := ErrorText("en", errors.New("kaputt")); text != "kaputt" {
+		t.Errorf("andere Fehler bleiben, wie sie sind, nicht %q", text)
+	}
+}
+
+func TestFlattenRejectsBooleanKeys(t *testing.T) {
+	var tree map[string]interface{}
+	if err := yaml.Unmarshal([]byte("debug:\n  on: \"an\"\n"), &tree); err != nil {
+		t.Fatal(err)
+	}
+	if err := flatten("", tree, map[string]string{}); err == nil {
+		t.Error("on ohne Anführungszeichen ist ein Wahrheitswert und darf kein Schlüssel sein")
+	}
+}
+
+// a literal key as the whole argument, not the start of one that's put together
+var keysInCode = []*regexp.Regexp{
+	regexp.MustCompile(`\.tr\("([^"]+)"[,)]`),
+	regexp.MustCompile(`locale\.T\([^,()]+, "([^"]+)"[,)]`),
+	regexp.MustCompile(`locale\.Errorf\("([^"]+)"[,)]`),
+}
+
+func TestKeysUsedInCode(t *testing.T) {
+	files, err := filepath.Glob("../discord/*.go")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("keine Quelltexte gefunden: %v", err)
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		source, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, pattern := range keysInCode {
+			for _, match := range pattern.FindAllStringSubmatch(string(source), -1) {
+				if _, ok := catalogs[DefaultLanguage][match[1]]; !ok {
+					t.Errorf("%s: den Text %s gibt es nicht", filepath.Base(file), match[1])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Package logging is the bot's structured, leveled logger. Every entry can carry the guild, shard,
+// socket and phase it belongs to, and debug output can be switched on for single guilds at runtime.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// the fields that entries are tagged with
+const (
+	FieldGuild  = "guild"
+	FieldShard  = "shard"
+	FieldSocket = "socket"
+	FieldPhase  = "phase"
+)
+
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+)
+
+// Config says where the log goes and how it's rotated
+type Config struct {
+	//debug, info, warn or error
+	Level string
+	//console or json
+	Format string
+	//no file is written if empty
+	File string
+	//the file is rotated once it's bigger than this
+	MaxSizeMB int
+	//rotated files older than this are deleted; 0 keeps them regardless of age
+	MaxAgeDays int
+	//at most this many rotated files are kept; 0 keeps all of them
+	MaxBackups int
+	//also rotate the file at midnight, so there's one file per day
+	RotateDaily bool
+}
+
+// DefaultConfig is what the bot used before logging was configurable, minus truncating logs.txt on every start
+func DefaultConfig() Config {
+	return Config{
+		Level:       "info",
+		Format:      FormatConsole,
+		File:        "logs.txt",
+		MaxSizeMB:   50,
+		MaxAgeDays:  14,
+		MaxBackups:  10,
+		RotateDaily: true,
+	}
+}
+
+type Fields = logrus.Fields
+
+var base = newBase()
+
+var (
+	level     = logrus.InfoLevel
+	levelLock sync.RWMutex
+
+	debugGuilds     = map[string]bool{}
+	debugGuildsLock sync.RWMutex
+
+	file      *lumberjack.Logger
+	stopDaily chan struct{}
+)
+
+func newBase() *logrus.Logger {
+	l := logrus.New()
+	//the real filtering happens in Logger.enabled, so guilds can have debug output when the rest doesn't
+	l.SetLevel(logrus.DebugLevel)
+	l.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	return l
+}
+
+// Init sets up the level, format and output. Anything still written through the standard log package
+// (including by libraries) ends up in the same place, at info level
+func Init(cfg Config) error {
+	lvl, err := logrus.ParseLevel(cfg.Level)
+	if err != nil {
+		return fmt.Errorf("ungültiges Log-Level %q", cfg.Level)
+	}
+	SetLevel(lvl)
+
+	switch strings.ToLower(cfg.Format) {
+	case FormatJSON:
+		base.SetFormatter(&logrus.JSONFormatter{})
+	case FormatConsole, "":
+		base.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	default:
+		return fmt.Errorf("ungültiges Log-Format %q, erlaubt sind %s und %s", cfg.Format, FormatConsole, FormatJSON)
+	}
+
+	var out io.Writer = os.Stdout
+	if cfg.File != "" {
+		file = &lumberjack.Logger{
+			Filename:   cfg.File,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxAge:     cfg.MaxAgeDays,
+			MaxBackups: cfg.MaxBackups,
+			LocalTime:  true,
+		}
+		out = io.MultiWriter(os.Stdout, file)
+		if cfg.RotateDaily {
+			stopDaily = make(chan struct{})
+			go rotateDaily(file, stopDaily)
+		}
+	}
+	base.SetOutput(out)
+
+	log.SetFlags(0)
+	log.SetOutput(base.WriterLevel(logrus.InfoLevel))
+	return nil
+}
+
+// Close flushes and closes the log file, if there is one
+func Close() error {
+	if stopDaily != nil {
+		close(stopDaily)
+		stopDaily = nil
+	}
+	if file != nil {
+		return file.Close()
+	}
+	return nil
+}
+
+func rotateDaily(f *lumberjack.Logger, stop chan struct{}) {
+	for {
+		now := time.Now()
+		midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+		select {
+		case <-stop:
+			return
+		case <-time.After(midnight.Sub(now)):
+			if err := f.Rotate(); err != nil {
+				base.Errorf("Log-Datei konnte nicht rotiert werden: %s", err)
+			}
+		}
+	}
+}
+
+func SetLevel(lvl logrus.Level) {
+	levelLock.Lock()
+	level = lvl
+	levelLock.Unlock()
+}
+
+func getLevel() logrus.Level {
+	levelLock.RLock()
+	defer levelLock.RUnlock()
+	return level
+}
+
+// SetGuildDebug turns debug output on or off for a single guild, regardless of the global level
+func SetGuildDebug(guildID string, enabled bool) {
+	debugGuildsLock.Lock()
+	defer debugGuildsLock.Unlock()
+	if enabled {
+		debugGuilds[guildID] = true
+	} else {
+		delete(debugGuilds, guildID)
+	}
+}
+
+func IsGuildDebug(guildID string) bool {
+	debugGuildsLock.RLock()
+	defer debugGuildsLock.RUnlock()
+	return debugGuilds[guildID]
+}
+
+// Logger writes entries tagged with a fixed set of fields
+type Logger struct {
+	entry   *logrus.Entry
+	guildID string
+}
+
+// WithFields returns a logger that tags every entry with the fields
+func WithFields(fields Fields) *Logger {
+	return (&Logger{entry: logrus.NewEntry(base)}).WithFields(fields)
+}
+
+// WithGuild is the common case of WithFields with only a guild ID
+func WithGuild(guildID string) *Logger {
+	return WithFields(Fields{FieldGuild: guildID})
+}
+
+func (l *Logger) WithFields(fields Fields) *Logger {
+	guildID := l.guildID
+	if id, ok := fields[FieldGuild].(string); ok {
+		guildID = id
+	}
+	return &Logger{
+		entry:   l.entry.WithFields(fields),
+		guildID: guildID,
+	}
+}
+
+func (l *Logger) WithField(key string, value interface{}) *Logger {
+	return l.WithFields(Fields{key: value})
+}
+
+func (l *Logger) WithError(err error) *Logger {
+	return &Logger{
+		entry:   l.entry.WithError(err),
+		guildID: l.guildID,
+	}
+}
+
+func (l *Logger) enabled(lvl logrus.Level) bool {
+	if lvl <= getLevel() {
+		return true
+	}
+	return lvl == logrus.DebugLevel && l.guildID != "" && IsGuildDebug(l.guildID)
+}
+
+func (l *Logger) Debug(args ...interface{}) {
+	if l.enabled(logrus.DebugLevel) {
+		l.entry.Debug(args...)
+	}
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	if l.enabled(logrus.DebugLevel) {
+		l.entry.Debugf(format, args...)
+	}
+}
+
+func (l *Logger) Info(args ...interface{}) {
+	if l.enabled(logrus.InfoLevel) {
+		l.entry.Info(args...)
+	}
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	if l.enabled(logrus.InfoLevel) {
+		l.entry.Infof(format, args...)
+	}
+}
+
+func (l *Logger) Warn(args ...interface{}) {
+	if l.enabled(logrus.WarnLevel) {
+		l.entry.Warn(args...)
+	}
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	if l.enabled(logrus.WarnLevel) {
+		l.entry.Warnf(format, args...)
+	}
+}
+
+func (l *Logger) Error(args ...interface{}) {
+	if l.enabled(logrus.ErrorLevel) {
+		l.entry.Error(args...)
+	}
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	if l.enabled(logrus.ErrorLevel) {
+		l.entry.Errorf(format, args...)
+	}
+}
+
+// Fatalf logs regardless of the level, and exits
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.entry.Fatalf(format, args...)
+}
+
+var root = &Logger{entry: logrus.NewEntry(base)}
+
+// package-level shortcuts for entries without any context
+
+func Debug(args ...interface{})                 { root.Debug(args...) }
+func Debugf(format string, args ...interface{}) { root.Debugf(format, args...) }
+func Info(args ...interface{})                  { root.Info(args...) }
+func Infof(format string, args ...interface{})  { root.Infof(format, args...) }
+func Warn(args ...interface{})                  { root.Warn(args...) }
+func Warnf(format string, args ...interface{})  { root.Warnf(format, args...) }
+func Error(args ...interface{})                 { root.Error(args...) }
+func Errorf(format string, args ...interface{}) { root.Errorf(format, args...) }
+func Fatalf(format string, args ...interface{}) { root.Fatalf(format, args...) }
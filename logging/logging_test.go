@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func capture(t *testing.T) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	base.SetOutput(buf)
+	base.SetFormatter(&logrus.JSONFormatter{})
+	SetLevel(logrus.InfoLevel)
+	t.Cleanup(func() {
+		base.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+		SetGuildDebug("1", false)
+	})
+	return buf
+}
+
+func TestGuildDebug(t *testing.T) {
+	buf := capture(t)
+
+	WithGuild("1").Debug("vorher")
+	WithGuild("2").Debug("anderer")
+	SetGuildDebug("1", true)
+	WithGuild("1").WithField(FieldPhase, "LOBBY").Debug("nachher")
+	WithGuild("2").Debug("anderer")
+	Debug("ohne gilde")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("erwartet genau eine Zeile, bekommen: %q", buf.String())
+	}
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["msg"] != "nachher" || entry[FieldGuild] != "1" || entry[FieldPhase] != "LOBBY" || entry["level"] != "debug" {
+		t.Errorf("unerwarteter Eintrag: %v", entry)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	buf := capture(t)
+	SetLevel(logrus.WarnLevel)
+
+	Info("leise")
+	Warn("laut")
+	if strings.Contains(buf.String(), "leise") || !strings.Contains(buf.String(), "laut") {
+		t.Errorf("Level wird nicht beachtet: %q", buf.String())
+	}
+}
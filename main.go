@@ -1,19 +1,22 @@
 package main
 
 import (
-	"errors"
-	"io"
-	"log"
+	"context"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"strconv"
-	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/denverquane/amongusdiscord/storage"
 
+	"github.com/denverquane/amongusdiscord/config"
+	"github.com/denverquane/amongusdiscord/coordination"
 	"github.com/denverquane/amongusdiscord/discord"
+	"github.com/denverquane/amongusdiscord/logging"
 	"github.com/joho/godotenv"
 )
 
@@ -23,152 +26,178 @@ var (
 	date    = "unknown"
 )
 
-//TODO if running in shard mode, we don't want to use the default port. Each shard should prob run on their own port
-const DefaultPort = "8123"
-const DefaultURL = "http://localhost"
+// LegacyEnvFile is still read into the environment if it's there, from before config.yaml
+const LegacyEnvFile = "config.txt"
 
 func main() {
-	err := discordMainWrapper()
+	//config print [flags] shows what the bot would run with, and doesn't start it
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "print" {
+		os.Exit(printConfig(os.Args[3:]))
+	}
+
+	err := discordMainWrapper(os.Args[1:])
 	if err != nil {
-		log.Println("Programm mit folgendem Fehler beendet:")
-		log.Println(err)
-		log.Println("Dieses Fenster wird automatisch in 10 Sekunden beendet")
+		logging.Error("Programm mit folgendem Fehler beendet:")
+		logging.Error(err)
+		logging.Info("Dieses Fenster wird automatisch in 10 Sekunden beendet")
 		time.Sleep(10 * time.Second)
 		return
 	}
 }
 
-func discordMainWrapper() error {
-	err := godotenv.Load("config.txt")
-	if err != nil {
-		err = godotenv.Load("final.txt")
-		if err != nil {
-			log.Println("Konfigurationsdatei kann nicht geöffnet werden, hoffentlich läuft Programm im Docker und  DISCORD_BOT_TOKEN wurde bereitgestellt...")
-			f, err := os.Create("config.txt")
-			if err != nil {
-				log.Println("Problem beim Erstellen der Beispielkonfiguration config.txt")
-				return err
-			}
-			_, err = f.WriteString("DISCORD_BOT_TOKEN = \n")
-			f.Close()
+func loadConfig(args []string) (*config.Config, error) {
+	if _, err := os.Stat(LegacyEnvFile); err == nil {
+		logging.Warnf("%s wird noch gelesen, die Einstellungen sollten aber nach %s umziehen", LegacyEnvFile, config.DefaultFile)
+		if err := godotenv.Load(LegacyEnvFile); err != nil {
+			return nil, err
 		}
 	}
+	return config.Load(args, os.LookupEnv)
+}
 
-	logEntry := os.Getenv("DISABLE_LOG_FILE")
-	if logEntry == "" {
-		file, err := os.Create("logs.txt")
-		if err != nil {
-			return err
-		}
-		mw := io.MultiWriter(os.Stdout, file)
-		log.SetOutput(mw)
+func printConfig(args []string) int {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
 	}
-
-	emojiGuildID := os.Getenv("EMOJI_GUILD_ID")
-
-	log.Println(version + "-" + commit)
-
-	discordToken := os.Getenv("DISCORD_BOT_TOKEN")
-	if discordToken == "" {
-		return errors.New("kein DISCORD_BOT_TOKEN bereitgestellt")
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
-
-	discordToken2 := os.Getenv("DISCORD_BOT_TOKEN_2")
-	if discordToken2 != "" {
-		log.Println("Sie haben einen 2. Discord Bot Token bereitgestellt, daher werde ich versuchen, ihn zu verwenden")
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
+	return 0
+}
 
-	numShardsStr := os.Getenv("NUM_SHARDS")
-	numShards, err := strconv.Atoi(numShardsStr)
+func discordMainWrapper(args []string) error {
+	cfg, err := loadConfig(args)
+	if err == flag.ErrHelp {
+		return nil
+	}
 	if err != nil {
-		numShards = 1
+		return err
 	}
-	ports := make([]string, numShards)
-	tempPort := strings.ReplaceAll(os.Getenv("PORT"), " ", "")
-	portStrings := strings.Split(tempPort, ",")
-	if len(ports) == 0 || len(tempPort) == 0 {
-		num, err := strconv.Atoi(tempPort)
-
-		if err != nil || num < 1024 || num > 65535 {
-			log.Printf("[Info] Ungültiger oder kein bestimmter PORT (Bereich [1024-65535]) angegeben. Standardmäßig gesetzt auf %s\n", DefaultPort)
-			ports[0] = DefaultPort
-		}
-	} else if len(portStrings) == numShards {
-		for i := 0; i < numShards; i++ {
-			num, err := strconv.Atoi(portStrings[i])
-			if err != nil || num < 0 || num > 65535 {
-				return errors.New("ungültiger oder kein bestimmter PORT (Bereich [0-65535]) provided")
-			}
-			ports[i] = portStrings[i]
-		}
-	} else {
-		return errors.New("Die Anzahl der Shards stimmt nicht mit der Anzahl der bereitgestellten Ports überein")
+	if err := cfg.Validate(); err != nil {
+		return err
 	}
 
-	url := os.Getenv("SERVER_URL")
-	if url == "" {
-		log.Printf("[Info] Keine gültige SERVER_URL angegeben. Standardmäßig gesetzt auf %s\n", DefaultURL)
-		url = DefaultURL
+	err = logging.Init(cfg.Log.Logging())
+	if err != nil {
+		return err
 	}
+	defer logging.Close()
 
-	extPort := os.Getenv("EXT_PORT")
-	if extPort == "" {
-		log.Print("[Info] Kein EXT_PORT bereitgestellt. Standardmäßig gesetzt auf PORT\n")
-	} else if extPort == "protocol" {
-		log.Print("[Info] EXT_PORT auf Protokoll gesetzt. Der URL wird kein Port hinzugefügt\n")
-	} else {
-		num, err := strconv.Atoi(extPort)
-		if err != nil || num > 65535 || (num < 1024 && num != 80 && num != 443) {
-			return errors.New("ungültiger EXT_PORT (Bereich [1024-65535]) bereitgestellt")
-		}
+	logging.Info(version + "-" + commit)
+
+	if cfg.DiscordToken2 != "" {
+		logging.Info("Sie haben einen 2. Discord Bot Token bereitgestellt, daher werde ich versuchen, ihn zu verwenden")
+	}
+	numShards := cfg.NumShards
+	shardIDs := cfg.Shards()
+	port := strconv.Itoa(cfg.Port)
+	if cfg.ExtPort == "" {
+		logging.Info("Kein EXT_PORT bereitgestellt. Standardmäßig gesetzt auf PORT")
+	} else if cfg.ExtPort == "protocol" {
+		logging.Info("EXT_PORT auf Protokoll gesetzt. Der URL wird kein Port hinzugefügt")
 	}
 
 	var storageClient storage.StorageInterface
 	dbSuccess := false
 
-	authPath := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
-	projectID := os.Getenv("FIRESTORE_PROJECT_ID")
-	if authPath != "" && projectID != "" {
-		log.Println("Die Variable GOOGLE_APPLICATION_CREDENTIALS wird gesetzt. Versuch, Firestore als Speichertreiber zu verwenden")
+	if cfg.FirestoreProjectID != "" {
+		logging.Info("Die Variable GOOGLE_APPLICATION_CREDENTIALS wird gesetzt. Versuch, Firestore als Speichertreiber zu verwenden")
+		//the Google client only looks in the environment
+		os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", cfg.GoogleCredentials)
 		storageClient = &storage.FirestoreDriver{}
-		err = storageClient.Init(projectID)
+		err = storageClient.Init(cfg.FirestoreProjectID)
 		if err != nil {
-			log.Printf("Fehler beim Erstellen des Firestore-Clients mit Fehler: %s", err)
+			logging.Errorf("Fehler beim Erstellen des Firestore-Clients mit Fehler: %s", err)
 		} else {
 			dbSuccess = true
-			log.Println("Erfolgreiche Initialisierung des Firestore-Clients als Speichertreiber")
+			logging.Info("Erfolgreiche Initialisierung des Firestore-Clients als Speichertreiber")
 		}
 	}
 
 	if !dbSuccess {
 		storageClient = &storage.FilesystemDriver{}
-		configPath := os.Getenv("CONFIG_PATH")
-		if configPath == "" {
-			configPath = "./"
-		}
-		log.Printf("Verwenden von %s als Basispfad für die Konfiguration", configPath)
+		configPath := cfg.ConfigPath
+		logging.Infof("Verwenden von %s als Basispfad für die Konfiguration", configPath)
 		err := storageClient.Init(configPath)
 		if err != nil {
-			log.Fatalf("Fehler beim Erstellen des Dateisystem-Speichertreibers mit Fehler: %s", err)
+			logging.Fatalf("Fehler beim Erstellen des Dateisystem-Speichertreibers mit Fehler: %s", err)
+		}
+		logging.Info("Erfolgreiche Initialisierung des lokalen Dateisystems als Speichertreiber")
+	}
+
+	processID := cfg.ProcessID
+	if processID == "" {
+		hostname, _ := os.Hostname()
+		processID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	var coordinator coordination.Coordinator
+	if cfg.Redis.URL != "" {
+		coordinator, err = coordination.NewRedisCoordinator(cfg.Redis.URL, cfg.Redis.Prefix)
+		if err != nil {
+			return fmt.Errorf("Verbindung zu Redis fehlgeschlagen: %s", err)
+		}
+		logging.Infof("Redis wird zur Abstimmung mit den anderen Prozessen verwendet, dieser Prozess heißt %s", processID)
+	} else {
+		coordinator = coordination.NewMemoryCoordinator()
+		if len(shardIDs) < numShards {
+			logging.Warn("Ohne REDIS_URL können Erfassungen die Shards der anderen Prozesse nicht erreichen")
 		}
-		log.Println("Erfolgreiche Initialisierung des lokalen Dateisystems als Speichertreiber")
 	}
-	log.Println("Bot läuft jetzt. Drücke STRG-C, um den Vorgang zu beenden.")
+
+	logging.Info("Bot läuft jetzt. Drücke STRG-C, um den Vorgang zu beenden.")
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, os.Kill)
 
-	bots := make([]*discord.Bot, numShards)
+	discord.DefaultEditWindow = time.Duration(cfg.StatusEditWindowMs) * time.Millisecond
+	bots := make([]*discord.Bot, len(shardIDs))
 
-	for i := 0; i < numShards; i++ {
-		bots[i] = discord.MakeAndStartBot(version+"-"+commit, discordToken, discordToken2, url, ports[i], extPort, emojiGuildID, numShards, i, storageClient)
+	for i, shardID := range shardIDs {
+		bots[i] = discord.MakeAndStartBot(version+"-"+commit, cfg.DiscordToken, cfg.DiscordToken2, cfg.ServerURL, port, cfg.ExtPort, cfg.EmojiGuildID, numShards, shardID, storageClient, coordinator)
+	}
+	ingress, err := discord.NewCaptureIngress(processID, numShards, bots, coordinator)
+	if err != nil {
+		return err
 	}
+	go ingress.ListenAndServe(port)
 
-	go discord.MessagesServer("5000", bots)
+	go discord.AdminServer(strconv.Itoa(cfg.AdminPort), cfg.AdminToken, bots, coordinator)
 
 	<-sc
-	for i := 0; i < numShards; i++ {
-		bots[i].Close()
+	shutdownTimeout := time.Duration(cfg.ShutdownTimeout) * time.Second
+	logging.Infof("Fahre herunter, höchstens %s lang", shutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	ingress.Close()
+	wg := sync.WaitGroup{}
+	for i := range bots {
+		if bots[i] == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(bot *discord.Bot) {
+			defer wg.Done()
+			if err := bot.Shutdown(ctx); err != nil {
+				logging.Warnf("Herunterfahren nicht vollständig: %s", err)
+			}
+		}(bots[i])
+	}
+	wg.Wait()
+
+	for i := range bots {
+		if bots[i] != nil {
+			bots[i].Close()
+		}
 	}
+	coordinator.Close()
 	storageClient.Close()
+	logging.Info("Heruntergefahren")
 	return nil
 }
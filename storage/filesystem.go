@@ -7,13 +7,47 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/denverquane/amongusdiscord/logging"
 )
 
 const FileSuffix = "_config.json"
+const LinkHistorySuffix = "_links.json"
+const GameHistorySuffix = "_games.json"
+
+// MaxGameRecords is how many games a guild's history file keeps; the oldest ones are dropped, so the file doesn't
+// grow forever and rewriting it stays quick
+var MaxGameRecords = 1000
+
+// WatchInterval is how often the base directory is rescanned for files that were added or edited by hand
+const WatchInterval = 2 * time.Second
+
+// temp files are written as ".<name>.tmp<random>" and renamed over the real file once they're complete
+const tempFilePrefix = "."
+const tempFileSuffix = ".tmp"
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
 
 type FilesystemDriver struct {
-	baseDir  string
-	fileInfo []os.FileInfo
+	baseDir string
+
+	//live index of every data file in baseDir, by file name
+	index     map[string]fileStamp
+	indexLock sync.RWMutex
+
+	//one lock per file, so writes for the same guild never interleave
+	fileLocks     map[string]*sync.Mutex
+	fileLocksLock sync.Mutex
+
+	callbacks     []GuildDataCallback
+	callbacksLock sync.RWMutex
+	watchOnce     sync.Once
+	stop          chan struct{}
 }
 
 func (fs *FilesystemDriver) Init(directory string) error {
@@ -29,64 +63,288 @@ func (fs *FilesystemDriver) Init(directory string) error {
 		return err
 	}
 
-	fs.fileInfo = fInfos
+	fs.index = map[string]fileStamp{}
+	fs.fileLocks = map[string]*sync.Mutex{}
+	fs.stop = make(chan struct{})
+
+	for _, info := range fInfos {
+		if info.IsDir() {
+			continue
+		}
+		if isTempFile(info.Name()) {
+			//left behind by a write that never finished; the real file is still intact
+			logging.Infof("Entferne unvollständige temporäre Datei %s", info.Name())
+			os.Remove(path.Join(directory, info.Name()))
+			continue
+		}
+		fs.index[info.Name()] = stampFromInfo(info)
+	}
 	return nil
 }
 
 func (fs *FilesystemDriver) GetGuildData(guildID string) (map[string]interface{}, error) {
-	for _, info := range fs.fileInfo {
-		if !info.IsDir() {
-			name := info.Name()
-			fullPath := path.Join(fs.baseDir, name)
-			if strings.Contains(fullPath, guildID+FileSuffix) {
-				f, err := os.Open(fullPath)
-				if err != nil {
-					return nil, err
-				}
-				bytes, err := ioutil.ReadAll(f)
-				f.Close()
-				if err != nil {
-					return nil, err
-				}
-				var intf map[string]interface{}
-				err = json.Unmarshal(bytes, &intf)
-				if err != nil {
-					return nil, err
-				}
-				return intf, nil
-			}
+	return fs.readData(guildID+FileSuffix, errors.New("Keine Konfiguration (config.json) gefunden"))
+}
+
+func (fs *FilesystemDriver) WriteGuildData(guildID string, data map[string]interface{}) error {
+	return fs.writeData(guildID+FileSuffix, data)
+}
+
+func (fs *FilesystemDriver) GetLinkHistory(guildID string) (map[string]interface{}, error) {
+	return fs.readData(guildID+LinkHistorySuffix, errors.New("Kein Verknüpfungsverlauf (links.json) gefunden"))
+}
+
+func (fs *FilesystemDriver) WriteLinkHistory(guildID string, data map[string]interface{}) error {
+	return fs.writeData(guildID+LinkHistorySuffix, data)
+}
+
+func (fs *FilesystemDriver) AddGameRecord(guildID string, record map[string]interface{}) error {
+	fileName := guildID + GameHistorySuffix
+	l := fs.lockFile(fileName)
+	l.Lock()
+	defer l.Unlock()
+
+	data, err := fs.readDataLocked(fileName, nil)
+	if err != nil {
+		return err
+	}
+	games, _ := data["games"].([]interface{})
+	games = append(games, record)
+	if len(games) > MaxGameRecords {
+		games = games[len(games)-MaxGameRecords:]
+	}
+	data["games"] = games
+	return fs.writeDataLocked(fileName, data)
+}
+
+func (fs *FilesystemDriver) GetGameRecords(guildID string) ([]map[string]interface{}, error) {
+	data, err := fs.readData(guildID+GameHistorySuffix, nil)
+	if err != nil {
+		return nil, err
+	}
+	games, _ := data["games"].([]interface{})
+	records := make([]map[string]interface{}, 0, len(games))
+	for _, v := range games {
+		if record, ok := v.(map[string]interface{}); ok {
+			records = append(records, record)
 		}
 	}
-	return map[string]interface{}{}, errors.New("Keine Konfiguration (config.json) gefunden")
+	return records, nil
 }
 
-func (fs *FilesystemDriver) WriteGuildData(guildID string, data map[string]interface{}) error {
+// OnGuildDataChanged registers a callback for when a guild's config file is edited outside of the bot.
+// The directory is only watched once the first callback is registered
+func (fs *FilesystemDriver) OnGuildDataChanged(callback GuildDataCallback) {
+	fs.callbacksLock.Lock()
+	fs.callbacks = append(fs.callbacks, callback)
+	fs.callbacksLock.Unlock()
+
+	fs.watchOnce.Do(func() {
+		go fs.watch(WatchInterval)
+	})
+}
+
+func (fs *FilesystemDriver) Close() error {
+	if fs.stop != nil {
+		close(fs.stop)
+		fs.stop = nil
+	}
+	return nil
+}
+
+func (fs *FilesystemDriver) lockFile(fileName string) *sync.Mutex {
+	fs.fileLocksLock.Lock()
+	defer fs.fileLocksLock.Unlock()
+
+	if l, ok := fs.fileLocks[fileName]; ok {
+		return l
+	}
+	l := &sync.Mutex{}
+	fs.fileLocks[fileName] = l
+	return l
+}
+
+// resolve finds the file on disk that holds fileName's data. Files are normally named exactly
+// <guildID><suffix>, but any file containing that name is accepted, for older configs
+func (fs *FilesystemDriver) resolve(fileName string) string {
+	fs.indexLock.RLock()
+	defer fs.indexLock.RUnlock()
+
+	if _, ok := fs.index[fileName]; ok {
+		return fileName
+	}
+	for name := range fs.index {
+		if strings.Contains(name, fileName) {
+			return name
+		}
+	}
+	return fileName
+}
+
+func (fs *FilesystemDriver) readData(fileName string, notFound error) (map[string]interface{}, error) {
+	l := fs.lockFile(fileName)
+	l.Lock()
+	defer l.Unlock()
+	return fs.readDataLocked(fileName, notFound)
+}
+
+// readDataLocked reads the file without taking its lock; the caller has to hold it.
+// A missing file returns notFound, along with an empty map
+func (fs *FilesystemDriver) readDataLocked(fileName string, notFound error) (map[string]interface{}, error) {
+	fullPath := path.Join(fs.baseDir, fs.resolve(fileName))
+	bytes, err := ioutil.ReadFile(fullPath)
+	if os.IsNotExist(err) {
+		return map[string]interface{}{}, notFound
+	} else if err != nil {
+		return nil, err
+	}
+	var intf map[string]interface{}
+	err = json.Unmarshal(bytes, &intf)
+	if err != nil {
+		return nil, err
+	}
+	return intf, nil
+}
+
+func (fs *FilesystemDriver) writeData(fileName string, data map[string]interface{}) error {
+	l := fs.lockFile(fileName)
+	l.Lock()
+	defer l.Unlock()
+	return fs.writeDataLocked(fileName, data)
+}
+
+// writeDataLocked writes the file without taking its lock; the caller has to hold it
+func (fs *FilesystemDriver) writeDataLocked(fileName string, data map[string]interface{}) error {
 	jsonBytes, err := json.MarshalIndent(data, "", "    ")
 	if err != nil {
 		return err
 	}
 
-	for _, fi := range fs.fileInfo {
-		//TODO enforce naming scheme?
-		if strings.Contains(fi.Name(), guildID+FileSuffix) {
-			f, err := os.OpenFile(path.Join(fs.baseDir, fi.Name()), os.O_CREATE|os.O_WRONLY, 0660)
-			if err != nil {
-				return err
-			}
-			_, err = f.Write(jsonBytes)
-			f.Close()
-			return err //can be nil if it worked
-		}
+	name := fs.resolve(fileName)
+	fullPath := path.Join(fs.baseDir, name)
+
+	tmp, err := ioutil.TempFile(fs.baseDir, tempFilePrefix+name+tempFileSuffix)
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	_, err = tmp.Write(jsonBytes)
+	if err == nil {
+		//make sure the bytes actually hit the disk before we swap the files
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0660)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, fullPath)
 	}
-	f, err := os.Create(path.Join(fs.baseDir, guildID+FileSuffix))
 	if err != nil {
+		os.Remove(tmpPath)
 		return err
 	}
-	_, err = f.Write(jsonBytes)
-	f.Close()
-	return err
-}
 
-func (fs *FilesystemDriver) Close() error {
+	//record what we just wrote, so the watcher doesn't mistake our own write for a manual edit
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return err
+	}
+	fs.indexLock.Lock()
+	fs.index[name] = stampFromInfo(info)
+	fs.indexLock.Unlock()
 	return nil
 }
+
+func (fs *FilesystemDriver) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	stop := fs.stop
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			fs.rescan()
+		}
+	}
+}
+
+// rescan refreshes the index, and fires the callbacks for any config file that's new or changed since the last scan
+func (fs *FilesystemDriver) rescan() {
+	fInfos, err := ioutil.ReadDir(fs.baseDir)
+	if err != nil {
+		logging.Error(err)
+		return
+	}
+
+	changed := make([]string, 0)
+	seen := map[string]bool{}
+
+	fs.indexLock.Lock()
+	for _, info := range fInfos {
+		name := info.Name()
+		if info.IsDir() || isTempFile(name) {
+			continue
+		}
+		seen[name] = true
+		stamp := stampFromInfo(info)
+		if old, ok := fs.index[name]; !ok || !old.modTime.Equal(stamp.modTime) || old.size != stamp.size {
+			fs.index[name] = stamp
+			if strings.HasSuffix(name, FileSuffix) {
+				changed = append(changed, name)
+			}
+		}
+	}
+	for name := range fs.index {
+		if !seen[name] {
+			delete(fs.index, name)
+		}
+	}
+	fs.indexLock.Unlock()
+
+	for _, name := range changed {
+		guildID := guildIDFromFileName(name)
+		data, err := fs.GetGuildData(guildID)
+		if err != nil {
+			//probably caught the file halfway through being saved by an editor; the next scan will pick it up
+			logging.WithGuild(guildID).Warnf("Geänderte Konfiguration %s konnte nicht gelesen werden: %s", name, err)
+			fs.indexLock.Lock()
+			delete(fs.index, name)
+			fs.indexLock.Unlock()
+			continue
+		}
+		logging.WithGuild(guildID).Infof("Konfiguration %s wurde außerhalb des Bots geändert, lade sie neu", name)
+
+		fs.callbacksLock.RLock()
+		for _, callback := range fs.callbacks {
+			callback(guildID, data)
+		}
+		fs.callbacksLock.RUnlock()
+	}
+}
+
+func guildIDFromFileName(name string) string {
+	name = strings.TrimSuffix(name, FileSuffix)
+	//legacy names can have anything in front of the ID; IDs themselves never contain underscores
+	if idx := strings.LastIndex(name, "_"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return name
+}
+
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, tempFilePrefix) && strings.Contains(name, tempFileSuffix)
+}
+
+func stampFromInfo(info os.FileInfo) fileStamp {
+	return fileStamp{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestFilesystemWriteTruncates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsdriver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := &FilesystemDriver{}
+	if err := fs.Init(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	long := map[string]interface{}{"commandPrefix": ".au", "defaultTrackedChannel": "a very long channel name"}
+	if err := fs.WriteGuildData("123", long); err != nil {
+		t.Fatal(err)
+	}
+	short := map[string]interface{}{"commandPrefix": "!"}
+	if err := fs.WriteGuildData("123", short); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.GetGuildData("123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1 || data["commandPrefix"] != "!" {
+		t.Errorf("expected only the shorter document, got %v", data)
+	}
+
+	infos, _ := ioutil.ReadDir(dir)
+	for _, info := range infos {
+		if isTempFile(info.Name()) {
+			t.Errorf("temp file %s was left behind", info.Name())
+		}
+	}
+}
+
+func TestFilesystemSeesLaterFilesAndReloads(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsdriver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := &FilesystemDriver{}
+	if err := fs.Init(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	reloaded := map[string]map[string]interface{}{}
+	fs.callbacks = append(fs.callbacks, func(guildID string, data map[string]interface{}) {
+		reloaded[guildID] = data
+	})
+
+	//created after Init, by hand
+	err = ioutil.WriteFile(path.Join(dir, "456"+FileSuffix), []byte(`{"commandPrefix": ".x"}`), 0660)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := fs.GetGuildData("456")
+	if err != nil || data["commandPrefix"] != ".x" {
+		t.Fatalf("file created after Init wasn't found: %v %v", data, err)
+	}
+
+	fs.rescan()
+	if reloaded["456"]["commandPrefix"] != ".x" {
+		t.Errorf("expected new file to be reported, got %v", reloaded)
+	}
+
+	//our own writes shouldn't look like manual edits
+	delete(reloaded, "456")
+	if err := fs.WriteGuildData("456", map[string]interface{}{"commandPrefix": ".y"}); err != nil {
+		t.Fatal(err)
+	}
+	fs.rescan()
+	if _, ok := reloaded["456"]; ok {
+		t.Errorf("bot's own write was reported as an edit")
+	}
+
+	//but edits by hand should
+	later := time.Now().Add(time.Minute)
+	err = ioutil.WriteFile(path.Join(dir, "456"+FileSuffix), []byte(`{"commandPrefix": ".z"}`), 0660)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path.Join(dir, "456"+FileSuffix), later, later)
+	fs.rescan()
+	if reloaded["456"]["commandPrefix"] != ".z" {
+		t.Errorf("manual edit wasn't reported, got %v", reloaded)
+	}
+}
+
+func TestFilesystemCapsGameHistory(t *testing.T) {
+	defer func(max int) { MaxGameRecords = max }(MaxGameRecords)
+	MaxGameRecords = 3
+
+	fs := &FilesystemDriver{}
+	if err := fs.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := fs.AddGameRecord("123", map[string]interface{}{"gameID": float64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := fs.GetGameRecords("123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != MaxGameRecords {
+		t.Fatalf("expected %d records, got %d", MaxGameRecords, len(records))
+	}
+	//the oldest ones go first
+	for i, record := range records {
+		if record["gameID"] != float64(i+2) {
+			t.Errorf("expected game %d at %d, got %v", i+2, i, record["gameID"])
+		}
+	}
+}
@@ -46,6 +46,40 @@ func (fs *FirestoreDriver) WriteGuildData(guildID string, data map[string]interf
 	return err
 }
 
+func (fs *FirestoreDriver) GetLinkHistory(guildID string) (map[string]interface{}, error) {
+	doc, err := fs.client.Collection("linkHistory").Doc(guildID).Get(fs.ctx)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Data(), nil
+}
+
+func (fs *FirestoreDriver) WriteLinkHistory(guildID string, data map[string]interface{}) error {
+	_, err := fs.client.Collection("linkHistory").Doc(guildID).Set(fs.ctx, data)
+	return err
+}
+
+func (fs *FirestoreDriver) AddGameRecord(guildID string, record map[string]interface{}) error {
+	record["guildID"] = guildID
+	_, _, err := fs.client.Collection("games").Add(fs.ctx, record)
+	return err
+}
+
+func (fs *FirestoreDriver) GetGameRecords(guildID string) ([]map[string]interface{}, error) {
+	docs := fs.client.Collection("games").Where("guildID", "==", guildID).Documents(fs.ctx)
+	records := make([]map[string]interface{}, 0)
+	for {
+		doc, err := docs.Next()
+		if err == iterator.Done {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, doc.Data())
+	}
+}
+
 func createFirestoreClient(ctx context.Context, projectID string) (*firestore.Client, error) {
 	// Sets your Google Cloud Platform project ID.
 	client, err := firestore.NewClient(ctx, projectID)
@@ -7,5 +7,18 @@ type StorageInterface interface {
 	Init(string) error
 	GetGuildData(string) (map[string]interface{}, error)
 	WriteGuildData(string, map[string]interface{}) error
+	GetLinkHistory(string) (map[string]interface{}, error)
+	WriteLinkHistory(string, map[string]interface{}) error
+	AddGameRecord(string, map[string]interface{}) error
+	GetGameRecords(string) ([]map[string]interface{}, error)
 	Close() error
 }
+
+// GuildDataCallback receives the full, freshly-read data for a guild whose data was changed outside of the bot
+type GuildDataCallback func(guildID string, data map[string]interface{})
+
+// WatchableStorage is implemented by drivers that can notice when guild data is edited by hand
+// (for example, the filesystem driver when someone edits a <guild>_config.json)
+type WatchableStorage interface {
+	OnGuildDataChanged(GuildDataCallback)
+}